@@ -35,6 +35,9 @@ type Manager interface {
 	Approve(identifier, voter string) (*types.Approval, error)
 	// Rejects Approval
 	Reject(identifier string) (*types.Approval, error)
+	// Reset drops the votes of an approval whose change was modified, ie: its diff,
+	// and requests approval again
+	Reset(r *types.Approval) error
 
 	Get(identifier string) (*types.Approval, error)
 	List() ([]*types.Approval, error)
@@ -331,6 +334,37 @@ func (m *DefaultManager) Reject(identifier string) (*types.Approval, error) {
 	return existing, nil
 }
 
+// Reset - clears votes of the approval and publishes it as a new request, changes made to r
+// (ie: an updated diff) are stored as well
+func (m *DefaultManager) Reset(r *types.Approval) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, err := m.Get(r.Identifier)
+	if err != nil {
+		return err
+	}
+
+	r.ID = existing.ID
+	r.VotesReceived = 0
+	r.Voters = make(types.JSONB)
+	r.Rejected = false
+	r.UpdatedAt = time.Now()
+
+	err = m.store.UpdateApproval(r)
+	if err != nil {
+		return err
+	}
+
+	m.addAuditEntry(r, types.AuditActionApprovalReset, "")
+
+	log.WithFields(log.Fields{
+		"identifier": r.Identifier,
+	}).Info("approvals.manager: votes reset")
+
+	return m.publishRequest(r)
+}
+
 // Get - get specified, not archived approval
 func (m *DefaultManager) Get(identifier string) (*types.Approval, error) {
 
@@ -358,6 +358,56 @@ func TestApproveTwoVoters(t *testing.T) {
 	}
 }
 
+func TestResetChangedDiff(t *testing.T) {
+	store, teardown := NewTestingUtils()
+	defer teardown()
+
+	am := New(&Opts{
+		Store: store,
+	})
+
+	err := am.Create(&types.Approval{
+		Provider:       types.ProviderTypeKubernetes,
+		Identifier:     "xxx/app-1:1.2.5",
+		CurrentVersion: "1.2.3",
+		NewVersion:     "1.2.5",
+		Diff:           "-image: app:1.2.3\n+image: app:1.2.5\n",
+		Deadline:       time.Now().Add(5 * time.Minute),
+		VotesRequired:  2,
+		VotesReceived:  0,
+	})
+	if err != nil {
+		t.Fatalf("failed to create approval: %s", err)
+	}
+
+	am.Approve("xxx/app-1:1.2.5", "warda")
+	am.Approve("xxx/app-1:1.2.5", "karolisr")
+
+	stored, err := am.Get("xxx/app-1:1.2.5")
+	if err != nil {
+		t.Fatalf("failed to get approval: %s", err)
+	}
+	stored.Diff = " replicas: 2\n-image: app:1.2.3\n+image: app:1.2.5\n"
+	err = am.Reset(stored)
+	if err != nil {
+		t.Fatalf("failed to reset approval: %s", err)
+	}
+
+	stored, err = am.Get("xxx/app-1:1.2.5")
+	if err != nil {
+		t.Fatalf("failed to get approval: %s", err)
+	}
+	if stored.VotesReceived != 0 || len(stored.GetVoters()) != 0 {
+		t.Errorf("votes were not reset: %d %v", stored.VotesReceived, stored.GetVoters())
+	}
+	if stored.Status() != types.ApprovalStatusPending {
+		t.Errorf("unexpected status: %s", stored.Status())
+	}
+	if stored.Diff != " replicas: 2\n-image: app:1.2.3\n+image: app:1.2.5\n" {
+		t.Errorf("diff was not stored: %s", stored.Diff)
+	}
+}
+
 func TestReject(t *testing.T) {
 	store, teardown := NewTestingUtils()
 	defer teardown()
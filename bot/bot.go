@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/alwinius/bow/approvals"
+	"github.com/alwinius/bow/provider"
 	"github.com/alwinius/bow/types"
 
 	log "github.com/sirupsen/logrus"
@@ -14,6 +15,7 @@ import (
 
 const (
 	RemoveApprovalPrefix = "rm approval"
+	RollbackPrefix       = "rollback"
 )
 
 var (
@@ -25,6 +27,7 @@ var (
 			`- "rm approval <approval identifier>" -> remove approval`,
 			`- "approve <approval identifier>" -> approve update request`,
 			`- "reject <approval identifier>" -> reject update request`,
+			`- "rollback <resource identifier>" -> revert the last update of a resource, ie: deployment/default/web`,
 			// `- "get deployments all" -> get a list of all deployments`,
 			// `- "describe deployment <deployment>" -> get details for specified deployment`,
 		},
@@ -37,7 +40,7 @@ var (
 	}
 
 	// dynamic bot command prefixes have to be matched
-	dynamicBotCommandPrefixes = []string{RemoveApprovalPrefix}
+	dynamicBotCommandPrefixes = []string{RemoveApprovalPrefix, RollbackPrefix}
 
 	ApprovalResponseKeyword = "approve"
 	RejectResponseKeyword   = "reject"
@@ -79,6 +82,7 @@ type ApprovalResponse struct {
 // BotManager holds approvalsManager and k8sImplementer for every bot
 type BotManager struct {
 	approvalsManager   approvals.Manager
+	rollbacker         provider.Rollbacker
 	botMessagesChannel chan *BotMessage
 	approvalsRespCh    chan *ApprovalResponse
 }
@@ -107,10 +111,11 @@ func RegisterBot(name string, b Bot) {
 	bots[name] = b
 }
 
-// Run all implemented bots
-func Run(approvalsManager approvals.Manager) {
+// Run all implemented bots, rollbacker reverts updates on request and may be nil
+func Run(approvalsManager approvals.Manager, rollbacker provider.Rollbacker) {
 	bm := &BotManager{
 		approvalsManager:   approvalsManager,
+		rollbacker:         rollbacker,
 		approvalsRespCh:    make(chan *ApprovalResponse), // don't add buffer to make it blocking
 		botMessagesChannel: make(chan *BotMessage),
 	}
@@ -190,6 +195,10 @@ func (bm *BotManager) handleBotMessage(m *BotMessage) string {
 		return strings.Join(responseLines, "\n")
 	}
 
+	if strings.HasPrefix(command, RollbackPrefix+" ") || command == RollbackPrefix {
+		return RollbackHandler(strings.TrimSpace(strings.TrimPrefix(command, RollbackPrefix)), m.User, bm.rollbacker)
+	}
+
 	if IsBotCommand(command) {
 		return fmt.Sprintf("bot commands not supported any more '%s'", command)
 	}
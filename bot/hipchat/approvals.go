@@ -6,11 +6,17 @@ import (
 	"github.com/alwinius/bow/types"
 )
 
+// diffPreviewLines - lines of the pending diff shown in approval requests
+const diffPreviewLines = 40
+
 func (b *Bot) RequestApproval(req *types.Approval) error {
 	msg := fmt.Sprintf(ApprovalRequiredTempl,
 		req.Message, req.Identifier, req.Identifier,
 		req.VotesReceived, req.VotesRequired, req.Delta(), req.Identifier,
 		req.Provider.String())
+	if req.Diff != "" {
+		msg += "\n" + req.DiffPreview(diffPreviewLines)
+	}
 	return b.postMessage(formatAsSnippet(msg))
 }
 
@@ -0,0 +1,36 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alwinius/bow/provider"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RollbackHandler - reverts the last update of a resource on behalf of a chat user
+func RollbackHandler(identifier string, user string, rollbacker provider.Rollbacker) string {
+	if identifier == "" {
+		return fmt.Sprintf("usage: %s <resource identifier>, ie: %s deployment/default/web", RollbackPrefix, RollbackPrefix)
+	}
+	if rollbacker == nil {
+		return "rollback is not supported by the providers"
+	}
+
+	rollback, err := rollbacker.Rollback(identifier, user)
+	if err == provider.ErrResourceNotFound {
+		return fmt.Sprintf("resource '%s' was not found", identifier)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"identifier": identifier,
+			"user":       user,
+		}).Error("bot.RollbackHandler: rollback failed")
+		return fmt.Sprintf("failed to roll back '%s': %s", identifier, err)
+	}
+
+	return fmt.Sprintf("reverted commit %s \"%s\" of '%s' in %s (%s), versions %s will not be applied again.",
+		rollback.Reverted, rollback.Subject, identifier, rollback.Repository, rollback.Commit, strings.Join(rollback.Blocked, ", "))
+}
@@ -7,39 +7,50 @@ import (
 	"github.com/nlopes/slack"
 )
 
+// diffPreviewLines - lines of the pending diff shown in approval requests
+const diffPreviewLines = 40
+
 // Request - request approval
 func (b *Bot) RequestApproval(req *types.Approval) error {
+	fields := []slack.AttachmentField{
+		slack.AttachmentField{
+			Title: "Approval required!",
+			Value: req.Message + "\n" + fmt.Sprintf("To vote for change type '%s approve %s' to reject it: '%s reject %s'.", b.name, req.Identifier, b.name, req.Identifier),
+			Short: false,
+		},
+		slack.AttachmentField{
+			Title: "Votes",
+			Value: fmt.Sprintf("%d/%d", req.VotesReceived, req.VotesRequired),
+			Short: true,
+		},
+		slack.AttachmentField{
+			Title: "Delta",
+			Value: req.Delta(),
+			Short: true,
+		},
+		slack.AttachmentField{
+			Title: "Identifier",
+			Value: req.Identifier,
+			Short: true,
+		},
+		slack.AttachmentField{
+			Title: "Provider",
+			Value: req.Provider.String(),
+			Short: true,
+		},
+	}
+	if req.Diff != "" {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Diff",
+			Value: "```" + req.DiffPreview(diffPreviewLines) + "```",
+			Short: false,
+		})
+	}
 	return b.postMessage(
 		"Approval required",
 		req.Message,
 		types.LevelSuccess.Color(),
-		[]slack.AttachmentField{
-			slack.AttachmentField{
-				Title: "Approval required!",
-				Value: req.Message + "\n" + fmt.Sprintf("To vote for change type '%s approve %s' to reject it: '%s reject %s'.", b.name, req.Identifier, b.name, req.Identifier),
-				Short: false,
-			},
-			slack.AttachmentField{
-				Title: "Votes",
-				Value: fmt.Sprintf("%d/%d", req.VotesReceived, req.VotesRequired),
-				Short: true,
-			},
-			slack.AttachmentField{
-				Title: "Delta",
-				Value: req.Delta(),
-				Short: true,
-			},
-			slack.AttachmentField{
-				Title: "Identifier",
-				Value: req.Identifier,
-				Short: true,
-			},
-			slack.AttachmentField{
-				Title: "Provider",
-				Value: req.Provider.String(),
-				Short: true,
-			},
-		})
+		fields)
 }
 
 func (b *Bot) ReplyToApproval(approval *types.Approval) error {
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"context"
@@ -16,7 +17,6 @@ import (
 
 	"github.com/alwinius/bow/approvals"
 	"github.com/alwinius/bow/bot"
-	"gopkg.in/src-d/go-git.v4/plumbing"
 
 	// "github.com/alwinius/bow/cache/memory"
 	"github.com/alwinius/bow/pkg/auth"
@@ -70,6 +70,36 @@ const (
 	EnvRepoPassword      = "REPO_PASSWORD"   // optional
 	EnvRepoChartPath     = "REPO_CHART_PATH" // optional
 	EnvRepoBranch        = "REPO_BRANCH"     // optional
+	EnvRepoRender        = "REPO_RENDER"     // optional, auto/helm/manifests/kustomize
+	EnvRepoInterval      = "REPO_INTERVAL"   // optional, time between polls, ie: 5m
+	EnvRepoInclude       = "REPO_INCLUDE"    // optional, comma separated globs of apps to discover, ie: apps/*,envs/*
+	EnvRepoExclude       = "REPO_EXCLUDE"    // optional, comma separated globs of directories to skip
+	EnvRepoConfig        = "REPO_CONFIG"     // optional, path to a file listing multiple repositories
+	EnvRepoForge         = "REPO_FORGE"      // optional, github/gitlab/gitea, enables pull request mode
+	EnvRepoForgeAPIURL   = "REPO_FORGE_API_URL"
+	EnvRepoForgeToken    = "REPO_FORGE_TOKEN"
+	EnvRepoWebhookSecret = "REPO_WEBHOOK_SECRET" // optional, verifies /v1/webhooks/git pushes
+	EnvRepoBatchWindow   = "REPO_BATCH_WINDOW"   // optional, ie: 2m, commits updates of that period together
+	EnvRepoWorkdir       = "REPO_WORKDIR"        // optional, checkouts are placed below, defaults to $XDG_DATA_HOME/repos
+	EnvRepoStorage       = "REPO_STORAGE"        // optional, disk/memory
+	EnvRepoShallow       = "REPO_SHALLOW"        // optional, true/false, fetch only the latest commit
+	EnvDryRun            = "DRY_RUN"             // optional, true/false, plan updates at /v1/plans without committing
+
+	EnvRepoCommitAuthorName      = "REPO_COMMIT_AUTHOR_NAME"      // optional
+	EnvRepoCommitAuthorEmail     = "REPO_COMMIT_AUTHOR_EMAIL"     // optional
+	EnvRepoCommitMessageTemplate = "REPO_COMMIT_MESSAGE_TEMPLATE" // optional, ie: update {{ .Repository }} to {{ .NewTag }}
+	EnvRepoCommitTrailers        = "REPO_COMMIT_TRAILERS"         // optional, true/false
+	EnvRepoCommitSigningKey      = "REPO_COMMIT_SIGNING_KEY"      // optional, path to an OpenPGP or SSH private key
+	EnvRepoCommitSigningPass     = "REPO_COMMIT_SIGNING_KEY_PASSPHRASE"
+
+	EnvRepoToken            = "REPO_TOKEN"        // optional, bearer token for HTTPS
+	EnvRepoSSHKeyPath       = "REPO_SSH_KEY_PATH" // optional, defaults to ~/.ssh/id_ed25519, id_ecdsa or id_rsa
+	EnvRepoSSHKey           = "REPO_SSH_KEY"      // optional, inline private key
+	EnvRepoSSHKeyPassphrase = "REPO_SSH_KEY_PASSPHRASE"
+	EnvRepoSSHUser          = "REPO_SSH_USER"    // optional, defaults to git
+	EnvRepoKnownHosts       = "REPO_KNOWN_HOSTS" // optional, strict host key checking against this file
+	EnvRepoCAFile           = "REPO_CA_FILE"     // optional, PEM bundle for HTTPS
+	EnvRepoProxy            = "REPO_PROXY"       // optional, HTTP(S) proxy URL
 
 	// EnvDefaultDockerRegistryCfg - default registry configuration that can be passed into
 	// bow for polling trigger
@@ -78,7 +108,6 @@ const (
 
 // EnvDebug - set to 1 or anything else to enable debug logging
 const EnvDebug = "DEBUG"
-const repoPath = "/home/alwin/projects/bow-tmp/"
 
 func main() {
 	ver := version.GetbowVersion()
@@ -164,18 +193,11 @@ func main() {
 	buf := k8s.NewBuffer(&g, t, log.StandardLogger(), 128)
 	wl := log.WithField("context", "watch")
 
-	absRepoPath, _ := filepath.Abs(repoPath)
-
-	b := os.Getenv(EnvRepoBranch)
-	if b == "" {
-		b = "master"
+	repos := setupRepos(dataDir)
+	for _, repo := range repos {
+		log.Debug("main: using branch ", repo.Branch, " from ", repo.URL)
+		gitrepo.WatchRepo(&g, repo, wl, buf)
 	}
-	branch := plumbing.NewBranchReferenceName(b)
-
-	log.Debug("main: using branch ", branch, " from ", os.Getenv(EnvRepoURL))
-	repo := gitrepo.Repo{Username: os.Getenv(EnvRepoUser), Password: os.Getenv(EnvRepoPassword), URL: os.Getenv(EnvRepoURL),
-		ChartPath: os.Getenv(EnvRepoChartPath), LocalPath: absRepoPath, Branch: branch}
-	gitrepo.WatchRepo(&g, repo, wl, buf)
 
 	// approvalsCache := memory.NewMemoryCache()
 	approvalsManager := approvals.New(&approvals.Opts{
@@ -191,7 +213,8 @@ func main() {
 		approvalsManager: approvalsManager,
 		grc:              &t.GenericResourceCache,
 		store:            sqlStore,
-		repo:             repo,
+		repos:            repos,
+		promotions:       setupPromotions(repos),
 	})
 
 	// registering secrets based credentials helper
@@ -218,9 +241,11 @@ func main() {
 		grc:              &t.GenericResourceCache,
 		store:            sqlStore,
 		uiDir:            *uiDir,
+		repos:            repos,
 	})
 
-	bot.Run(approvalsManager) // the bot handles communication via Slack
+	rollbacker, _ := providers.(provider.Rollbacker)
+	bot.Run(approvalsManager, rollbacker) // the bot handles communication via Slack
 
 	signalChan := make(chan os.Signal, 1)
 	cleanupDone := make(chan bool)
@@ -256,7 +281,106 @@ type ProviderOpts struct {
 	approvalsManager approvals.Manager
 	grc              *k8s.GenericResourceCache
 	store            store.Store
-	repo             gitrepo.Repo
+	repos            []*gitrepo.Repo
+	promotions       []*gitrepo.Promotion
+}
+
+// setupRepos - creates watched repositories either from the file in REPO_CONFIG or
+// from the single repository REPO_* environment variables. Checkouts are placed below
+// REPO_WORKDIR or the repos directory in dataDir.
+func setupRepos(dataDir string) []*gitrepo.Repo {
+	workdir := filepath.Join(dataDir, "repos")
+	if os.Getenv(EnvRepoWorkdir) != "" {
+		workdir = os.Getenv(EnvRepoWorkdir)
+	}
+
+	if os.Getenv(EnvRepoConfig) != "" {
+		repos, err := gitrepo.LoadConfig(os.Getenv(EnvRepoConfig), workdir)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"path":  os.Getenv(EnvRepoConfig),
+			}).Fatal("main: failed to load repository config")
+		}
+		return repos
+	}
+
+	rc := gitrepo.RepoConfig{
+		URL:       os.Getenv(EnvRepoURL),
+		Branch:    os.Getenv(EnvRepoBranch),
+		ChartPath: os.Getenv(EnvRepoChartPath),
+		Render:    os.Getenv(EnvRepoRender),
+		Include:   splitList(os.Getenv(EnvRepoInclude)),
+		Exclude:   splitList(os.Getenv(EnvRepoExclude)),
+		Storage:   os.Getenv(EnvRepoStorage),
+		Shallow:   os.Getenv(EnvRepoShallow) == "true",
+		Interval:  os.Getenv(EnvRepoInterval),
+		AuthConfig: gitrepo.AuthConfig{
+			Username:         os.Getenv(EnvRepoUser),
+			Password:         os.Getenv(EnvRepoPassword),
+			Token:            os.Getenv(EnvRepoToken),
+			SSHKeyPath:       os.Getenv(EnvRepoSSHKeyPath),
+			SSHKey:           os.Getenv(EnvRepoSSHKey),
+			SSHKeyPassphrase: os.Getenv(EnvRepoSSHKeyPassphrase),
+			SSHUser:          os.Getenv(EnvRepoSSHUser),
+			KnownHosts:       os.Getenv(EnvRepoKnownHosts),
+			CAFile:           os.Getenv(EnvRepoCAFile),
+			Proxy:            os.Getenv(EnvRepoProxy),
+		},
+
+		WebhookSecret: os.Getenv(EnvRepoWebhookSecret),
+		BatchWindow:   os.Getenv(EnvRepoBatchWindow),
+		Commit: &gitrepo.CommitConfig{
+			AuthorName:           os.Getenv(EnvRepoCommitAuthorName),
+			AuthorEmail:          os.Getenv(EnvRepoCommitAuthorEmail),
+			MessageTemplate:      os.Getenv(EnvRepoCommitMessageTemplate),
+			Trailers:             os.Getenv(EnvRepoCommitTrailers) == "true",
+			SigningKey:           os.Getenv(EnvRepoCommitSigningKey),
+			SigningKeyPassphrase: os.Getenv(EnvRepoCommitSigningPass),
+		},
+	}
+	if os.Getenv(EnvRepoForge) != "" {
+		rc.PullRequests = &gitrepo.PullRequestConfig{
+			Enabled: true,
+			Forge:   os.Getenv(EnvRepoForge),
+			APIURL:  os.Getenv(EnvRepoForgeAPIURL),
+			Token:   os.Getenv(EnvRepoForgeToken),
+		}
+	}
+
+	repo, err := gitrepo.NewRepo(rc, workdir)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatalf("main: failed to set up repository, check %s", EnvRepoURL)
+	}
+	return []*gitrepo.Repo{repo}
+}
+
+// setupPromotions - promotion pipelines between environments of repos, only available with REPO_CONFIG
+func setupPromotions(repos []*gitrepo.Repo) []*gitrepo.Promotion {
+	if os.Getenv(EnvRepoConfig) == "" {
+		return nil
+	}
+	promotions, err := gitrepo.LoadPromotions(os.Getenv(EnvRepoConfig), repos)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"path":  os.Getenv(EnvRepoConfig),
+		}).Fatal("main: failed to load promotions")
+	}
+	return promotions
+}
+
+// splitList - splits comma separated environment variable, empty elements are dropped
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
 }
 
 // setupProviders - setting up available providers. New providers should be initialised here and added to
@@ -264,12 +388,15 @@ type ProviderOpts struct {
 func setupProviders(opts *ProviderOpts) (providers provider.Providers) {
 	var enabledProviders []provider.Provider
 
-	k8sProvider, err := kubernetes.NewProvider(opts.sender, opts.approvalsManager, opts.grc, opts.repo)
+	k8sProvider, err := kubernetes.NewProvider(opts.sender, opts.approvalsManager, opts.grc, opts.repos...)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error": err,
 		}).Fatal("main.setupProviders: failed to create kubernetes provider")
 	}
+	k8sProvider.SetPromotions(opts.promotions)
+	k8sProvider.SetStore(opts.store)
+	k8sProvider.SetDryRun(os.Getenv(EnvDryRun) == "true")
 	go func() {
 		err := k8sProvider.Start()
 		if err != nil {
@@ -309,6 +436,7 @@ type TriggerOpts struct {
 	grc              *k8s.GenericResourceCache
 	store            store.Store
 	uiDir            string
+	repos            []*gitrepo.Repo
 }
 
 // setupTriggers - setting up triggers. New triggers should be added to this function. Each trigger
@@ -332,6 +460,7 @@ func setupTriggers(ctx context.Context, opts *TriggerOpts) (teardown func()) {
 		Authenticator:         authenticator,
 		UIDir:                 opts.uiDir,
 		AuthenticatedWebhooks: os.Getenv(constants.EnvAuthenticatedWebhooks) == "true",
+		Repos:                 opts.repos,
 	})
 
 	go func() {
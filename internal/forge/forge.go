@@ -0,0 +1,270 @@
+// Package forge talks to git hosting services (GitHub, GitLab, Gitea) to open
+// pull/merge requests for updates that cannot be pushed to the watched branch directly
+package forge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const timeout = 15 * time.Second
+
+// maxPages - pages followed when listing, guards against servers linking pages in a loop
+const maxPages = 100
+
+// PullRequest - pull request (GitHub, Gitea) or merge request (GitLab)
+type PullRequest struct {
+	Number int
+	Title  string
+	Body   string
+	// Head is the branch with the changes, Base the branch it should be merged into
+	Head string
+	Base string
+	URL  string
+}
+
+// Client - forge API client
+type Client interface {
+	// ListOpen returns open pull requests targeting base branch
+	ListOpen(base string) ([]*PullRequest, error)
+	Create(pr *PullRequest) (*PullRequest, error)
+	// Update changes title and body of an existing pull request
+	Update(pr *PullRequest) (*PullRequest, error)
+}
+
+// Opts - forge client options
+type Opts struct {
+	// Kind is one of github, gitlab or gitea
+	Kind string
+	// APIURL defaults to the public GitHub/GitLab API, required for Gitea
+	APIURL string
+	// Project is owner/repository, for GitLab the full project path
+	Project string
+	Token   string
+}
+
+// New - creates forge client
+func New(opts Opts) (Client, error) {
+	if opts.Project == "" {
+		return nil, fmt.Errorf("forge project cannot be empty")
+	}
+	if opts.Token == "" {
+		return nil, fmt.Errorf("forge token cannot be empty")
+	}
+
+	c := &client{
+		http:    &http.Client{Timeout: timeout},
+		project: opts.Project,
+		token:   opts.Token,
+	}
+
+	switch strings.ToLower(opts.Kind) {
+	case "github":
+		c.baseURL = defaultString(opts.APIURL, "https://api.github.com")
+		return &github{c}, nil
+	case "gitlab":
+		c.baseURL = defaultString(opts.APIURL, "https://gitlab.com")
+		return &gitlab{c}, nil
+	case "gitea":
+		if opts.APIURL == "" {
+			return nil, fmt.Errorf("gitea API URL cannot be empty")
+		}
+		c.baseURL = opts.APIURL
+		return &gitea{c}, nil
+	}
+
+	return nil, fmt.Errorf("unknown forge '%s', expected github, gitlab or gitea", opts.Kind)
+}
+
+// ProjectFromURL - extracts owner/repository from git@host:owner/repo.git or
+// https://host/owner/repo.git
+func ProjectFromURL(url string) string {
+	project := strings.TrimSuffix(strings.TrimRight(url, "/"), ".git")
+	if i := strings.Index(project, "://"); i >= 0 {
+		project = project[i+3:]
+		if j := strings.Index(project, "/"); j >= 0 {
+			return project[j+1:]
+		}
+		return ""
+	}
+	if i := strings.Index(project, ":"); i >= 0 {
+		return project[i+1:]
+	}
+	return project
+}
+
+// WebURL - web page of the repository at url, ie: https://github.com/alwinius/bow for
+// git@github.com:alwinius/bow.git. Credentials and SSH ports are dropped, local paths have none.
+func WebURL(url string) string {
+	u := strings.TrimSuffix(strings.TrimRight(url, "/"), ".git")
+	// web pages of SSH remotes are served over https on the default port
+	scheme, keepPort := "https", false
+	if i := strings.Index(u, "://"); i >= 0 {
+		switch u[:i] {
+		case "http", "https":
+			scheme, keepPort = u[:i], true
+		case "ssh", "git":
+		default:
+			return ""
+		}
+		u = u[i+3:]
+	} else if i := strings.Index(u, ":"); i >= 0 && !strings.Contains(u[:i], "/") {
+		// git@host:owner/repo
+		u = u[:i] + "/" + u[i+1:]
+	} else {
+		return ""
+	}
+
+	slash := strings.Index(u, "/")
+	if slash < 0 || slash == len(u)-1 {
+		return ""
+	}
+	host, path := u[:slash], u[slash+1:]
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	if i := strings.Index(host, ":"); i >= 0 && !keepPort {
+		host = host[:i]
+	}
+	return scheme + "://" + host + "/" + path
+}
+
+// CommitURL - web page of commit in the repository at url, GitLab's layout is used for kind gitlab
+// or, without kind, hosts with gitlab in their name; GitHub's (shared by Gitea) otherwise
+func CommitURL(kind, url, commit string) string {
+	web := WebURL(url)
+	if web == "" || commit == "" {
+		return ""
+	}
+	kind = strings.ToLower(kind)
+	if kind == "gitlab" || (kind == "" && strings.Contains(web, "gitlab")) {
+		return web + "/-/commit/" + commit
+	}
+	return web + "/commit/" + commit
+}
+
+func defaultString(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+type client struct {
+	http    *http.Client
+	baseURL string
+	project string
+	token   string
+}
+
+// do - sends JSON request and decodes JSON response into out
+func (c *client) do(method, path string, header http.Header, in, out interface{}) error {
+	resp, err := c.send(method, strings.TrimRight(c.baseURL, "/")+path, header, in)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// list - GETs path and every page following it in the rel="next" Link header, which GitHub,
+// GitLab and Gitea send. Each page is passed to decode.
+func (c *client) list(path string, header http.Header, decode func(page *json.Decoder) error) error {
+	next := strings.TrimRight(c.baseURL, "/") + path
+	for pages := 0; next != ""; pages++ {
+		if pages == maxPages {
+			return fmt.Errorf("GET %s returned more than %d pages", path, maxPages)
+		}
+
+		resp, err := c.send("GET", next, header, nil)
+		if err != nil {
+			return err
+		}
+		err = decode(json.NewDecoder(resp.Body))
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		next, err = nextPage(next, resp.Header.Get("Link"))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nextPage - URL of the rel="next" link in a Link header, ie:
+// <https://api.github.com/repositories/1/pulls?page=2>; rel="next", <...>; rel="last".
+// Links to other hosts are refused, they would receive the token.
+func nextPage(current string, header string) (string, error) {
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.Split(link, ";")
+		if len(parts) < 2 {
+			continue
+		}
+		for _, param := range parts[1:] {
+			if strings.Replace(strings.TrimSpace(param), " ", "", -1) != `rel="next"` {
+				continue
+			}
+			base, err := url.Parse(current)
+			if err != nil {
+				return "", err
+			}
+			next, err := base.Parse(strings.Trim(strings.TrimSpace(parts[0]), "<>"))
+			if err != nil {
+				return "", fmt.Errorf("invalid next page link: %s", err)
+			}
+			if next.Host != base.Host {
+				return "", fmt.Errorf("next page link points to another host: %s", next.Host)
+			}
+			return next.String(), nil
+		}
+	}
+	return "", nil
+}
+
+// send - sends JSON request to url, responses without a 2xx status are errors
+func (c *client) send(method, url string, header http.Header, in interface{}) (*http.Response, error) {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s %s failed with status %d: %s", method, req.URL.Path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
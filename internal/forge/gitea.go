@@ -0,0 +1,64 @@
+package forge
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// gitea - Gitea API v1 client, the pull request API mirrors GitHub's
+type gitea struct {
+	*client
+}
+
+func (g *gitea) header() http.Header {
+	return http.Header{"Authorization": []string{"token " + g.token}}
+}
+
+func (g *gitea) ListOpen(base string) ([]*PullRequest, error) {
+	var prs []githubPullRequest
+	err := g.list(fmt.Sprintf("/api/v1/repos/%s/pulls?state=open&limit=50", g.project), g.header(), func(page *json.Decoder) error {
+		var prsPage []githubPullRequest
+		err := page.Decode(&prsPage)
+		prs = append(prs, prsPage...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// gitea cannot filter by base branch
+	var result []*PullRequest
+	for _, pr := range prs {
+		if pr.Base.Ref == base {
+			result = append(result, pr.toPullRequest())
+		}
+	}
+	return result, nil
+}
+
+func (g *gitea) Create(pr *PullRequest) (*PullRequest, error) {
+	var created githubPullRequest
+	err := g.do("POST", fmt.Sprintf("/api/v1/repos/%s/pulls", g.project), g.header(), &githubCreatePullRequest{
+		Title: pr.Title,
+		Body:  pr.Body,
+		Head:  pr.Head,
+		Base:  pr.Base,
+	}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return created.toPullRequest(), nil
+}
+
+func (g *gitea) Update(pr *PullRequest) (*PullRequest, error) {
+	var updated githubPullRequest
+	err := g.do("PATCH", fmt.Sprintf("/api/v1/repos/%s/pulls/%d", g.project, pr.Number), g.header(), &githubUpdatePullRequest{
+		Title: pr.Title,
+		Body:  pr.Body,
+	}, &updated)
+	if err != nil {
+		return nil, err
+	}
+	return updated.toPullRequest(), nil
+}
@@ -0,0 +1,99 @@
+package forge
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// github - GitHub REST API v3 client
+type github struct {
+	*client
+}
+
+type githubRef struct {
+	Ref string `json:"ref"`
+}
+
+type githubPullRequest struct {
+	Number  int       `json:"number,omitempty"`
+	Title   string    `json:"title"`
+	Body    string    `json:"body"`
+	HTMLURL string    `json:"html_url,omitempty"`
+	Head    githubRef `json:"head"`
+	Base    githubRef `json:"base"`
+}
+
+type githubCreatePullRequest struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	Head  string `json:"head"`
+	Base  string `json:"base"`
+}
+
+type githubUpdatePullRequest struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+func (g *github) header() http.Header {
+	return http.Header{"Authorization": []string{"token " + g.token}}
+}
+
+func (g *github) ListOpen(base string) ([]*PullRequest, error) {
+	var prs []githubPullRequest
+	path := fmt.Sprintf("/repos/%s/pulls?state=open&per_page=100&base=%s", g.project, url.QueryEscape(base))
+	err := g.list(path, g.header(), func(page *json.Decoder) error {
+		var prsPage []githubPullRequest
+		err := page.Decode(&prsPage)
+		prs = append(prs, prsPage...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*PullRequest
+	for _, pr := range prs {
+		result = append(result, pr.toPullRequest())
+	}
+	return result, nil
+}
+
+func (g *github) Create(pr *PullRequest) (*PullRequest, error) {
+	var created githubPullRequest
+	err := g.do("POST", fmt.Sprintf("/repos/%s/pulls", g.project), g.header(), &githubCreatePullRequest{
+		Title: pr.Title,
+		Body:  pr.Body,
+		Head:  pr.Head,
+		Base:  pr.Base,
+	}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return created.toPullRequest(), nil
+}
+
+func (g *github) Update(pr *PullRequest) (*PullRequest, error) {
+	var updated githubPullRequest
+	err := g.do("PATCH", fmt.Sprintf("/repos/%s/pulls/%d", g.project, pr.Number), g.header(), &githubUpdatePullRequest{
+		Title: pr.Title,
+		Body:  pr.Body,
+	}, &updated)
+	if err != nil {
+		return nil, err
+	}
+	return updated.toPullRequest(), nil
+}
+
+func (pr *githubPullRequest) toPullRequest() *PullRequest {
+	return &PullRequest{
+		Number: pr.Number,
+		Title:  pr.Title,
+		Body:   pr.Body,
+		Head:   pr.Head.Ref,
+		Base:   pr.Base.Ref,
+		URL:    pr.HTMLURL,
+	}
+}
@@ -0,0 +1,87 @@
+package forge
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// gitlab - GitLab API v4 client, pull requests are merge requests here
+type gitlab struct {
+	*client
+}
+
+type gitlabMergeRequest struct {
+	IID          int    `json:"iid,omitempty"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	WebURL       string `json:"web_url,omitempty"`
+	SourceBranch string `json:"source_branch,omitempty"`
+	TargetBranch string `json:"target_branch,omitempty"`
+}
+
+func (g *gitlab) header() http.Header {
+	return http.Header{"Private-Token": []string{g.token}}
+}
+
+func (g *gitlab) projectPath() string {
+	return "/api/v4/projects/" + url.PathEscape(g.project)
+}
+
+func (g *gitlab) ListOpen(base string) ([]*PullRequest, error) {
+	var mrs []gitlabMergeRequest
+	path := fmt.Sprintf("%s/merge_requests?state=opened&per_page=100&target_branch=%s", g.projectPath(), url.QueryEscape(base))
+	err := g.list(path, g.header(), func(page *json.Decoder) error {
+		var mrsPage []gitlabMergeRequest
+		err := page.Decode(&mrsPage)
+		mrs = append(mrs, mrsPage...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*PullRequest
+	for _, mr := range mrs {
+		result = append(result, mr.toPullRequest())
+	}
+	return result, nil
+}
+
+func (g *gitlab) Create(pr *PullRequest) (*PullRequest, error) {
+	var created gitlabMergeRequest
+	err := g.do("POST", g.projectPath()+"/merge_requests", g.header(), &gitlabMergeRequest{
+		Title:        pr.Title,
+		Description:  pr.Body,
+		SourceBranch: pr.Head,
+		TargetBranch: pr.Base,
+	}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return created.toPullRequest(), nil
+}
+
+func (g *gitlab) Update(pr *PullRequest) (*PullRequest, error) {
+	var updated gitlabMergeRequest
+	err := g.do("PUT", fmt.Sprintf("%s/merge_requests/%d", g.projectPath(), pr.Number), g.header(), &gitlabMergeRequest{
+		Title:       pr.Title,
+		Description: pr.Body,
+	}, &updated)
+	if err != nil {
+		return nil, err
+	}
+	return updated.toPullRequest(), nil
+}
+
+func (mr *gitlabMergeRequest) toPullRequest() *PullRequest {
+	return &PullRequest{
+		Number: mr.IID,
+		Title:  mr.Title,
+		Body:   mr.Description,
+		Head:   mr.SourceBranch,
+		Base:   mr.TargetBranch,
+		URL:    mr.WebURL,
+	}
+}
@@ -0,0 +1,255 @@
+package gitrepo
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	nethttp "net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	cryptossh "golang.org/x/crypto/ssh"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/client"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+)
+
+// defaultSSHUser - user of SSH URLs without one, ie: github.com:team/deployment.git
+const defaultSSHUser = "git"
+
+// defaultSSHKeys - tried in order below $HOME/.ssh when no key is configured
+var defaultSSHKeys = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// AuthConfig - credentials and transport settings used to talk to the remote
+type AuthConfig struct {
+	// Username and Password (or a personal access token as password) for HTTPS
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Token is sent as bearer token for HTTPS, for GitHub App installation tokens use
+	// username x-access-token and the token as password instead
+	Token string `json:"token"`
+
+	// SSHKeyPath is the path of the private key, defaults to $HOME/.ssh/id_ed25519, id_ecdsa or id_rsa
+	SSHKeyPath string `json:"sshKeyPath"`
+	// SSHKey is an inline private key, takes precedence over SSHKeyPath
+	SSHKey           string `json:"sshKey"`
+	SSHKeyPassphrase string `json:"sshKeyPassphrase"`
+	// SSHUser defaults to the user of the URL or git
+	SSHUser string `json:"sshUser"`
+	// KnownHosts enables strict host key checking against this file only,
+	// $HOME/.ssh/known_hosts and /etc/ssh/ssh_known_hosts are used otherwise
+	KnownHosts string `json:"knownHosts"`
+
+	// CAFile is a PEM bundle trusted in addition to the system roots for HTTPS
+	CAFile string `json:"caFile"`
+	// Proxy is the URL of an HTTP(S) proxy, ie: http://proxy.internal:3128
+	Proxy string `json:"proxy"`
+}
+
+// newAuth - creates the transport authentication for url, fails on unusable credentials
+func newAuth(cfg AuthConfig, rawURL string) (transport.AuthMethod, error) {
+	ep, err := transport.NewEndpoint(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %s", err)
+	}
+
+	switch ep.Protocol {
+	case "ssh":
+		if cfg.Token != "" || cfg.CAFile != "" || cfg.Proxy != "" {
+			return nil, fmt.Errorf("token, caFile and proxy only apply to HTTPS URLs")
+		}
+		return newSSHAuth(cfg, ep)
+	case "http", "https":
+		if cfg.SSHKey != "" || cfg.SSHKeyPath != "" || cfg.KnownHosts != "" {
+			return nil, fmt.Errorf("SSH keys and known hosts only apply to SSH URLs")
+		}
+		err = registerHTTPTransport(ep.Host, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return newHTTPAuth(cfg)
+	default:
+		return nil, nil
+	}
+}
+
+func newHTTPAuth(cfg AuthConfig) (transport.AuthMethod, error) {
+	if cfg.Token != "" {
+		if cfg.Username != "" || cfg.Password != "" {
+			return nil, fmt.Errorf("set either a token or username and password")
+		}
+		return &http.TokenAuth{Token: cfg.Token}, nil
+	}
+	if cfg.Username != "" || cfg.Password != "" {
+		if cfg.Username == "" || cfg.Password == "" {
+			return nil, fmt.Errorf("username and password have to be set together")
+		}
+		return &http.BasicAuth{Username: cfg.Username, Password: cfg.Password}, nil
+	}
+	return nil, nil
+}
+
+func newSSHAuth(cfg AuthConfig, ep *transport.Endpoint) (transport.AuthMethod, error) {
+	key := []byte(cfg.SSHKey)
+	source := "inline key"
+	if len(key) == 0 {
+		path, err := sshKeyPath(cfg.SSHKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		key, err = ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SSH key: %s", err)
+		}
+		source = path
+	}
+
+	raw, err := parsePrivateKey(key, cfg.SSHKeyPassphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH key %s: %s", source, err)
+	}
+	signer, err := cryptossh.NewSignerFromKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH key %s: %s", source, err)
+	}
+
+	user := cfg.SSHUser
+	if user == "" {
+		user = ep.User
+	}
+	if user == "" {
+		user = defaultSSHUser
+	}
+
+	auth := &ssh.PublicKeys{User: user, Signer: signer}
+	if cfg.KnownHosts != "" {
+		auth.HostKeyCallback, err = ssh.NewKnownHostsCallback(cfg.KnownHosts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load known hosts %s: %s", cfg.KnownHosts, err)
+		}
+	}
+	return auth, nil
+}
+
+// sshKeyPath - configured key path or the first default key that exists
+func sshKeyPath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	dir := filepath.Join(os.Getenv("HOME"), ".ssh")
+	for _, name := range defaultSSHKeys {
+		candidate := filepath.Join(dir, name)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no SSH key configured and none of %s found in %s", strings.Join(defaultSSHKeys, ", "), dir)
+}
+
+// parsePrivateKey - parses PEM (optionally encrypted), PKCS#8 and unencrypted OpenSSH private keys
+func parsePrivateKey(key []byte, passphrase string) (interface{}, error) {
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded key found")
+	}
+	if block.Type == "PRIVATE KEY" {
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+
+	var (
+		raw interface{}
+		err error
+	)
+	if passphrase != "" {
+		raw, err = cryptossh.ParseRawPrivateKeyWithPassphrase(key, []byte(passphrase))
+	} else {
+		raw, err = cryptossh.ParseRawPrivateKey(key)
+	}
+	if err != nil && block.Type == "OPENSSH PRIVATE KEY" && strings.Contains(err.Error(), "encrypted") {
+		return nil, fmt.Errorf("encrypted keys in OpenSSH format are not supported, use an unencrypted key from a secret or a PEM encrypted RSA/ECDSA key")
+	}
+	return raw, err
+}
+
+// hostTransport - go-git uses one HTTP client for all repositories, CA bundles and proxies
+// are therefore looked up by host of the request. settings keeps the CA bundle and proxy
+// of every registered host, repositories on one host cannot use different ones.
+type hostTransport struct {
+	mu       sync.RWMutex
+	hosts    map[string]*nethttp.Transport
+	settings map[string]AuthConfig
+}
+
+var httpTransports = &hostTransport{
+	hosts:    make(map[string]*nethttp.Transport),
+	settings: make(map[string]AuthConfig),
+}
+var installHTTPTransport sync.Once
+
+func (t *hostTransport) RoundTrip(req *nethttp.Request) (*nethttp.Response, error) {
+	t.mu.RLock()
+	rt, ok := t.hosts[req.URL.Hostname()]
+	t.mu.RUnlock()
+	if !ok {
+		return nethttp.DefaultTransport.RoundTrip(req)
+	}
+	return rt.RoundTrip(req)
+}
+
+// registerHTTPTransport - routes requests to host through a transport trusting cfg.CAFile
+// and using cfg.Proxy, fails when another repository on host was registered with a different
+// CA bundle or proxy
+func registerHTTPTransport(host string, cfg AuthConfig) error {
+	settings := AuthConfig{CAFile: cfg.CAFile, Proxy: cfg.Proxy}
+	httpTransports.mu.Lock()
+	defer httpTransports.mu.Unlock()
+	if registered, ok := httpTransports.settings[host]; ok {
+		if registered != settings {
+			return fmt.Errorf("repositories on %s have to use the same CA bundle and proxy, got caFile '%s' and proxy '%s', already registered caFile '%s' and proxy '%s'",
+				host, cfg.CAFile, cfg.Proxy, registered.CAFile, registered.Proxy)
+		}
+		return nil
+	}
+	if cfg.CAFile == "" && cfg.Proxy == "" {
+		httpTransports.settings[host] = settings
+		return nil
+	}
+
+	rt := nethttp.DefaultTransport.(*nethttp.Transport).Clone()
+	if cfg.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		ca, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read CA bundle: %s", err)
+		}
+		if !pool.AppendCertsFromPEM(ca) {
+			return fmt.Errorf("no certificates found in CA bundle %s", cfg.CAFile)
+		}
+		rt.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	if cfg.Proxy != "" {
+		proxy, err := url.Parse(cfg.Proxy)
+		if err != nil || proxy.Host == "" {
+			return fmt.Errorf("invalid proxy URL '%s'", cfg.Proxy)
+		}
+		rt.Proxy = nethttp.ProxyURL(proxy)
+	}
+	httpTransports.hosts[host] = rt
+	httpTransports.settings[host] = settings
+
+	installHTTPTransport.Do(func() {
+		c := http.NewClient(&nethttp.Client{Transport: httpTransports})
+		client.InstallProtocol("http", c)
+		client.InstallProtocol("https", c)
+	})
+	return nil
+}
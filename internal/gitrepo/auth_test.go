@@ -0,0 +1,155 @@
+package gitrepo
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+)
+
+func generateSSHKey(t *testing.T, dir, keyType, passphrase string, format ...string) string {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not available")
+	}
+	path := filepath.Join(dir, "id_"+keyType)
+	args := append([]string{"-q", "-t", keyType, "-N", passphrase, "-f", path}, format...)
+	out, err := exec.Command("ssh-keygen", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", out)
+	}
+	return path
+}
+
+func TestNewAuthSSH(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	ed25519 := generateSSHKey(t, dir, "ed25519", "")
+	rsa := generateSSHKey(t, dir, "rsa", "secret", "-m", "PEM")
+	encrypted := filepath.Join(dir, "encrypted")
+	os.Mkdir(encrypted, 0755)
+	encryptedKey := generateSSHKey(t, encrypted, "ed25519", "secret")
+	inline, _ := ioutil.ReadFile(ed25519)
+	knownHosts := filepath.Join(dir, "known_hosts")
+	writeTestFile(t, knownHosts, "github.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl\n")
+
+	tests := []struct {
+		name    string
+		url     string
+		cfg     AuthConfig
+		user    string
+		wantErr string
+	}{
+		{name: "ed25519 path", url: "git@github.com:team/deployment.git", cfg: AuthConfig{SSHKeyPath: ed25519, KnownHosts: knownHosts}, user: "git"},
+		{name: "inline key, user from url", url: "ssh://deploy@github.com/team/deployment.git", cfg: AuthConfig{SSHKey: string(inline)}, user: "deploy"},
+		{name: "configured user", url: "github.com:team/deployment.git", cfg: AuthConfig{SSHKeyPath: ed25519, SSHUser: "bot"}, user: "bot"},
+		{name: "pem with passphrase", url: "git@github.com:team/deployment.git", cfg: AuthConfig{SSHKeyPath: rsa, SSHKeyPassphrase: "secret"}, user: "git"},
+		{name: "pem wrong passphrase", url: "git@github.com:team/deployment.git", cfg: AuthConfig{SSHKeyPath: rsa, SSHKeyPassphrase: "wrong"}, wantErr: "failed to parse SSH key"},
+		{name: "encrypted openssh", url: "git@github.com:team/deployment.git", cfg: AuthConfig{SSHKeyPath: encryptedKey, SSHKeyPassphrase: "secret"}, wantErr: "not supported"},
+		{name: "missing key", url: "git@github.com:team/deployment.git", cfg: AuthConfig{SSHKeyPath: filepath.Join(dir, "missing")}, wantErr: "failed to read SSH key"},
+		{name: "missing known hosts", url: "git@github.com:team/deployment.git", cfg: AuthConfig{SSHKeyPath: ed25519, KnownHosts: filepath.Join(dir, "missing")}, wantErr: "failed to load known hosts"},
+		{name: "token for ssh", url: "git@github.com:team/deployment.git", cfg: AuthConfig{SSHKeyPath: ed25519, Token: "token"}, wantErr: "only apply to HTTPS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := newAuth(tt.cfg, tt.url)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing '%s', got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			keys, ok := auth.(*ssh.PublicKeys)
+			if !ok || keys.Signer == nil {
+				t.Fatalf("expected public key auth, got %v", auth)
+			}
+			if keys.User != tt.user {
+				t.Errorf("unexpected user %s, want %s", keys.User, tt.user)
+			}
+			if (tt.cfg.KnownHosts != "") != (keys.HostKeyCallback != nil) {
+				t.Errorf("host key callback should only be set with known hosts")
+			}
+		})
+	}
+}
+
+func TestNewAuthHTTPS(t *testing.T) {
+	url := "https://github.com/team/deployment.git"
+
+	auth, err := newAuth(AuthConfig{Token: "token"}, url)
+	if _, ok := auth.(*http.TokenAuth); err != nil || !ok {
+		t.Errorf("expected token auth, got %v, %v", auth, err)
+	}
+	auth, err = newAuth(AuthConfig{Username: "x-access-token", Password: "token"}, url)
+	if _, ok := auth.(*http.BasicAuth); err != nil || !ok {
+		t.Errorf("expected basic auth, got %v, %v", auth, err)
+	}
+	auth, err = newAuth(AuthConfig{}, url)
+	if auth != nil || err != nil {
+		t.Errorf("expected anonymous access, got %v, %v", auth, err)
+	}
+
+	for _, cfg := range []AuthConfig{
+		{Token: "token", Username: "user", Password: "password"},
+		{Username: "user"},
+		{SSHKeyPath: "/root/.ssh/id_rsa"},
+		{Proxy: "::invalid"},
+		{CAFile: "/missing/ca.pem"},
+	} {
+		_, err = newAuth(cfg, url)
+		if err == nil {
+			t.Errorf("expected error for %+v", cfg)
+		}
+	}
+}
+
+func TestHTTPTransportCAFile(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	ts := httptest.NewTLSServer(nethttp.HandlerFunc(func(resp nethttp.ResponseWriter, req *nethttp.Request) {}))
+	defer ts.Close()
+
+	req, _ := nethttp.NewRequest("GET", ts.URL, nil)
+	_, err := httpTransports.RoundTrip(req)
+	if err == nil {
+		t.Fatalf("expected untrusted certificate to be rejected")
+	}
+
+	ca := filepath.Join(dir, "ca.pem")
+	writeTestFile(t, ca, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})))
+	_, err = newAuth(AuthConfig{CAFile: ca}, ts.URL+"/team/deployment.git")
+	if err != nil {
+		t.Fatalf("failed to set up auth: %s", err)
+	}
+
+	resp, err := httpTransports.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("request with CA bundle failed: %s", err)
+	}
+	resp.Body.Close()
+
+	// a second repository on the same host cannot use other settings
+	_, err = newAuth(AuthConfig{CAFile: ca}, ts.URL+"/team/other.git")
+	if err != nil {
+		t.Errorf("same settings for the host should be accepted: %s", err)
+	}
+	for _, cfg := range []AuthConfig{{}, {CAFile: ca, Proxy: "http://proxy.internal:3128"}} {
+		_, err = newAuth(cfg, ts.URL+"/team/other.git")
+		if err == nil {
+			t.Errorf("expected conflicting settings %+v to be rejected", cfg)
+		}
+	}
+}
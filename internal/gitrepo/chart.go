@@ -0,0 +1,161 @@
+package gitrepo
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/alwinius/bow/provider/helm"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/ignore"
+)
+
+// ChartConfigFile - render settings of a chart, placed next to its Chart.yaml
+const ChartConfigFile = ".bow.yaml"
+
+// chartConfig - content of ChartConfigFile, mirrors the flags of `helm template`
+type chartConfig struct {
+	ReleaseName string `json:"releaseName"`
+	// Namespace is passed to the templates and set on resources without a namespace
+	Namespace string `json:"namespace"`
+	// ValuesFiles are merged in order on top of the chart's values.yaml, relative to the chart
+	ValuesFiles []string `json:"valuesFiles"`
+	// Set are overrides in --set format, ie: image.pullPolicy=Always. Images pinned here are
+	// not updated by bow.
+	Set         []string `json:"set"`
+	KubeVersion string   `json:"kubeVersion"`
+	APIVersions []string `json:"apiVersions"`
+}
+
+// loadChartConfig - reads ChartConfigFile of the chart in dir, a missing file results in the defaults
+func (r *Repo) loadChartConfig(dir string) (*chartConfig, error) {
+	cfg := &chartConfig{}
+	content, err := r.readFile(path.Join(dir, ChartConfigFile))
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = yaml.Unmarshal(content, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", path.Join(dir, ChartConfigFile), err)
+	}
+	return cfg, nil
+}
+
+// valuesFiles - values files of the chart in dir relative to the repository root
+func (c *chartConfig) valuesFiles(dir string) ([]string, error) {
+	var files []string
+	for _, f := range c.ValuesFiles {
+		name := path.Join(dir, f)
+		if name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("values file %s is outside of the repository", f)
+		}
+		files = append(files, name)
+	}
+	return files, nil
+}
+
+// renderChart - renders the chart in dir (relative to the repository root) with its chart config
+func (r *Repo) renderChart(dir string) ([]rendered, error) {
+	cfg, err := r.loadChartConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+	valuesFiles, err := cfg.valuesFiles(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path.Join(dir, ChartConfigFile), err)
+	}
+
+	opts := helm.RenderOptions{
+		ReleaseName: cfg.ReleaseName,
+		Namespace:   cfg.Namespace,
+		Set:         cfg.Set,
+		KubeVersion: cfg.KubeVersion,
+		APIVersions: cfg.APIVersions,
+	}
+	for _, name := range valuesFiles {
+		content, err := r.readFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read values file: %s", err)
+		}
+		opts.Values = append(opts.Values, content)
+	}
+
+	files, err := r.chartFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	manifests, err := helm.ProcessChartFiles(files, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []rendered
+	for _, m := range manifests {
+		sources := chartSources(dir, m)
+		for _, name := range valuesFiles {
+			if !contains(sources, name) {
+				sources = append(sources, name)
+			}
+		}
+		result = append(result, rendered{content: m.Content, sources: sources, namespace: cfg.Namespace})
+	}
+	return result, nil
+}
+
+// chartFiles - reads the chart in dir (relative to the repository root), honoring its .helmignore
+func (r *Repo) chartFiles(dir string) ([]*chartutil.BufferedFile, error) {
+	rules := ignore.Empty()
+	content, err := r.readFile(path.Join(dir, ignore.HelmIgnore))
+	if err == nil {
+		rules, err = ignore.Parse(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+	}
+	rules.AddDefaults()
+
+	var files []*chartutil.BufferedFile
+	var walk func(name string) error
+	walk = func(name string) error {
+		infos, err := r.fs.ReadDir(path.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		for _, info := range infos {
+			n := path.Join(name, info.Name())
+			if rules.Ignore(n, info) {
+				continue
+			}
+			if info.IsDir() {
+				err = walk(n)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			data, err := r.readFile(path.Join(dir, n))
+			if err != nil {
+				return fmt.Errorf("error reading %s: %s", n, err)
+			}
+			files = append(files, &chartutil.BufferedFile{Name: n, Data: data})
+		}
+		return nil
+	}
+	return files, walk("")
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
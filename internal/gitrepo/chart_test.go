@@ -0,0 +1,119 @@
+package gitrepo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alwinius/bow/types"
+
+	"k8s.io/api/apps/v1"
+)
+
+const chartConfigTemplate = `apiVersion: {{ if .Capabilities.APIVersions.Has "apps/v1" }}apps/v1{{ else }}extensions/v1beta1{{ end }}
+kind: Deployment
+metadata:
+  name: {{ .Release.Name }}
+  annotations:
+    kube-minor: "{{ .Capabilities.KubeVersion.Minor }}"
+    release-namespace: {{ .Release.Namespace }}
+spec:
+  replicas: {{ .Values.replicas }}
+  template:
+    spec:
+      containers:
+      - name: web
+        image: {{ .Values.image.repository }}:{{ .Values.image.tag }}
+`
+
+func TestRenderChartConfig(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRemote(t, dir, map[string]string{
+		"chart/Chart.yaml":                "name: web\nversion: 0.1.0\n",
+		"chart/values.yaml":               "replicas: 1\nimage:\n  repository: nginx\n  tag: 1.15.0\n",
+		"chart/templates/deployment.yaml": chartConfigTemplate,
+		"chart/.bow.yaml": `releaseName: web-prod
+namespace: prod
+valuesFiles:
+- ../env/prod.yaml
+set:
+- replicas=3
+kubeVersion: "1.14"
+apiVersions:
+- apps/v1
+`,
+		"env/prod.yaml": "image:\n  tag: 1.16.0\n",
+	})
+	repo := newTestRepo(t, dir, remote)
+	repo.ChartPath = "chart"
+
+	resources, err := renderResources(repo)
+	if err != nil {
+		t.Fatalf("failed to render: %s", err)
+	}
+	if len(resources) != 1 {
+		t.Fatalf("expected one resource, got %d", len(resources))
+	}
+	deployment, ok := resources[0].(*v1.Deployment)
+	if !ok {
+		t.Fatalf("expected apps/v1 deployment, got %T", resources[0])
+	}
+	if deployment.Name != "web-prod" || deployment.Namespace != "prod" {
+		t.Errorf("unexpected identity %s/%s", deployment.Namespace, deployment.Name)
+	}
+	if deployment.Annotations["kube-minor"] != "14" || deployment.Annotations["release-namespace"] != "prod" {
+		t.Errorf("unexpected annotations: %v", deployment.Annotations)
+	}
+	if *deployment.Spec.Replicas != 3 {
+		t.Errorf("set value not applied, replicas: %d", *deployment.Spec.Replicas)
+	}
+	if img := deployment.Spec.Template.Spec.Containers[0].Image; img != "nginx:1.16.0" {
+		t.Errorf("values file not applied, image: %s", img)
+	}
+	files := strings.Split(deployment.Annotations[types.BowSourceFilesAnnotation], ",")
+	if !reflect.DeepEqual(files, []string{"chart/templates/deployment.yaml", "chart/values.yaml", "env/prod.yaml"}) {
+		t.Errorf("unexpected sources: %v", files)
+	}
+
+	// the tag is set in the overlay, values.yaml stays untouched
+	_, failed, err := repo.Apply([]*Change{{Image: "nginx:1.16.0", Repository: "nginx", OldTag: "1.16.0", NewTag: "1.17.0", Sources: files}})
+	if err != nil || len(failed) > 0 {
+		t.Fatalf("failed to apply: %v, %v", err, failed)
+	}
+	if content := runGit(t, dir, "--git-dir", remote, "show", "master:env/prod.yaml"); content != "image:\n  tag: 1.17.0\n" {
+		t.Errorf("unexpected overlay: %s", content)
+	}
+	if content := runGit(t, dir, "--git-dir", remote, "show", "master:chart/values.yaml"); !strings.Contains(content, "tag: 1.15.0") {
+		t.Errorf("defaults were changed: %s", content)
+	}
+}
+
+func TestRenderChartConfigInvalid(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRemote(t, dir, map[string]string{
+		"chart/Chart.yaml":                "name: web\nversion: 0.1.0\n",
+		"chart/values.yaml":               "replicas: 1\nimage:\n  repository: nginx\n  tag: 1.15.0\n",
+		"chart/templates/deployment.yaml": chartConfigTemplate,
+	})
+	repo := newTestRepo(t, dir, remote)
+
+	for config, wantErr := range map[string]string{
+		"valuesFiles: [missing.yaml]\n":   "failed to read values file",
+		"valuesFiles: [../../x.yaml]\n":   "outside of the repository",
+		"set: ['replicas']\n":             "failed to parse set value",
+		"kubeVersion: latest\n":           "could not parse a kubernetes version",
+		"releaseName: [not, a, string]\n": "failed to parse chart/.bow.yaml",
+	} {
+		writeTestFile(t, filepath.Join(repo.LocalPath, "chart", ChartConfigFile), config)
+		_, err := repo.render(RenderPath{Path: "chart"})
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("%s: expected error containing '%s', got %v", strings.TrimSpace(config), wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package gitrepo
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+	"time"
+
+	"golang.org/x/crypto/openpgp"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// defaultMessageTemplate - commit message used when no template is configured
+const defaultMessageTemplate = "updating {{ .Image }} to {{ .NewTag }}"
+
+// CommitConfig - identity, message and signing of the commits created by bow
+type CommitConfig struct {
+	AuthorName  string `json:"authorName"`
+	AuthorEmail string `json:"authorEmail"`
+	// MessageTemplate is a text/template executed with a Change, ie:
+	// "chore(deps): update {{ .Repository }} to {{ .NewTag }}"
+	MessageTemplate string `json:"messageTemplate"`
+	// Trailers appends Approved-by, Trigger and Release-Notes trailers to the message
+	Trailers bool `json:"trailers"`
+	// SigningKey is the path of an armored OpenPGP private key or an SSH private key
+	SigningKey           string `json:"signingKey"`
+	SigningKeyPassphrase string `json:"signingKeyPassphrase"`
+}
+
+// Change - update written to the repository, passed to commit message templates
+type Change struct {
+	// Image as referenced by the resource before the update, ie: nginx:1.15.0
+	Image string
+	// Repository of the image without tag, ie: index.docker.io/library/nginx
+	Repository string
+	OldTag     string
+	NewTag     string
+	// Resource identifier, ie: deployment/default/web
+	Resource string
+	// Sources are the files the resource was rendered from, relative to the repository root
+	Sources      []string
+	Approvers    []string
+	Trigger      string
+	ReleaseNotes string
+}
+
+// NewImage - repository with the new tag
+func (c *Change) NewImage() string {
+	return c.Repository + ":" + c.NewTag
+}
+
+// committer - signs commits, either with go-git's OpenPGP support or by rewriting
+// the commit with an SSH signature
+type committer struct {
+	name    string
+	email   string
+	message *template.Template
+	config  CommitConfig
+
+	pgpKey *openpgp.Entity
+	sshKey *sshSigner
+}
+
+func newCommitter(cfg *CommitConfig) (*committer, error) {
+	c := &committer{
+		name:  committerName,
+		email: committerEMail,
+	}
+	if cfg == nil {
+		cfg = &CommitConfig{}
+	}
+	c.config = *cfg
+	if cfg.AuthorName != "" {
+		c.name = cfg.AuthorName
+	}
+	if cfg.AuthorEmail != "" {
+		c.email = cfg.AuthorEmail
+	}
+
+	text := cfg.MessageTemplate
+	if text == "" {
+		text = defaultMessageTemplate
+	}
+	var err error
+	c.message, err = template.New("commit").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid commit message template: %s", err)
+	}
+
+	if cfg.SigningKey != "" {
+		key, err := ioutil.ReadFile(cfg.SigningKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signing key: %s", err)
+		}
+		if bytes.Contains(key, []byte("BEGIN PGP PRIVATE KEY BLOCK")) {
+			c.pgpKey, err = readPGPKey(key, cfg.SigningKeyPassphrase)
+		} else {
+			c.sshKey, err = newSSHSigner(key, cfg.SigningKeyPassphrase)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load signing key %s: %s", cfg.SigningKey, err)
+		}
+	}
+
+	return c, nil
+}
+
+func readPGPKey(key []byte, passphrase string) (*openpgp.Entity, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(key))
+	if err != nil {
+		return nil, err
+	}
+	if len(entities) == 0 || entities[0].PrivateKey == nil {
+		return nil, fmt.Errorf("no private key found")
+	}
+	entity := entities[0]
+	if entity.PrivateKey.Encrypted {
+		err = entity.PrivateKey.Decrypt([]byte(passphrase))
+		if err != nil {
+			return nil, err
+		}
+		for _, sub := range entity.Subkeys {
+			if sub.PrivateKey != nil && sub.PrivateKey.Encrypted {
+				sub.PrivateKey.Decrypt([]byte(passphrase))
+			}
+		}
+	}
+	return entity, nil
+}
+
+// messageFor - renders commit message of changes. A single change uses the template as is,
+// batches get a summary line followed by the first line of every rendered change.
+func (c *committer) messageFor(changes ...*Change) (string, error) {
+	var lines []string
+	for _, change := range changes {
+		var buf bytes.Buffer
+		err := c.message.Execute(&buf, change)
+		if err != nil {
+			return "", fmt.Errorf("failed to render commit message: %s", err)
+		}
+		lines = append(lines, strings.TrimSpace(buf.String()))
+	}
+
+	var msg string
+	if len(lines) == 1 {
+		msg = lines[0]
+	} else {
+		msg = fmt.Sprintf("updating %d images\n", len(lines))
+		for _, line := range lines {
+			msg += "\n- " + strings.SplitN(line, "\n", 2)[0]
+		}
+	}
+
+	if c.config.Trailers {
+		var trailers []string
+		seen := make(map[string]bool)
+		add := func(trailer string) {
+			if !seen[trailer] {
+				seen[trailer] = true
+				trailers = append(trailers, trailer)
+			}
+		}
+		for _, change := range changes {
+			for _, approver := range change.Approvers {
+				add("Approved-by: " + approver)
+			}
+			if change.Trigger != "" {
+				add("Trigger: " + change.Trigger)
+			}
+			if change.ReleaseNotes != "" {
+				add("Release-Notes: " + change.ReleaseNotes)
+			}
+		}
+		if len(trailers) > 0 {
+			msg += "\n\n" + strings.Join(trailers, "\n")
+		}
+	}
+	return msg + "\n", nil
+}
+
+// commit - commits all changes in the worktree and signs the commit if a key is configured
+func (c *committer) commit(repository *git.Repository, w *git.Worktree, msg string) (plumbing.Hash, error) {
+	signature := &object.Signature{
+		Name:  c.name,
+		Email: c.email,
+		When:  time.Now(),
+	}
+	hash, err := w.Commit(msg, &git.CommitOptions{
+		All:       true,
+		Author:    signature,
+		Committer: signature,
+		SignKey:   c.pgpKey,
+	})
+	if err != nil || c.sshKey == nil {
+		return hash, err
+	}
+
+	return c.signSSH(repository, hash)
+}
+
+// signSSH - replaces commit with a copy carrying an SSH signature and moves HEAD to it
+func (c *committer) signSSH(repository *git.Repository, hash plumbing.Hash) (plumbing.Hash, error) {
+	commit, err := repository.CommitObject(hash)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	payload := &plumbing.MemoryObject{}
+	err = commit.Encode(payload)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	reader, err := payload.Reader()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	commit.PGPSignature, err = c.sshKey.sign(data)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	obj := repository.Storer.NewEncodedObject()
+	err = commit.Encode(obj)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	signed, err := repository.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	head, err := repository.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	name := plumbing.HEAD
+	if head.Type() != plumbing.HashReference {
+		name = head.Target()
+	}
+	err = repository.Storer.SetReference(plumbing.NewHashReference(name, signed))
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return signed, nil
+}
@@ -0,0 +1,231 @@
+package gitrepo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+var testChange = &Change{
+	Image:        "nginx:1.0.0",
+	Repository:   "index.docker.io/library/nginx",
+	OldTag:       "1.0.0",
+	NewTag:       "1.1.0",
+	Resource:     "deployment/default/web",
+	Approvers:    []string{"alice", "bob"},
+	Trigger:      "poll",
+	ReleaseNotes: "https://example.com/releases/1.1.0",
+}
+
+func TestCommitMessage(t *testing.T) {
+	c, err := newCommitter(nil)
+	if err != nil {
+		t.Fatalf("failed to create committer: %s", err)
+	}
+	msg, err := c.messageFor(testChange)
+	if err != nil {
+		t.Fatalf("failed to render message: %s", err)
+	}
+	if msg != "updating nginx:1.0.0 to 1.1.0\n" {
+		t.Errorf("unexpected default message: %q", msg)
+	}
+
+	c, err = newCommitter(&CommitConfig{
+		MessageTemplate: "chore(deps): update {{ .Repository }} to {{ .NewTag }}\n\nUpdates {{ .Resource }} from {{ .OldTag }}.",
+		Trailers:        true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create committer: %s", err)
+	}
+	msg, err = c.messageFor(testChange)
+	if err != nil {
+		t.Fatalf("failed to render message: %s", err)
+	}
+	expected := `chore(deps): update index.docker.io/library/nginx to 1.1.0
+
+Updates deployment/default/web from 1.0.0.
+
+Approved-by: alice
+Approved-by: bob
+Trigger: poll
+Release-Notes: https://example.com/releases/1.1.0
+`
+	if msg != expected {
+		t.Errorf("unexpected message:\n%s", msg)
+	}
+
+	_, err = newCommitter(&CommitConfig{MessageTemplate: "{{ .Missing"})
+	if err == nil {
+		t.Errorf("expected error for invalid template")
+	}
+}
+
+func TestCommitMessageBatch(t *testing.T) {
+	c, err := newCommitter(&CommitConfig{Trailers: true})
+	if err != nil {
+		t.Fatalf("failed to create committer: %s", err)
+	}
+	redis := &Change{Image: "redis:5.0.0", Repository: "index.docker.io/library/redis", OldTag: "5.0.0", NewTag: "5.0.1", Approvers: []string{"bob"}, Trigger: "poll"}
+	msg, err := c.messageFor(testChange, redis)
+	if err != nil {
+		t.Fatalf("failed to render message: %s", err)
+	}
+	expected := `updating 2 images
+
+- updating nginx:1.0.0 to 1.1.0
+- updating redis:5.0.0 to 5.0.1
+
+Approved-by: alice
+Approved-by: bob
+Trigger: poll
+Release-Notes: https://example.com/releases/1.1.0
+`
+	if msg != expected {
+		t.Errorf("unexpected message:\n%s", msg)
+	}
+}
+
+func TestApplyBatch(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRemote(t, dir, map[string]string{
+		"web.yaml":   "image: nginx:1.0.0\n",
+		"cache.yaml": "image: redis:5.0.0\n",
+	})
+	repo := newTestRepo(t, dir, remote)
+
+	missing := &Change{Image: "mysql:8.0.0", Repository: "mysql", OldTag: "8.0.0", NewTag: "8.0.1", Sources: []string{"web.yaml"}}
+	commit, failed, err := repo.Apply([]*Change{
+		{Image: "nginx:1.0.0", Repository: "nginx", OldTag: "1.0.0", NewTag: "1.1.0", Sources: []string{"web.yaml"}},
+		missing,
+		{Image: "redis:5.0.0", Repository: "redis", OldTag: "5.0.0", NewTag: "5.0.1", Sources: []string{"cache.yaml"}},
+	})
+	if len(failed) != 1 || failed[missing] == nil {
+		t.Errorf("expected mysql update to fail, got %v", failed)
+	}
+	if err != nil {
+		t.Fatalf("failed to commit: %s", err)
+	}
+
+	if head := runGit(t, dir, "--git-dir", remote, "rev-parse", "master"); strings.TrimSpace(head) != commit {
+		t.Errorf("pushed commit %s, expected %s", head, commit)
+	}
+	if count := runGit(t, dir, "--git-dir", remote, "rev-list", "--count", "master"); strings.TrimSpace(count) != "2" {
+		t.Errorf("expected a single commit on top of the initial one, got %s commits", count)
+	}
+	for file, content := range map[string]string{"web.yaml": "image: nginx:1.1.0\n", "cache.yaml": "image: redis:5.0.1\n"} {
+		if got := runGit(t, dir, "--git-dir", remote, "show", "master:"+file); got != content {
+			t.Errorf("unexpected content of %s: %s", file, got)
+		}
+	}
+}
+
+func TestCommitIdentityAndPGPSignature(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	entity, err := openpgp.NewEntity("bow bot", "", "bot@example.com", nil)
+	if err != nil {
+		t.Fatalf("failed to create key: %s", err)
+	}
+	var key bytes.Buffer
+	w, _ := armor.Encode(&key, openpgp.PrivateKeyType, nil)
+	entity.SerializePrivate(w, nil)
+	w.Close()
+	keyPath := filepath.Join(dir, "key.asc")
+	writeTestFile(t, keyPath, key.String())
+
+	remote := newTestRemote(t, dir, map[string]string{"deployment.yaml": "image: nginx:1.0.0\n"})
+	repo := newTestRepo(t, dir, remote)
+	repo.committer, err = newCommitter(&CommitConfig{AuthorName: "bow bot", AuthorEmail: "bot@example.com", SigningKey: keyPath})
+	if err != nil {
+		t.Fatalf("failed to create committer: %s", err)
+	}
+
+	writeTestFile(t, filepath.Join(repo.LocalPath, "deployment.yaml"), "image: nginx:1.1.0\n")
+	_, err = repo.CommitAndPushAll(testChange)
+	if err != nil {
+		t.Fatalf("failed to commit: %s", err)
+	}
+
+	commit := headCommit(t, repo)
+	if commit.Author.Name != "bow bot" || commit.Committer.Email != "bot@example.com" {
+		t.Errorf("unexpected identity: %s, %s", commit.Author, commit.Committer)
+	}
+	if commit.PGPSignature == "" {
+		t.Fatalf("commit is not signed")
+	}
+
+	signature := commit.PGPSignature
+	commit.PGPSignature = ""
+	payload := &plumbing.MemoryObject{}
+	commit.Encode(payload)
+	reader, _ := payload.Reader()
+	_, err = openpgp.CheckArmoredDetachedSignature(openpgp.EntityList{entity}, reader, strings.NewReader(signature))
+	if err != nil {
+		t.Errorf("invalid signature: %s", err)
+	}
+}
+
+func TestCommitSSHSignature(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not available")
+	}
+
+	for _, keyType := range []string{"ed25519", "rsa", "ecdsa"} {
+		t.Run(keyType, func(t *testing.T) {
+			dir := newTestDir(t)
+			defer os.RemoveAll(dir)
+
+			keyPath := filepath.Join(dir, "id_"+keyType)
+			out, err := exec.Command("ssh-keygen", "-q", "-t", keyType, "-N", "", "-m", "PEM", "-f", keyPath).CombinedOutput()
+			if err != nil {
+				t.Fatalf("failed to generate key: %s", out)
+			}
+			public, _ := ioutil.ReadFile(keyPath + ".pub")
+			allowed := filepath.Join(dir, "allowed_signers")
+			writeTestFile(t, allowed, "bot@example.com "+string(public))
+
+			remote := newTestRemote(t, dir, map[string]string{"deployment.yaml": "image: nginx:1.0.0\n"})
+			repo := newTestRepo(t, dir, remote)
+			repo.committer, err = newCommitter(&CommitConfig{AuthorEmail: "bot@example.com", SigningKey: keyPath})
+			if err != nil {
+				t.Fatalf("failed to create committer: %s", err)
+			}
+
+			writeTestFile(t, filepath.Join(repo.LocalPath, "deployment.yaml"), "image: nginx:1.1.0\n")
+			_, err = repo.CommitAndPushAll(testChange)
+			if err != nil {
+				t.Fatalf("failed to commit: %s", err)
+			}
+
+			// the signed commit was pushed, not the unsigned one
+			cmd := exec.Command("git", "--git-dir", remote, "-c", "gpg.ssh.allowedSignersFile="+allowed, "verify-commit", "master")
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("signature not verified: %s", out)
+			}
+		})
+	}
+}
+
+func headCommit(t *testing.T, repo *Repo) *object.Commit {
+	head, err := repo.repository.Head()
+	if err != nil {
+		t.Fatalf("failed to get HEAD: %s", err)
+	}
+	commit, err := repo.repository.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatalf("failed to get commit: %s", err)
+	}
+	return commit
+}
@@ -0,0 +1,209 @@
+package gitrepo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+// Config - list of repositories that bow should watch, usually loaded
+// from the file given in REPO_CONFIG
+type Config struct {
+	Repositories []RepoConfig `json:"repositories"`
+	// Promotions apply versions deployed to one environment to another, see LoadPromotions
+	Promotions []PromotionConfig `json:"promotions"`
+}
+
+// RepoConfig - settings of a single watched repository
+type RepoConfig struct {
+	// Name is used to route update plans back to this repository,
+	// defaults to the last element of the URL
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	Branch    string `json:"branch"`
+	ChartPath string `json:"chartPath"`
+	// Render selects how ChartPath is rendered: auto (default), helm or manifests
+	Render string `json:"render"`
+	// Paths lists several directories with their own render mode, replaces ChartPath
+	Paths []RenderPath `json:"paths"`
+	// Include globs (ie: apps/*, envs/**) discover every chart, kustomization and manifest
+	// directory they match, replaces ChartPath. Exclude globs skip directories and their content.
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+	// AuthConfig fields (username, password, token, sshKeyPath, ...) are set inline
+	AuthConfig
+	// LocalPath is the checkout directory, defaults to <base dir>/<name>
+	LocalPath string `json:"localPath"`
+	// Storage is disk (default) or memory, the latter keeps the clone in memory only
+	Storage string `json:"storage"`
+	// Shallow fetches only the latest commit of the branch
+	Shallow bool `json:"shallow"`
+
+	// Interval between polls of the remote (ie: 5m), defaults to 30s
+	Interval string `json:"interval"`
+	// WebhookSecret verifies push webhooks sent to /v1/webhooks/git
+	WebhookSecret string `json:"webhookSecret"`
+	// BatchWindow collects approved updates for this long (ie: 2m) and commits them together,
+	// every update is committed on its own if empty
+	BatchWindow string `json:"batchWindow"`
+
+	PullRequests *PullRequestConfig `json:"pullRequests"`
+	Commit       *CommitConfig      `json:"commit"`
+}
+
+// LoadConfig - reads repository configuration file and creates a Repo for every entry.
+// Relative or empty local paths are placed below baseDir.
+func LoadConfig(path string, baseDir string) ([]*Repo, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository config: %s", err)
+	}
+
+	var cfg Config
+	err = yaml.Unmarshal(b, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse repository config: %s", err)
+	}
+
+	if len(cfg.Repositories) == 0 {
+		return nil, fmt.Errorf("no repositories defined in %s", path)
+	}
+
+	var repos []*Repo
+	names := make(map[string]bool)
+	for _, rc := range cfg.Repositories {
+		repo, err := NewRepo(rc, baseDir)
+		if err != nil {
+			return nil, err
+		}
+		if names[repo.Name] {
+			return nil, fmt.Errorf("duplicate repository name '%s', set a unique name for every repository", repo.Name)
+		}
+		names[repo.Name] = true
+		repos = append(repos, repo)
+	}
+
+	return repos, nil
+}
+
+// NewRepo - creates repository from config, filling in defaults
+func NewRepo(rc RepoConfig, baseDir string) (*Repo, error) {
+	if rc.URL == "" {
+		return nil, fmt.Errorf("repository URL cannot be empty")
+	}
+
+	if rc.Name == "" {
+		rc.Name = nameFromURL(rc.URL)
+	}
+
+	if rc.Branch == "" {
+		rc.Branch = "master"
+	}
+
+	localPath := rc.LocalPath
+	if localPath == "" {
+		localPath = rc.Name
+	}
+	if !filepath.IsAbs(localPath) {
+		localPath = filepath.Join(baseDir, localPath)
+	}
+	localPath, err := filepath.Abs(localPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !validStorage(rc.Storage) {
+		return nil, fmt.Errorf("repository %s: unknown storage '%s', use %s or %s", rc.Name, rc.Storage, StorageDisk, StorageMemory)
+	}
+
+	if !validRenderMode(rc.Render) {
+		return nil, fmt.Errorf("repository %s: unknown render mode '%s'", rc.Name, rc.Render)
+	}
+	var paths []RenderPath
+	for _, p := range rc.Paths {
+		if !validRenderMode(p.Render) {
+			return nil, fmt.Errorf("repository %s: unknown render mode '%s' for path %s", rc.Name, p.Render, p.Path)
+		}
+		paths = append(paths, RenderPath{Path: strings.Trim(p.Path, "/"), Render: p.Render})
+	}
+
+	for _, pattern := range append(append([]string{}, rc.Include...), rc.Exclude...) {
+		if err := validGlob(pattern); err != nil {
+			return nil, fmt.Errorf("repository %s: %s", rc.Name, err)
+		}
+	}
+
+	interval := DefaultInterval
+	if rc.Interval != "" {
+		interval, err = time.ParseDuration(rc.Interval)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("repository %s: invalid interval '%s'", rc.Name, rc.Interval)
+		}
+	}
+
+	var batchWindow time.Duration
+	if rc.BatchWindow != "" {
+		batchWindow, err = time.ParseDuration(rc.BatchWindow)
+		if err != nil || batchWindow < 0 {
+			return nil, fmt.Errorf("repository %s: invalid batch window '%s'", rc.Name, rc.BatchWindow)
+		}
+	}
+
+	repo := &Repo{
+		Name:      rc.Name,
+		URL:       rc.URL,
+		Branch:    plumbing.NewBranchReferenceName(rc.Branch),
+		ChartPath: strings.Trim(rc.ChartPath, "/"),
+		LocalPath: localPath,
+		Storage:   rc.Storage,
+		Shallow:   rc.Shallow,
+		Render:    rc.Render,
+		Paths:     paths,
+		Include:   rc.Include,
+		Exclude:   rc.Exclude,
+		Interval:  interval,
+
+		WebhookSecret: rc.WebhookSecret,
+		BatchWindow:   batchWindow,
+	}
+
+	repo.auth, err = newAuth(rc.AuthConfig, rc.URL)
+	if err != nil {
+		return nil, fmt.Errorf("repository %s: %s", rc.Name, err)
+	}
+
+	repo.committer, err = newCommitter(rc.Commit)
+	if err != nil {
+		return nil, fmt.Errorf("repository %s: %s", rc.Name, err)
+	}
+
+	if rc.PullRequests != nil {
+		repo.forgeKind = rc.PullRequests.Forge
+	}
+	if rc.PullRequests != nil && rc.PullRequests.Enabled {
+		repo.forge, err = newForgeClient(rc.PullRequests, rc.URL)
+		if err != nil {
+			return nil, fmt.Errorf("repository %s: %s", rc.Name, err)
+		}
+	}
+
+	return repo, nil
+}
+
+// nameFromURL - turns git@host:org/deployment.git or https://host/org/deployment
+// into "deployment"
+func nameFromURL(url string) string {
+	name := strings.TrimSuffix(strings.TrimRight(url, "/"), ".git")
+	if i := strings.LastIndexAny(name, "/:"); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "" {
+		return "default"
+	}
+	return name
+}
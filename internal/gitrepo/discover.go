@@ -0,0 +1,153 @@
+package gitrepo
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// discoverPaths - configured paths plus every app found by discover, configured paths keep
+// their render mode
+func (r *Repo) discoverPaths() ([]RenderPath, error) {
+	paths := append([]RenderPath{}, r.Paths...)
+	discovered, err := r.discover()
+	for _, p := range discovered {
+		if !r.configured(p.Path) {
+			paths = append(paths, p)
+		}
+	}
+	return paths, err
+}
+
+func (r *Repo) configured(dir string) bool {
+	for _, p := range r.Paths {
+		if p.Path == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// discover - finds charts, kustomizations and directories of plain manifests matching the
+// include globs. Charts are not searched for nested apps. Below a kustomization only nested
+// kustomizations and charts are apps, plain YAML there is usually one of its resources. Hidden
+// and excluded directories are skipped along with everything below them, so are directories that
+// cannot be read.
+func (r *Repo) discover() ([]RenderPath, error) {
+	var paths []RenderPath
+	var walk func(dir string, inKustomization bool) error
+	walk = func(dir string, inKustomization bool) error {
+		if dir != "" && matchAny(r.Exclude, dir) {
+			return nil
+		}
+		readDir := dir
+		if readDir == "" {
+			readDir = "."
+		}
+		infos, err := r.fs.ReadDir(readDir)
+		if err != nil && dir != "" {
+			log.WithFields(log.Fields{
+				"error": err,
+				"repo":  r.Name,
+				"dir":   dir,
+			}).Warn("gitrepo.discover: failed to read directory, skipping it")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+
+		var mode string
+		var manifests bool
+		var dirs []string
+		for _, info := range infos {
+			name := info.Name()
+			switch {
+			case strings.HasPrefix(name, "."):
+			case info.IsDir():
+				dirs = append(dirs, path.Join(dir, name))
+			case name == "Chart.yaml":
+				mode = RenderHelm
+			case isKustomization(name):
+				if mode == "" {
+					mode = RenderKustomize
+				}
+			case path.Ext(name) == ".yaml" || path.Ext(name) == ".yml":
+				manifests = true
+			}
+		}
+		if mode == "" && manifests && !inKustomization {
+			mode = RenderManifests
+		}
+
+		if mode != "" && matchAny(r.Include, dir) {
+			paths = append(paths, RenderPath{Path: dir, Render: mode, flat: mode == RenderManifests})
+		}
+		if mode == RenderHelm {
+			return nil
+		}
+		for _, d := range dirs {
+			err = walk(d, inKustomization || mode == RenderKustomize)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return paths, walk("", false)
+}
+
+// validGlob - checks every element of pattern, see matchGlob
+func validGlob(pattern string) error {
+	for _, elem := range strings.Split(strings.Trim(pattern, "/"), "/") {
+		if _, err := path.Match(elem, ""); err != nil {
+			return fmt.Errorf("invalid glob '%s': %s", pattern, err)
+		}
+	}
+	return nil
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob - matches slash separated name against pattern element by element as path.Match
+// does, "**" matches any number of elements including none
+func matchGlob(pattern string, name string) bool {
+	patterns := strings.Split(strings.Trim(pattern, "/"), "/")
+	var names []string
+	if name != "" {
+		names = strings.Split(name, "/")
+	}
+	return matchElems(patterns, names)
+}
+
+func matchElems(patterns []string, names []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			for i := 0; i <= len(names); i++ {
+				if matchElems(patterns[1:], names[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(names) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(patterns[0], names[0]); !ok {
+			return false
+		}
+		patterns, names = patterns[1:], names[1:]
+	}
+	return len(names) == 0
+}
@@ -0,0 +1,158 @@
+package gitrepo
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/alwinius/bow/types"
+
+	"gopkg.in/src-d/go-billy.v4"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+func TestDiscover(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRemote(t, dir, map[string]string{
+		"apps/web/Chart.yaml":                       "name: web\nversion: 0.1.0\n",
+		"apps/web/values.yaml":                      "image:\n  repository: nginx\n  tag: 1.15.0\n",
+		"apps/web/templates/deployment.yaml":        deploymentYaml("web", "{{ .Values.image.repository }}:{{ .Values.image.tag }}"),
+		"apps/web/charts/sub/Chart.yaml":            "name: sub\nversion: 0.1.0\n",
+		"apps/broken/Chart.yaml":                    "name: broken\nversion: 0.1.0\n",
+		"apps/broken/templates/deployment.yaml":     "{{ .Missing",
+		"apps/api/base/kustomization.yaml":          "resources:\n- deployment.yaml\n",
+		"apps/api/base/deployment.yaml":             deploymentYaml("api", "api:1.0.0"),
+		"apps/api/overlays/prod/kustomization.yaml": "bases:\n- ../../base\nnamePrefix: prod-\n",
+		"apps/api/overlays/prod/patches/p.yaml":     "kind: Patch\n",
+		"envs/prod/worker.yaml":                     deploymentYaml("worker", "worker:1.0.0"),
+		"envs/prod/nested/db.yaml":                  deploymentYaml("db", "postgres:11"),
+		"envs/prod/.hidden/ignored.yaml":            deploymentYaml("ignored", "ignored:1"),
+		"envs/legacy/old.yaml":                      deploymentYaml("old", "old:1"),
+		"docs/example.yaml":                         deploymentYaml("example", "example:1"),
+	})
+	repo := newTestRepo(t, dir, remote)
+	repo.Include = []string{"apps/**", "envs/**"}
+	repo.Exclude = []string{"apps/api/base", "envs/legacy"}
+
+	paths, err := repo.renderPaths()
+	if err != nil {
+		t.Fatalf("failed to discover: %s", err)
+	}
+	want := []RenderPath{
+		{Path: "apps/api/overlays/prod", Render: RenderKustomize},
+		{Path: "apps/broken", Render: RenderHelm},
+		{Path: "apps/web", Render: RenderHelm},
+		{Path: "envs/prod", Render: RenderManifests, flat: true},
+		{Path: "envs/prod/nested", Render: RenderManifests, flat: true},
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("unexpected paths:\n%v\nwant\n%v", paths, want)
+	}
+
+	// the broken chart does not stop the others
+	resources, err := renderResources(repo)
+	if err != ErrIncompleteRender {
+		t.Errorf("expected incomplete render, got %v", err)
+	}
+	apps := make(map[string]string)
+	for _, obj := range resources {
+		accessor, _ := meta.Accessor(obj)
+		apps[accessor.GetName()] = accessor.GetAnnotations()[types.BowSourceAppAnnotation]
+	}
+	wantApps := map[string]string{
+		"prod-api": "apps/api/overlays/prod",
+		"web":      "apps/web",
+		"worker":   "envs/prod",
+		"db":       "envs/prod/nested",
+	}
+	if !reflect.DeepEqual(apps, wantApps) {
+		t.Errorf("unexpected apps: %v", apps)
+	}
+
+	// configured paths keep their render mode
+	repo.Paths = []RenderPath{{Path: "envs/prod", Render: RenderManifests}}
+	paths, _ = repo.renderPaths()
+	var names []string
+	for _, p := range paths {
+		names = append(names, p.Path)
+	}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{"apps/api/overlays/prod", "apps/broken", "apps/web", "envs/prod", "envs/prod/nested"}) {
+		t.Errorf("unexpected paths: %v", names)
+	}
+	if paths[0].flat {
+		t.Errorf("configured path must be read recursively")
+	}
+}
+
+// unreadableFs - fails to read one directory
+type unreadableFs struct {
+	billy.Filesystem
+	dir string
+}
+
+func (fs unreadableFs) ReadDir(dir string) ([]os.FileInfo, error) {
+	if dir == fs.dir {
+		return nil, os.ErrPermission
+	}
+	return fs.Filesystem.ReadDir(dir)
+}
+
+func TestDiscoverSkipsUnreadableDirectory(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRemote(t, dir, map[string]string{
+		"apps/web/Chart.yaml":         "name: web\nversion: 0.1.0\n",
+		"apps/secret/deployment.yaml": deploymentYaml("secret", "secret:1"),
+		"envs/prod/worker.yaml":       deploymentYaml("worker", "worker:1.0.0"),
+	})
+	repo := newTestRepo(t, dir, remote)
+	repo.Include = []string{"**"}
+	repo.fs = unreadableFs{Filesystem: repo.fs, dir: "apps/secret"}
+
+	paths, err := repo.discover()
+	if err != nil {
+		t.Fatalf("failed to discover: %s", err)
+	}
+	want := []RenderPath{
+		{Path: "apps/web", Render: RenderHelm},
+		{Path: "envs/prod", Render: RenderManifests, flat: true},
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("unexpected paths:\n%v\nwant\n%v", paths, want)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"apps/*", "apps/web", true},
+		{"apps/*", "apps/web/overlays", false},
+		{"apps/*/", "apps/web", true},
+		{"apps/**", "apps", true},
+		{"apps/**", "apps/web/overlays/prod", true},
+		{"**/prod", "apps/web/overlays/prod", true},
+		{"**/prod", "prod", true},
+		{"**", "", true},
+		{"apps/*/overlays/*", "apps/web/overlays/prod", true},
+		{"envs/p*", "envs/staging", false},
+		{"apps/web", "apps/web2", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+
+	if err := validGlob("apps/[web"); err == nil {
+		t.Errorf("expected error for invalid glob")
+	}
+}
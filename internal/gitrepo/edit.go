@@ -0,0 +1,155 @@
+package gitrepo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/alwinius/bow/util/image"
+	"github.com/alwinius/bow/util/yamledit"
+)
+
+// ErrImageNotFound - none of the source files has an image field that could be updated
+var ErrImageNotFound = errors.New("no editable image field found")
+
+// SetImage updates every YAML image field referencing oldImage in the given source files
+// (relative to the repository root) to newTag, as well as Helm values keeping repository and tag
+// apart. Other files and fields that merely mention the image are left alone. ErrImageNotFound is returned if nothing could be changed.
+func (r *Repo) SetImage(sources []string, oldImage string, newTag string) error {
+	r.init()
+	r.fileAccessLock.Lock()
+	defer r.fileAccessLock.Unlock()
+
+	changed, err := r.planImageUpdate(r.readFile, sources, oldImage, newTag)
+	if err != nil {
+		return err
+	}
+
+	for name, content := range changed {
+		err = r.writeFile(name, content)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply writes changes on top of the latest state of the branch, commits them together and
+// pushes the commit. Changes that cannot be written, ie: because the image was not found in their
+// sources, are left out of the commit and returned in failed.
+func (r *Repo) Apply(changes []*Change) (commit string, failed map[*Change]error, err error) {
+	r.init()
+	r.fileAccessLock.Lock()
+	defer r.fileAccessLock.Unlock()
+
+	failed = make(map[*Change]error)
+	var applied []*Change
+	for _, change := range changes {
+		updated, err := r.planImageUpdate(r.readFile, change.Sources, change.Image, change.NewTag)
+		if err == nil {
+			for name, content := range updated {
+				err = r.writeFile(name, content)
+				if err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			failed[change] = err
+			continue
+		}
+		applied = append(applied, change)
+	}
+
+	if len(applied) == 0 {
+		return "", failed, nil
+	}
+	commit, err = r.commitAndPush(applied)
+	return commit, failed, err
+}
+
+// planImageUpdate - returns new content of every source file that would change, files are
+// read with read, ie: from the checkout or a scratch copy of it
+func (r *Repo) planImageUpdate(read func(name string) ([]byte, error), sources []string, oldImage string, newTag string) (map[string][]byte, error) {
+	ref, err := image.Parse(oldImage)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string][]byte)
+	contents := make(map[string][]byte)
+	var values []string
+	for _, name := range sources {
+		content, err := read(name)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		if !isKustomization(name) && !strings.Contains("/"+name, "/templates/") {
+			contents[name] = content
+			values = append(values, name)
+		}
+
+		if isKustomization(name) {
+			if updated, ok := editKustomization(content, oldImage, newTag); ok {
+				changed[name] = updated
+			}
+			continue
+		}
+
+		updated, imageChanged := editImageFields(content, ref, newTag)
+		updated, valuesChanged := editValues(updated, ref, newTag)
+		if imageChanged || valuesChanged {
+			changed[name] = updated
+		}
+	}
+
+	// chart values overlays that only set the tag
+	if len(changed) == 0 {
+		changed = editLayeredValues(values, contents, ref, newTag)
+	}
+
+	if len(changed) == 0 {
+		return nil, fmt.Errorf("%s: %s in %s", ErrImageNotFound, oldImage, strings.Join(sources, ", "))
+	}
+	return changed, nil
+}
+
+// editImageFields - sets tag of every "image" field that references the same repository and tag as ref
+func editImageFields(content []byte, ref *image.Reference, newTag string) ([]byte, bool) {
+	var replacements []yamledit.Replacement
+	for _, s := range yamledit.Scalars(content) {
+		if s.Key() != "image" || strings.Contains(s.Value, "{{") {
+			continue
+		}
+		if !sameImage(s.Value, ref) {
+			continue
+		}
+		replacements = append(replacements, yamledit.Replacement{Scalar: s, Value: withTag(s.Value, newTag)})
+	}
+
+	if len(replacements) == 0 {
+		return content, false
+	}
+	return yamledit.Replace(content, replacements...), true
+}
+
+// withTag - replaces tag or digest of the image reference as written in the file,
+// so registry and name keep their original (short or fully qualified) form
+func withTag(img string, tag string) string {
+	return imageName(img) + ":" + tag
+}
+
+// imageName - strips tag and digest from the image reference as written
+func imageName(img string) string {
+	if i := strings.Index(img, "@"); i >= 0 {
+		img = img[:i]
+	}
+	if i := strings.LastIndex(img, ":"); i > strings.LastIndex(img, "/") {
+		img = img[:i]
+	}
+	return img
+}
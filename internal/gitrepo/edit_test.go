@@ -0,0 +1,87 @@
+package gitrepo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetImage(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRemote(t, dir, map[string]string{
+		"README.md": "deploy with image: nginx:1.0.0\n",
+		"app/deployment.yaml": `spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.0.0 # keep me
+      - name: proxy
+        image: quay.io/nginx:1.0.0
+      - name: other
+        image: mynginx:1.0.0
+`,
+		"other/deployment.yaml": "image: nginx:1.0.0\n",
+	})
+	repo := newTestRepo(t, dir, remote)
+
+	err := repo.SetImage([]string{"app/deployment.yaml", "app/missing.yaml"}, "nginx:1.0.0", "1.1.0")
+	if err != nil {
+		t.Fatalf("failed to set image: %s", err)
+	}
+
+	expected := map[string]string{
+		"README.md": "deploy with image: nginx:1.0.0\n",
+		"app/deployment.yaml": `spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.1.0 # keep me
+      - name: proxy
+        image: quay.io/nginx:1.0.0
+      - name: other
+        image: mynginx:1.0.0
+`,
+		"other/deployment.yaml": "image: nginx:1.0.0\n",
+	}
+	for name, want := range expected {
+		got, _ := ioutil.ReadFile(filepath.Join(repo.LocalPath, name))
+		if string(got) != want {
+			t.Errorf("unexpected content of %s:\n%s", name, got)
+		}
+	}
+}
+
+func TestSetImageNotFound(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRemote(t, dir, map[string]string{
+		"templates/deployment.yaml": "image: {{ .Values.image }}\n",
+	})
+	repo := newTestRepo(t, dir, remote)
+
+	err := repo.SetImage([]string{"templates/deployment.yaml"}, "nginx:1.0.0", "1.1.0")
+	if err == nil {
+		t.Errorf("expected error when image field cannot be edited")
+	}
+}
+
+func TestWithTag(t *testing.T) {
+	tests := map[string]string{
+		"nginx:1.0":                      "nginx:2.0",
+		"nginx":                          "nginx:2.0",
+		"localhost:5000/nginx:1.0":       "localhost:5000/nginx:2.0",
+		"localhost:5000/nginx":           "localhost:5000/nginx:2.0",
+		"nginx@sha256:0123456789abcdef0": "nginx:2.0",
+	}
+	for img, want := range tests {
+		if got := withTag(img, "2.0"); got != want {
+			t.Errorf("withTag(%s) = %s, want %s", img, got, want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package gitrepo
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+// newTestDir - temporary directory for remotes and checkouts, remove it when done
+func newTestDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "bow-gitrepo")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	return dir
+}
+
+// newTestRemote - creates a bare repository below dir with files committed to
+// master and returns its path
+func newTestRemote(t *testing.T, dir string, files map[string]string) string {
+	work := filepath.Join(dir, "work")
+	remote := filepath.Join(dir, "remote.git")
+
+	runGit(t, dir, "init", "-q", "--bare", "-b", "master", remote)
+	runGit(t, dir, "init", "-q", "-b", "master", work)
+	for name, content := range files {
+		writeTestFile(t, filepath.Join(work, name), content)
+	}
+	runGit(t, work, "add", "-A")
+	runGit(t, work, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial")
+	runGit(t, work, "push", "-q", remote, "master")
+
+	return remote
+}
+
+// newTestRepo - creates Repo with a checkout of remote below dir
+func newTestRepo(t *testing.T, dir string, remote string) *Repo {
+	repo := &Repo{
+		Name:      "test",
+		URL:       remote,
+		LocalPath: filepath.Join(dir, "checkout"),
+		Branch:    plumbing.NewBranchReferenceName("master"),
+	}
+	repo.init()
+	if repo.repository == nil {
+		t.Fatalf("failed to clone %s", remote)
+	}
+	return repo
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatalf("failed to create dir: %s", err)
+	}
+	err = ioutil.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatalf("failed to write file: %s", err)
+	}
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %s: %s", args, err, out)
+	}
+	return string(out)
+}
@@ -0,0 +1,355 @@
+package gitrepo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/alwinius/bow/util/image"
+	"github.com/alwinius/bow/util/yamledit"
+
+	"github.com/ghodss/yaml"
+)
+
+// RenderKustomize - path is a Kustomize base or overlay
+const RenderKustomize = "kustomize"
+
+var kustomizationFiles = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+var imagesKey = regexp.MustCompile(`(?m)^images:`)
+
+// maximum depth of nested bases, guards against cycles
+const maxKustomizeDepth = 20
+
+// kustomization - supported subset of kustomization.yaml. Resources and bases are built
+// recursively, namespace, name prefix/suffix and the images transformer are applied.
+// Generators, patches, labels and remote bases are not supported, kustomizations using any
+// other field fail to render instead of being rendered without it.
+type kustomization struct {
+	APIVersion string           `json:"apiVersion"`
+	Kind       string           `json:"kind"`
+	Resources  []string         `json:"resources"`
+	Bases      []string         `json:"bases"`
+	Namespace  string           `json:"namespace"`
+	NamePrefix string           `json:"namePrefix"`
+	NameSuffix string           `json:"nameSuffix"`
+	Images     []kustomizeImage `json:"images"`
+}
+
+// clusterScopedKinds - kinds the namespace of a kustomization is not applied to
+var clusterScopedKinds = map[string]bool{
+	"APIService":                     true,
+	"CertificateSigningRequest":      true,
+	"ClusterRole":                    true,
+	"ClusterRoleBinding":             true,
+	"ComponentStatus":                true,
+	"CSIDriver":                      true,
+	"CSINode":                        true,
+	"CustomResourceDefinition":       true,
+	"MutatingWebhookConfiguration":   true,
+	"Namespace":                      true,
+	"Node":                           true,
+	"PersistentVolume":               true,
+	"PodSecurityPolicy":              true,
+	"PriorityClass":                  true,
+	"RuntimeClass":                   true,
+	"StorageClass":                   true,
+	"ValidatingWebhookConfiguration": true,
+	"VolumeAttachment":               true,
+}
+
+// parseKustomization - decodes content strictly, unsupported fields are an error
+func parseKustomization(content []byte) (*kustomization, error) {
+	j, err := yaml.YAMLToJSON(content)
+	if err != nil {
+		return nil, err
+	}
+	var k kustomization
+	if bytes.Equal(bytes.TrimSpace(j), []byte("null")) {
+		return &k, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&k)
+	if err != nil {
+		return nil, fmt.Errorf("%s, supported are resources, bases, namespace, namePrefix, nameSuffix and images", err)
+	}
+	return &k, nil
+}
+
+// kustomizeImage - entry of the images transformer
+type kustomizeImage struct {
+	Name    string `json:"name"`
+	NewName string `json:"newName"`
+	NewTag  string `json:"newTag"`
+	Digest  string `json:"digest"`
+}
+
+// findKustomization - returns path of the kustomization file in dir (relative to the repository
+// root) or an empty string
+func (r *Repo) findKustomization(dir string) string {
+	for _, name := range kustomizationFiles {
+		if r.exists(path.Join(dir, name)) {
+			return path.Join(dir, name)
+		}
+	}
+	return ""
+}
+
+// renderKustomization - builds kustomization in dir (relative to the repository root). All
+// resources are attributed to the kustomization file of dir, so updates go to its images block.
+func (r *Repo) renderKustomization(dir string) ([]rendered, error) {
+	source := r.findKustomization(dir)
+	if source == "" {
+		return nil, fmt.Errorf("no kustomization found in %s", dir)
+	}
+
+	objects, err := r.buildKustomization(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []rendered
+	for _, obj := range objects {
+		content, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, rendered{content: string(content), sources: []string{source}})
+	}
+	return result, nil
+}
+
+func (r *Repo) buildKustomization(dir string, depth int) ([]map[string]interface{}, error) {
+	if depth > maxKustomizeDepth {
+		return nil, fmt.Errorf("kustomization %s: too many nested bases", dir)
+	}
+	file := r.findKustomization(dir)
+	if file == "" {
+		return nil, fmt.Errorf("no kustomization found in %s", dir)
+	}
+	b, err := r.readFile(file)
+	if err != nil {
+		return nil, err
+	}
+	k, err := parseKustomization(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", file, err)
+	}
+
+	var objects []map[string]interface{}
+	for _, res := range append(k.Bases, k.Resources...) {
+		if strings.Contains(res, "://") || strings.HasPrefix(res, "github.com/") {
+			return nil, fmt.Errorf("kustomization %s: remote resource %s is not supported", file, res)
+		}
+		p := path.Join(dir, res)
+		if strings.HasPrefix(p, "../") || p == ".." {
+			return nil, fmt.Errorf("kustomization %s: resource %s is outside of the repository", file, res)
+		}
+		dir, err := r.isDir(p)
+		if err != nil {
+			return nil, fmt.Errorf("kustomization %s: %s", file, err)
+		}
+
+		if dir {
+			base, err := r.buildKustomization(p, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, base...)
+			continue
+		}
+
+		content, err := r.readFile(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range splitDocuments(string(content)) {
+			var obj map[string]interface{}
+			err = yaml.Unmarshal([]byte(doc), &obj)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %s", p, err)
+			}
+			if obj != nil {
+				objects = append(objects, obj)
+			}
+		}
+	}
+
+	for _, obj := range objects {
+		metadata, ok := obj["metadata"].(map[string]interface{})
+		if !ok {
+			metadata = make(map[string]interface{})
+			obj["metadata"] = metadata
+		}
+		if kind, _ := obj["kind"].(string); k.Namespace != "" && !clusterScopedKinds[kind] {
+			metadata["namespace"] = k.Namespace
+		}
+		if name, ok := metadata["name"].(string); ok {
+			metadata["name"] = k.NamePrefix + name + k.NameSuffix
+		}
+		for _, img := range k.Images {
+			transformImages(obj, img)
+		}
+	}
+
+	return objects, nil
+}
+
+// transformImages - applies images transformer entry to every container of obj
+func transformImages(obj interface{}, img kustomizeImage) {
+	switch v := obj.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if key == "containers" || key == "initContainers" {
+				if containers, ok := value.([]interface{}); ok {
+					for _, c := range containers {
+						if container, ok := c.(map[string]interface{}); ok {
+							if current, ok := container["image"].(string); ok {
+								container["image"] = transformImage(current, img)
+							}
+						}
+					}
+				}
+				continue
+			}
+			transformImages(value, img)
+		}
+	case []interface{}:
+		for _, value := range v {
+			transformImages(value, img)
+		}
+	}
+}
+
+func transformImage(current string, img kustomizeImage) string {
+	name := imageName(current)
+	if name != img.Name {
+		return current
+	}
+	suffix := current[len(name):]
+	if img.NewName != "" {
+		name = img.NewName
+	}
+	switch {
+	case img.Digest != "":
+		return name + "@" + img.Digest
+	case img.NewTag != "":
+		return name + ":" + img.NewTag
+	}
+	return name + suffix
+}
+
+func isKustomization(name string) bool {
+	base := path.Base(name)
+	for _, k := range kustomizationFiles {
+		if base == k {
+			return true
+		}
+	}
+	return false
+}
+
+// editKustomization - sets newTag (or digest, for sha256 versions) of the images entry that
+// produced oldImage. An entry is added to the images block if there is none for the image yet.
+func editKustomization(content []byte, oldImage string, newTag string) ([]byte, bool) {
+	field := "newTag"
+	if strings.HasPrefix(newTag, "sha256:") {
+		field = "digest"
+	}
+
+	entries := make(map[string]map[string]yamledit.Scalar)
+	var order []string
+	for _, s := range yamledit.Scalars(content) {
+		if s.Doc != 0 || len(s.Path) != 3 || s.Path[0] != "images" {
+			continue
+		}
+		if _, ok := entries[s.Path[1]]; !ok {
+			entries[s.Path[1]] = make(map[string]yamledit.Scalar)
+			order = append(order, s.Path[1])
+		}
+		entries[s.Path[1]][s.Path[2]] = s
+	}
+
+	for _, i := range order {
+		entry := entries[i]
+		name, ok := entry["name"]
+		if !ok {
+			continue
+		}
+		produced := name.Value
+		if newName, ok := entry["newName"]; ok {
+			produced = newName.Value
+		}
+		if !sameRepository(produced, oldImage) {
+			continue
+		}
+
+		offset := yamledit.LineEnd(content, name.Offset())
+		current, hasField := entry[field]
+		if hasField {
+			offset = current.Offset()
+		}
+		apply := func(content []byte) []byte {
+			if hasField {
+				return yamledit.Replace(content, yamledit.Replacement{Scalar: current, Value: newTag})
+			}
+			return yamledit.Insert(content, offset, "\n"+strings.Repeat(" ", name.Indent())+field+": "+yamledit.Format(newTag, 0))
+		}
+
+		// digest takes precedence over newTag, drop it when switching to a tag. Edits are
+		// applied back to front so the offsets stay valid.
+		digest, removeDigest := entry["digest"]
+		removeDigest = removeDigest && field == "newTag"
+		if removeDigest && digest.Offset() > offset {
+			content = apply(yamledit.RemoveLine(content, digest))
+		} else {
+			content = apply(content)
+			if removeDigest {
+				content = yamledit.RemoveLine(content, digest)
+			}
+		}
+		return content, true
+	}
+
+	if len(order) > 0 {
+		last := entries[order[len(order)-1]]
+		var lastLine yamledit.Scalar
+		for _, s := range last {
+			if s.Line >= lastLine.Line {
+				lastLine = s
+			}
+		}
+		indent := strings.Repeat(" ", lastLine.Indent()-2)
+		end := yamledit.LineEnd(content, lastLine.Offset())
+		entry := "\n" + indent + "- name: " + yamledit.Format(imageName(oldImage), 0) +
+			"\n" + indent + "  " + field + ": " + yamledit.Format(newTag, 0)
+		return yamledit.Insert(content, end, entry), true
+	}
+	if imagesKey.Match(content) {
+		// images block in a format that cannot be extended safely
+		return content, false
+	}
+
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		content = append(content, '\n')
+	}
+	entry := "images:\n- name: " + yamledit.Format(imageName(oldImage), 0) + "\n  " + field + ": " + yamledit.Format(newTag, 0) + "\n"
+	return append(content, []byte(entry)...), true
+}
+
+// sameRepository - checks whether both image references point to the same repository
+func sameRepository(a, b string) bool {
+	refA, err := image.Parse(imageName(a))
+	if err != nil {
+		return false
+	}
+	refB, err := image.Parse(imageName(b))
+	if err != nil {
+		return false
+	}
+	return refA.Repository() == refB.Repository()
+}
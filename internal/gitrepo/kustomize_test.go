@@ -0,0 +1,177 @@
+package gitrepo
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+func TestRenderKustomization(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRemote(t, dir, map[string]string{
+		"base/kustomization.yaml": "resources:\n- deployment.yaml\n",
+		"base/deployment.yaml":    deploymentYaml("web", "nginx:1.0.0"),
+		"overlays/prod/kustomization.yaml": `bases:
+- ../../base
+namespace: prod
+namePrefix: prod-
+images:
+- name: nginx
+  newTag: 1.1.0
+`,
+	})
+	repo := newTestRepo(t, dir, remote)
+	repo.Paths = []RenderPath{{Path: "overlays/prod"}}
+
+	manifests, err := repo.getManifests()
+	if err != nil {
+		t.Fatalf("failed to render: %s", err)
+	}
+	if len(manifests) != 1 {
+		t.Fatalf("expected one resource, got %d", len(manifests))
+	}
+	gr, err := yamlToGenericResource(manifests[0].content)
+	if err != nil || gr == nil {
+		t.Fatalf("failed to decode resource: %v", err)
+	}
+	accessor, _ := meta.Accessor(gr)
+	if accessor.GetName() != "prod-web" || accessor.GetNamespace() != "prod" {
+		t.Errorf("unexpected name %s/%s", accessor.GetNamespace(), accessor.GetName())
+	}
+	if img := gr.(*appsv1.Deployment).Spec.Template.Spec.Containers[0].Image; img != "nginx:1.1.0" {
+		t.Errorf("unexpected image: %s", img)
+	}
+	if strings.Join(manifests[0].sources, ",") != "overlays/prod/kustomization.yaml" {
+		t.Errorf("unexpected sources: %v", manifests[0].sources)
+	}
+
+	// updates go to the overlay, the base stays untouched
+	err = repo.SetImage(manifests[0].sources, "nginx:1.1.0", "1.2.0")
+	if err != nil {
+		t.Fatalf("failed to set image: %s", err)
+	}
+	overlay, _ := repo.readFile("overlays/prod/kustomization.yaml")
+	if !strings.Contains(string(overlay), "  newTag: 1.2.0\n") {
+		t.Errorf("overlay not updated:\n%s", overlay)
+	}
+	base, _ := repo.readFile("base/deployment.yaml")
+	if !strings.Contains(string(base), "nginx:1.0.0") {
+		t.Errorf("base was changed:\n%s", base)
+	}
+}
+
+func TestRenderKustomizationClusterScoped(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRemote(t, dir, map[string]string{
+		"kustomization.yaml": "apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\nnamespace: prod\nresources:\n- deployment.yaml\n- rbac.yaml\n",
+		"deployment.yaml":    deploymentYaml("web", "nginx:1.0.0"),
+		"rbac.yaml":          "apiVersion: rbac.authorization.k8s.io/v1\nkind: ClusterRole\nmetadata:\n  name: web\n",
+	})
+	repo := newTestRepo(t, dir, remote)
+
+	objects, err := repo.buildKustomization("", 0)
+	if err != nil {
+		t.Fatalf("failed to build: %s", err)
+	}
+	namespaces := make(map[string]interface{})
+	for _, obj := range objects {
+		namespaces[obj["kind"].(string)] = obj["metadata"].(map[string]interface{})["namespace"]
+	}
+	if namespaces["Deployment"] != "prod" || namespaces["ClusterRole"] != nil {
+		t.Errorf("unexpected namespaces: %v", namespaces)
+	}
+}
+
+func TestRenderKustomizationUnsupported(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRemote(t, dir, map[string]string{
+		"kustomization.yaml": "resources:\n- deployment.yaml\npatchesStrategicMerge:\n- image.yaml\n",
+		"deployment.yaml":    deploymentYaml("web", "nginx:1.0.0"),
+		"image.yaml":         deploymentYaml("web", "nginx:2.0.0"),
+	})
+	repo := newTestRepo(t, dir, remote)
+
+	_, err := repo.buildKustomization("", 0)
+	if err == nil || !strings.Contains(err.Error(), "patchesStrategicMerge") {
+		t.Errorf("expected unsupported field to fail, got %v", err)
+	}
+}
+
+func TestEditKustomization(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		oldImage string
+		newTag   string
+		want     string
+	}{
+		{
+			name:     "new tag",
+			content:  "images:\n  - name: nginx\n    newTag: \"1.0\" # prod\n  - name: redis\n",
+			oldImage: "nginx:1.0",
+			newTag:   "1.1",
+			want:     "images:\n  - name: nginx\n    newTag: \"1.1\" # prod\n  - name: redis\n",
+		},
+		{
+			name:     "missing tag field",
+			content:  "images:\n- name: nginx\n- name: redis\n  newName: quay.io/redis\n",
+			oldImage: "quay.io/redis:5.0.0",
+			newTag:   "5.0.1",
+			want:     "images:\n- name: nginx\n- name: redis\n  newTag: 5.0.1\n  newName: quay.io/redis\n",
+		},
+		{
+			name:     "digest replaced by tag",
+			content:  "images:\n- name: nginx\n  digest: sha256:abc\n  newTag: 1.0.0\n",
+			oldImage: "nginx@sha256:abc",
+			newTag:   "1.1.0",
+			want:     "images:\n- name: nginx\n  newTag: 1.1.0\n",
+		},
+		{
+			name:     "digest",
+			content:  "images:\n- name: nginx\n  digest: sha256:abc\n",
+			oldImage: "nginx@sha256:abc",
+			newTag:   "sha256:def",
+			want:     "images:\n- name: nginx\n  digest: sha256:def\n",
+		},
+		{
+			name:     "append entry",
+			content:  "images:\n  - name: redis\n    newTag: 5.0.0\nnamespace: prod\n",
+			oldImage: "nginx:1.0.0",
+			newTag:   "1.1.0",
+			want:     "images:\n  - name: redis\n    newTag: 5.0.0\n  - name: nginx\n    newTag: 1.1.0\nnamespace: prod\n",
+		},
+		{
+			name:     "add images block",
+			content:  "resources:\n- ../../base",
+			oldImage: "localhost:5000/nginx:1.0.0",
+			newTag:   "1.1.0",
+			want:     "resources:\n- ../../base\nimages:\n- name: localhost:5000/nginx\n  newTag: 1.1.0\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := editKustomization([]byte(tt.content), tt.oldImage, tt.newTag)
+			if !ok {
+				t.Fatalf("kustomization not changed")
+			}
+			if string(got) != tt.want {
+				t.Errorf("unexpected result:\n%s", got)
+			}
+		})
+	}
+
+	_, ok := editKustomization([]byte("images: []\n"), "nginx:1.0.0", "1.1.0")
+	if ok {
+		t.Errorf("expected flow style images block to be left alone")
+	}
+}
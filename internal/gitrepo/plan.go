@@ -0,0 +1,164 @@
+package gitrepo
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	fdiff "gopkg.in/src-d/go-git.v4/plumbing/format/diff"
+	"gopkg.in/src-d/go-git.v4/utils/diff"
+)
+
+// PlannedChange - change as Apply would write it, with the unified diff of its files
+type PlannedChange struct {
+	*Change
+	Files []string
+	Diff  string
+}
+
+// scratch - edited files of a dry run on top of the checkout, nothing is written to the checkout
+type scratch struct {
+	repo  *Repo
+	files map[string][]byte
+}
+
+func (s *scratch) readFile(name string) ([]byte, error) {
+	if content, ok := s.files[name]; ok {
+		return content, nil
+	}
+	return s.repo.readFile(name)
+}
+
+// Plan edits the sources of changes in a scratch copy of the checkout instead of committing
+// them. Changes see the edits of the changes before them, as in a commit made by Apply.
+// Changes that cannot be written are returned in failed.
+func (r *Repo) Plan(changes []*Change) (planned []*PlannedChange, failed map[*Change]error) {
+	r.init()
+	r.fileAccessLock.Lock()
+	defer r.fileAccessLock.Unlock()
+	return r.plan(changes)
+}
+
+// PlanCheckout - Plan on the checkout as last refreshed by the watcher, without pulling first.
+// All changes fail when the repository was not cloned yet.
+func (r *Repo) PlanCheckout(changes []*Change) (planned []*PlannedChange, failed map[*Change]error) {
+	r.fileAccessLock.Lock()
+	defer r.fileAccessLock.Unlock()
+	if r.repository == nil {
+		failed = make(map[*Change]error)
+		for _, change := range changes {
+			failed[change] = fmt.Errorf("repository %s is not checked out yet", r.Name)
+		}
+		return nil, failed
+	}
+	return r.plan(changes)
+}
+
+func (r *Repo) plan(changes []*Change) (planned []*PlannedChange, failed map[*Change]error) {
+	s := &scratch{repo: r, files: make(map[string][]byte)}
+	failed = make(map[*Change]error)
+	for _, change := range changes {
+		updated, err := r.planImageUpdate(s.readFile, change.Sources, change.Image, change.NewTag)
+		if err != nil {
+			failed[change] = err
+			continue
+		}
+
+		files, d, err := s.apply(updated)
+		if err != nil {
+			failed[change] = err
+			continue
+		}
+		planned = append(planned, &PlannedChange{Change: change, Files: files, Diff: d})
+	}
+	return planned, failed
+}
+
+// apply - stores updated files in the scratch copy, returns their names and the diff to their
+// previous content
+func (s *scratch) apply(updated map[string][]byte) (files []string, d string, err error) {
+	for name := range updated {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+
+	var patch filePatches
+	for _, name := range files {
+		old, err := s.readFile(name)
+		if err != nil {
+			return nil, "", err
+		}
+		patch = append(patch, newFilePatch(name, old, updated[name]))
+	}
+	d, err = unifiedDiff(patch)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to diff %s: %s", strings.Join(files, ", "), err)
+	}
+
+	for name, content := range updated {
+		s.files[name] = content
+	}
+	return files, d, nil
+}
+
+// unifiedDiff - encodes patch the way `git diff` prints it
+func unifiedDiff(patch filePatches) (string, error) {
+	var buf bytes.Buffer
+	err := fdiff.NewUnifiedEncoder(&buf, fdiff.DefaultContextLines).Encode(patch)
+	return buf.String(), err
+}
+
+// filePatches, filePatch, file and chunk implement go-git's diff.Patch for file contents
+// that are not stored in the repository
+type filePatches []fdiff.FilePatch
+
+func (p filePatches) FilePatches() []fdiff.FilePatch { return p }
+func (p filePatches) Message() string                { return "" }
+
+type filePatch struct {
+	from, to *file
+	chunks   []fdiff.Chunk
+}
+
+func newFilePatch(name string, old, new []byte) *filePatch {
+	p := &filePatch{
+		from: &file{path: name, hash: plumbing.ComputeHash(plumbing.BlobObject, old)},
+		to:   &file{path: name, hash: plumbing.ComputeHash(plumbing.BlobObject, new)},
+	}
+	for _, d := range diff.Do(string(old), string(new)) {
+		c := &chunk{content: d.Text}
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			c.op = fdiff.Add
+		case diffmatchpatch.DiffDelete:
+			c.op = fdiff.Delete
+		}
+		p.chunks = append(p.chunks, c)
+	}
+	return p
+}
+
+func (p *filePatch) IsBinary() bool               { return false }
+func (p *filePatch) Files() (from, to fdiff.File) { return p.from, p.to }
+func (p *filePatch) Chunks() []fdiff.Chunk        { return p.chunks }
+
+type file struct {
+	path string
+	hash plumbing.Hash
+}
+
+func (f *file) Hash() plumbing.Hash     { return f.hash }
+func (f *file) Mode() filemode.FileMode { return filemode.Regular }
+func (f *file) Path() string            { return f.path }
+
+type chunk struct {
+	content string
+	op      fdiff.Operation
+}
+
+func (c *chunk) Content() string       { return c.content }
+func (c *chunk) Type() fdiff.Operation { return c.op }
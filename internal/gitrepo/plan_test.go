@@ -0,0 +1,77 @@
+package gitrepo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	original := "web:\n  image: nginx:1.15.0\napi:\n  image: api:1.0.0\n"
+	remote := newTestRemote(t, dir, map[string]string{"images.yaml": original})
+	repo := newTestRepo(t, dir, remote)
+	head := runGit(t, dir, "--git-dir", remote, "rev-parse", "master")
+
+	web := &Change{Image: "nginx:1.15.0", Repository: "nginx", OldTag: "1.15.0", NewTag: "1.16.0", Sources: []string{"images.yaml"}}
+	api := &Change{Image: "api:1.0.0", Repository: "api", OldTag: "1.0.0", NewTag: "1.1.0", Sources: []string{"images.yaml"}}
+	missing := &Change{Image: "redis:5", Repository: "redis", OldTag: "5", NewTag: "6", Sources: []string{"images.yaml"}}
+	planned, failed := repo.Plan([]*Change{web, api, missing})
+
+	if len(failed) != 1 || failed[missing] == nil {
+		t.Errorf("expected only the missing image to fail, got %v", failed)
+	}
+	if len(planned) != 2 {
+		t.Fatalf("expected 2 planned changes, got %d", len(planned))
+	}
+	if !strings.HasPrefix(planned[0].Diff, "diff --git a/images.yaml b/images.yaml\nindex ") || !strings.Contains(planned[0].Diff, "-  image: nginx:1.15.0\n+  image: nginx:1.16.0\n") {
+		t.Errorf("unexpected diff:\n%s", planned[0].Diff)
+	}
+	// the second change is planned on top of the first one
+	if !strings.Contains(planned[1].Diff, " web:\n   image: nginx:1.16.0\n api:\n-  image: api:1.0.0\n+  image: api:1.1.0\n") {
+		t.Errorf("unexpected diff:\n%s", planned[1].Diff)
+	}
+	if planned[1].Files[0] != "images.yaml" || planned[1].Change != api {
+		t.Errorf("unexpected planned change: %+v", planned[1])
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(repo.LocalPath, "images.yaml"))
+	if err != nil || string(content) != original {
+		t.Errorf("checkout was changed: %s, %v", content, err)
+	}
+	if now := runGit(t, dir, "--git-dir", remote, "rev-parse", "master"); now != head {
+		t.Errorf("plan must not push, head moved from %s to %s", head, now)
+	}
+}
+
+func TestPlanCheckout(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRemote(t, dir, map[string]string{"images.yaml": "web:\n  image: nginx:1.15.0\n"})
+	repo := newTestRepo(t, dir, remote)
+
+	// the remote moves on, the checkout is not pulled
+	work := filepath.Join(dir, "work")
+	writeTestFile(t, filepath.Join(work, "images.yaml"), "web:\n  image: nginx:1.15.1\n")
+	runGit(t, work, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-am", "manual update")
+	runGit(t, work, "push", "-q", remote, "master")
+
+	web := &Change{Image: "nginx:1.15.0", Repository: "nginx", OldTag: "1.15.0", NewTag: "1.16.0", Sources: []string{"images.yaml"}}
+	planned, failed := repo.PlanCheckout([]*Change{web})
+	if len(failed) != 0 || len(planned) != 1 {
+		t.Fatalf("expected one planned change, got %v, %v", planned, failed)
+	}
+	if !strings.Contains(planned[0].Diff, "-  image: nginx:1.15.0\n+  image: nginx:1.16.0\n") {
+		t.Errorf("expected diff against the checkout, got:\n%s", planned[0].Diff)
+	}
+
+	notCloned := &Repo{Name: "missing"}
+	if _, failed := notCloned.PlanCheckout([]*Change{web}); failed[web] == nil {
+		t.Errorf("expected change to fail without checkout")
+	}
+}
@@ -0,0 +1,149 @@
+package gitrepo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+// Environment - resources rendered from a path of a watched repository, ie: the staging branch
+// (a repository entry of its own) or envs/staging in a shared repository
+type Environment struct {
+	// Repo is the name of the repository, any repository matches if empty
+	Repo string `json:"repo"`
+	// Path is a directory relative to the repository root, the whole repository matches if empty
+	Path string `json:"path"`
+}
+
+// Contains - whether a resource rendered from repo out of the given files or app directories
+// belongs to the environment
+func (e Environment) Contains(repo string, paths []string) bool {
+	if e.Repo != "" && e.Repo != repo {
+		return false
+	}
+	if e.Path == "" {
+		return true
+	}
+	for _, p := range paths {
+		if p == e.Path || strings.HasPrefix(p, e.Path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps - whether a resource could belong to both environments
+func (e Environment) overlaps(other Environment) bool {
+	if e.Repo != "" && other.Repo != "" && e.Repo != other.Repo {
+		return false
+	}
+	return e.Contains(e.Repo, []string{other.Path}) || other.Contains(other.Repo, []string{e.Path})
+}
+
+func (e Environment) String() string {
+	switch {
+	case e.Repo == "":
+		return e.Path
+	case e.Path == "":
+		return e.Repo
+	}
+	return e.Repo + ":" + e.Path
+}
+
+// PromotionConfig - pipeline settings as given in the promotions section of REPO_CONFIG
+type PromotionConfig struct {
+	Name string      `json:"name"`
+	From Environment `json:"from"`
+	To   Environment `json:"to"`
+	// Soak is how long a version has to run in From before it is promoted (ie: 2h)
+	Soak string `json:"soak"`
+	// Approvals required for a promotion, either approvals or soak time is enough if both are set
+	Approvals int `json:"approvals"`
+}
+
+// Promotion - pipeline applying versions that bow deployed to From to the same images in To
+type Promotion struct {
+	Name      string
+	From      Environment
+	To        Environment
+	Soak      time.Duration
+	Approvals int
+}
+
+// LoadPromotions - reads the promotion pipelines from the repository configuration file,
+// environments have to refer to repositories in repos
+func LoadPromotions(path string, repos []*Repo) ([]*Promotion, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository config: %s", err)
+	}
+
+	var cfg Config
+	err = yaml.Unmarshal(b, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse repository config: %s", err)
+	}
+
+	var promotions []*Promotion
+	names := make(map[string]bool)
+	for _, pc := range cfg.Promotions {
+		promotion, err := NewPromotion(pc, repos)
+		if err != nil {
+			return nil, err
+		}
+		if names[promotion.Name] {
+			return nil, fmt.Errorf("duplicate promotion name '%s'", promotion.Name)
+		}
+		names[promotion.Name] = true
+		promotions = append(promotions, promotion)
+	}
+	return promotions, nil
+}
+
+// NewPromotion - creates promotion pipeline from config
+func NewPromotion(pc PromotionConfig, repos []*Repo) (*Promotion, error) {
+	if pc.Name == "" {
+		return nil, fmt.Errorf("promotion name cannot be empty")
+	}
+
+	known := make(map[string]bool)
+	for _, r := range repos {
+		known[r.Name] = true
+	}
+	from := Environment{Repo: pc.From.Repo, Path: strings.Trim(pc.From.Path, "/")}
+	to := Environment{Repo: pc.To.Repo, Path: strings.Trim(pc.To.Path, "/")}
+	for _, env := range []Environment{from, to} {
+		if env.Repo == "" && len(repos) > 1 {
+			return nil, fmt.Errorf("promotion %s: repo must be set when several repositories are watched", pc.Name)
+		}
+		if env.Repo != "" && !known[env.Repo] {
+			return nil, fmt.Errorf("promotion %s: unknown repository '%s'", pc.Name, env.Repo)
+		}
+	}
+	if from.overlaps(to) {
+		return nil, fmt.Errorf("promotion %s: environments %s and %s overlap", pc.Name, from, to)
+	}
+
+	var soak time.Duration
+	if pc.Soak != "" {
+		var err error
+		soak, err = time.ParseDuration(pc.Soak)
+		if err != nil || soak < 0 {
+			return nil, fmt.Errorf("promotion %s: invalid soak time '%s'", pc.Name, pc.Soak)
+		}
+	}
+	if pc.Approvals < 0 {
+		return nil, fmt.Errorf("promotion %s: approvals cannot be negative", pc.Name)
+	}
+
+	return &Promotion{
+		Name:      pc.Name,
+		From:      from,
+		To:        to,
+		Soak:      soak,
+		Approvals: pc.Approvals,
+	}, nil
+}
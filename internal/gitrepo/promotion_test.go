@@ -0,0 +1,93 @@
+package gitrepo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadPromotions(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "repos.yaml")
+	writeTestFile(t, path, `repositories:
+- name: staging
+  url: https://example.com/deploy.git
+  branch: staging
+- name: production
+  url: https://example.com/deploy.git
+promotions:
+- name: staging-to-production
+  from:
+    repo: staging
+  to:
+    repo: production
+    path: /envs/prod/
+  soak: 2h
+- name: approved
+  from:
+    repo: production
+    path: envs/prod
+  to:
+    repo: production
+    path: envs/dr
+  approvals: 2
+`)
+	repos, err := LoadConfig(path, dir)
+	if err != nil {
+		t.Fatalf("failed to load repositories: %s", err)
+	}
+	promotions, err := LoadPromotions(path, repos)
+	if err != nil {
+		t.Fatalf("failed to load promotions: %s", err)
+	}
+	if len(promotions) != 2 {
+		t.Fatalf("expected 2 promotions, got %d", len(promotions))
+	}
+	p := promotions[0]
+	if p.To.Path != "envs/prod" || p.Soak != 2*time.Hour || p.Approvals != 0 {
+		t.Errorf("unexpected promotion: %+v", p)
+	}
+	if promotions[1].Approvals != 2 || promotions[1].Soak != 0 {
+		t.Errorf("unexpected promotion: %+v", promotions[1])
+	}
+
+	for pc, wantErr := range map[*PromotionConfig]string{
+		{Name: "", From: Environment{Repo: "staging"}, To: Environment{Repo: "production"}}:                                    "name cannot be empty",
+		{Name: "x", From: Environment{Repo: "staging"}, To: Environment{Repo: "unknown"}}:                                      "unknown repository",
+		{Name: "x", From: Environment{Path: "envs/staging"}, To: Environment{Repo: "production"}}:                              "repo must be set",
+		{Name: "x", From: Environment{Repo: "production"}, To: Environment{Repo: "production", Path: "envs/dr"}}:               "overlap",
+		{Name: "x", From: Environment{Repo: "production", Path: "envs"}, To: Environment{Repo: "production", Path: "envs/dr"}}: "overlap",
+		{Name: "x", From: Environment{Repo: "staging"}, To: Environment{Repo: "production"}, Soak: "soon"}:                     "invalid soak time",
+		{Name: "x", From: Environment{Repo: "staging"}, To: Environment{Repo: "production"}, Approvals: -1}:                    "cannot be negative",
+	} {
+		_, err := NewPromotion(*pc, repos)
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("%+v: expected error containing '%s', got %v", *pc, wantErr, err)
+		}
+	}
+}
+
+func TestEnvironmentContains(t *testing.T) {
+	tests := []struct {
+		env   Environment
+		repo  string
+		paths []string
+		want  bool
+	}{
+		{Environment{Repo: "staging"}, "staging", []string{"chart"}, true},
+		{Environment{Repo: "staging"}, "production", []string{"chart"}, false},
+		{Environment{Path: "envs/prod"}, "deploy", []string{"envs/prod"}, true},
+		{Environment{Path: "envs/prod"}, "deploy", []string{"envs/prod/web"}, true},
+		{Environment{Path: "envs/prod"}, "deploy", []string{"envs/production"}, false},
+		{Environment{Repo: "deploy", Path: "envs/prod"}, "deploy", []string{"base/deployment.yaml", "envs/prod/values.yaml"}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.env.Contains(tt.repo, tt.paths); got != tt.want {
+			t.Errorf("%s contains %s %v = %v, want %v", tt.env, tt.repo, tt.paths, got, tt.want)
+		}
+	}
+}
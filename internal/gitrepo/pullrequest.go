@@ -0,0 +1,207 @@
+package gitrepo
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alwinius/bow/internal/forge"
+	"github.com/alwinius/bow/util/image"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+// pullRequestBranchPrefix - branches pushed in pull request mode start with it
+const pullRequestBranchPrefix = "bow/"
+
+// ErrPullRequestOpen - returned when a pull request with the same update is open already, nothing
+// was pushed
+var ErrPullRequestOpen = errors.New("pull request for this update is already open")
+
+// PullRequestConfig - opt-in mode which pushes updates to a separate branch and
+// opens a pull/merge request instead of pushing to the watched branch
+type PullRequestConfig struct {
+	Enabled bool `json:"enabled"`
+	// Forge is one of github, gitlab or gitea
+	Forge  string `json:"forge"`
+	APIURL string `json:"apiURL"`
+	// Project defaults to owner/repository taken from the repository URL
+	Project string `json:"project"`
+	Token   string `json:"token"`
+}
+
+func newForgeClient(cfg *PullRequestConfig, url string) (forge.Client, error) {
+	project := cfg.Project
+	if project == "" {
+		project = forge.ProjectFromURL(url)
+	}
+	return forge.New(forge.Opts{
+		Kind:    cfg.Forge,
+		APIURL:  cfg.APIURL,
+		Project: project,
+		Token:   cfg.Token,
+	})
+}
+
+// pullRequestMarker - added to the pull request body to find the pull request again
+// when a newer tag of the same image arrives for the same resource
+func pullRequestMarker(changes ...*Change) string {
+	var keys []string
+	for _, change := range changes {
+		keys = append(keys, changeKey(change))
+	}
+	sort.Strings(keys)
+	return fmt.Sprintf("bow-update: %s", strings.Join(keys, ","))
+}
+
+// changeKey - resource and image repository of change, ie:
+// prod/apps/web/deployment/default/web index.docker.io/library/nginx
+func changeKey(change *Change) string {
+	if change.Resource == "" {
+		return change.Repository
+	}
+	return change.Resource + " " + change.Repository
+}
+
+// hasMarker - body contains marker on a line of its own
+func hasMarker(body string, marker string) bool {
+	for _, line := range strings.Split(body, "\n") {
+		if strings.TrimSpace(line) == marker {
+			return true
+		}
+	}
+	return false
+}
+
+// pullRequestBranch - ie: bow/nginx-1.15.3, the resource of the change is only kept in the
+// marker of the pull request
+func pullRequestBranch(ref *image.Reference) string {
+	return pullRequestBranchPrefix + strings.TrimPrefix(ref.ShortName(), "library/") + "-" + ref.Tag()
+}
+
+// uniqueBranch - branch, or branch with a hash of marker appended when another open pull request
+// uses branch already, ie: the same image of another resource was updated to the same tag
+func uniqueBranch(branch string, marker string, open []*forge.PullRequest) string {
+	for _, pr := range open {
+		if pr.Head == branch {
+			sum := sha1.Sum([]byte(marker))
+			return branch + "-" + hex.EncodeToString(sum[:])[:8]
+		}
+	}
+	return branch
+}
+
+// pullRequestTarget - branch and marker of the pull request for changes. Single changes are
+// keyed by resource and image, batches by the set of updated resources and images, ie:
+// bow/batch-1a2b3c4d
+func pullRequestTarget(changes []*Change, commit plumbing.Hash) (branch string, marker string, err error) {
+	if len(changes) == 1 {
+		ref, err := image.Parse(changes[0].NewImage())
+		if err != nil {
+			return "", "", err
+		}
+		return pullRequestBranch(ref), pullRequestMarker(changes[0]), nil
+	}
+	return pullRequestBranchPrefix + "batch-" + commit.String()[:8], pullRequestMarker(changes...), nil
+}
+
+// revertPullRequestTarget - branch and marker of the pull request reverting commit,
+// ie: bow/revert-1a2b3c4d
+func revertPullRequestTarget(commit string) (branch string, marker string) {
+	return pullRequestBranchPrefix + "revert-" + commit[:8], "bow-revert: " + commit
+}
+
+// pushPullRequest - pushes commit to branch and opens a pull request for it. An already open
+// pull request with marker in its body is reused, its branch is overwritten with the new commit.
+// ErrPullRequestOpen is returned when that pull request has the same title, ie: is for the same update.
+// The local branch is reset to base afterwards, so the watched branch stays untouched.
+func (r *Repo) pushPullRequest(w *git.Worktree, base, commit plumbing.Hash, msg string, branch string, marker string) error {
+	defer func() {
+		err := w.Reset(&git.ResetOptions{Commit: base, Mode: git.HardReset})
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+				"repo":  r.Name,
+			}).Error("repo.pushPullRequest: failed to reset worktree after pushing pull request branch")
+		}
+	}()
+
+	open, err := r.forge.ListOpen(r.Branch.Short())
+	if err != nil {
+		return fmt.Errorf("failed to list open pull requests: %s", err)
+	}
+	r.forgetPullRequests(open)
+
+	var existing *forge.PullRequest
+	for _, pr := range open {
+		if hasMarker(pr.Body, marker) {
+			existing = pr
+			break
+		}
+	}
+
+	title := strings.SplitN(msg, "\n", 2)[0]
+	if existing != nil && existing.Title == title {
+		logrus.WithFields(logrus.Fields{
+			"repo":         r.Name,
+			"pull_request": existing.URL,
+		}).Debug("repo.pushPullRequest: pull request for this update is already open")
+		return ErrPullRequestOpen
+	}
+
+	if existing != nil {
+		branch = existing.Head
+	} else {
+		branch = uniqueBranch(branch, marker, open)
+	}
+
+	refName := plumbing.NewBranchReferenceName(branch)
+	err = r.repository.Storer.SetReference(plumbing.NewHashReference(refName, commit))
+	if err != nil {
+		return err
+	}
+	defer r.repository.Storer.RemoveReference(refName)
+
+	logrus.Debug("repo.pushPullRequest: pushing git commit to ", branch)
+	err = r.repository.Push(&git.PushOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{config.RefSpec("+" + refName.String() + ":" + refName.String())},
+		Auth:       r.auth,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return err
+	}
+
+	pr := &forge.PullRequest{
+		Title: title,
+		Body:  msg + "\n\n" + marker,
+		Head:  branch,
+		Base:  r.Branch.Short(),
+	}
+	if existing != nil {
+		pr.Number = existing.Number
+		pr, err = r.forge.Update(pr)
+	} else {
+		pr, err = r.forge.Create(pr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open pull request: %s", err)
+	}
+	if pr.Head == "" {
+		pr.Head = branch
+	}
+	r.recordPullRequest(commit, pr)
+
+	logrus.WithFields(logrus.Fields{
+		"repo":         r.Name,
+		"branch":       branch,
+		"pull_request": pr.URL,
+	}).Info("repo.pushPullRequest: pull request opened")
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package gitrepo
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alwinius/bow/internal/forge"
+)
+
+// fakeForge - minimal GitHub API stand-in keeping pull requests in memory
+type fakeForge struct {
+	t   *testing.T
+	prs []map[string]interface{}
+}
+
+func (f *fakeForge) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case "GET":
+		json.NewEncoder(resp).Encode(f.prs)
+	case "POST":
+		var in map[string]string
+		json.NewDecoder(req.Body).Decode(&in)
+		pr := map[string]interface{}{
+			"number": len(f.prs) + 1,
+			"title":  in["title"],
+			"body":   in["body"],
+			"head":   map[string]string{"ref": in["head"]},
+			"base":   map[string]string{"ref": in["base"]},
+		}
+		f.prs = append(f.prs, pr)
+		resp.WriteHeader(http.StatusCreated)
+		json.NewEncoder(resp).Encode(pr)
+	case "PATCH":
+		var in map[string]string
+		json.NewDecoder(req.Body).Decode(&in)
+		pr := f.prs[0]
+		pr["title"] = in["title"]
+		pr["body"] = in["body"]
+		json.NewEncoder(resp).Encode(pr)
+	default:
+		f.t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+	}
+}
+
+func TestPullRequestMode(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRemote(t, dir, map[string]string{
+		"deployment.yaml": "image: nginx:1.0.0\n",
+		"cache.yaml":      "image: nginx:1.0.0\n",
+	})
+	repo := newTestRepo(t, dir, remote)
+
+	ff := &fakeForge{t: t}
+	ts := httptest.NewServer(ff)
+	defer ts.Close()
+
+	var err error
+	repo.forge, err = forge.New(forge.Opts{Kind: "github", APIURL: ts.URL, Project: "team/deployment", Token: "secret"})
+	if err != nil {
+		t.Fatalf("failed to create forge client: %s", err)
+	}
+
+	const branch = "bow/nginx-1.1.0"
+	update := func(file, resource, tag string) string {
+		writeTestFile(t, filepath.Join(repo.LocalPath, file), "image: nginx:"+tag+"\n")
+		commit, err := repo.CommitAndPushAll(&Change{Image: "nginx:1.0.0", Repository: "nginx", OldTag: "1.0.0", NewTag: tag, Resource: resource})
+		if err != nil {
+			t.Fatalf("failed to commit and push: %s", err)
+		}
+		return commit
+	}
+
+	commit := update("deployment.yaml", "prod/deployment/default/web", "1.1.0")
+
+	if len(ff.prs) != 1 {
+		t.Fatalf("expected one pull request, got %d", len(ff.prs))
+	}
+	if ff.prs[0]["head"].(map[string]string)["ref"] != branch {
+		t.Errorf("unexpected head branch: %v", ff.prs[0]["head"])
+	}
+
+	// watched branch stays untouched, the change is on the pull request branch
+	if content := runGit(t, dir, "--git-dir", remote, "show", "master:deployment.yaml"); content != "image: nginx:1.0.0\n" {
+		t.Errorf("master was changed: %s", content)
+	}
+	if content := runGit(t, dir, "--git-dir", remote, "show", branch+":deployment.yaml"); content != "image: nginx:1.1.0\n" {
+		t.Errorf("unexpected content on pull request branch: %s", content)
+	}
+	if metadata := repo.CommitMetadata(commit); metadata["branch"] != branch || metadata["commit"] != commit {
+		t.Errorf("unexpected commit metadata: %v", metadata)
+	}
+	local, _ := ioutil.ReadFile(filepath.Join(repo.LocalPath, "deployment.yaml"))
+	if string(local) != "image: nginx:1.0.0\n" {
+		t.Errorf("local checkout was not reset: %s", local)
+	}
+
+	// newer tag reuses the open pull request and its branch
+	update("deployment.yaml", "prod/deployment/default/web", "1.2.0")
+
+	if len(ff.prs) != 1 {
+		t.Fatalf("expected pull request to be reused, got %d", len(ff.prs))
+	}
+	if !strings.Contains(ff.prs[0]["title"].(string), "1.2.0") {
+		t.Errorf("pull request title not updated: %v", ff.prs[0]["title"])
+	}
+	if content := runGit(t, dir, "--git-dir", remote, "show", branch+":deployment.yaml"); content != "image: nginx:1.2.0\n" {
+		t.Errorf("unexpected content on pull request branch: %s", content)
+	}
+	if len(repo.pullRequests) != 1 {
+		t.Errorf("expected only the latest commit of the pull request to be recorded, got %d", len(repo.pullRequests))
+	}
+
+	// the same update again pushes nothing
+	writeTestFile(t, filepath.Join(repo.LocalPath, "deployment.yaml"), "image: nginx:1.2.0\n")
+	commit, err = repo.CommitAndPushAll(&Change{Image: "nginx:1.0.0", Repository: "nginx", OldTag: "1.0.0", NewTag: "1.2.0", Resource: "prod/deployment/default/web"})
+	if err != ErrPullRequestOpen || commit != "" {
+		t.Errorf("expected ErrPullRequestOpen without commit, got %q, %v", commit, err)
+	}
+	if len(ff.prs) != 1 {
+		t.Fatalf("expected pull request to be reused, got %d", len(ff.prs))
+	}
+
+	// the same image of another resource gets a pull request of its own, its branch name is
+	// taken by the pull request of web already
+	update("cache.yaml", "prod/deployment/default/cache", "1.1.0")
+
+	if len(ff.prs) != 2 {
+		t.Fatalf("expected a second pull request, got %d", len(ff.prs))
+	}
+	cacheBranch := ff.prs[1]["head"].(map[string]string)["ref"]
+	if !strings.HasPrefix(cacheBranch, branch+"-") || len(cacheBranch) != len(branch)+9 {
+		t.Errorf("unexpected head branch: %v", cacheBranch)
+	}
+	if content := runGit(t, dir, "--git-dir", remote, "show", branch+":deployment.yaml"); content != "image: nginx:1.2.0\n" {
+		t.Errorf("pull request branch of web was changed: %s", content)
+	}
+
+	// closed pull requests are forgotten
+	if len(repo.pullRequests) != 2 {
+		t.Errorf("expected two recorded pull requests, got %d", len(repo.pullRequests))
+	}
+	repo.forgetPullRequests([]*forge.PullRequest{{Number: 2}})
+	if len(repo.pullRequests) != 1 {
+		t.Errorf("expected the closed pull request to be forgotten, got %d", len(repo.pullRequests))
+	}
+}
@@ -0,0 +1,148 @@
+package gitrepo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+// pushRetries - how often a rejected push is retried on top of the new remote head
+const pushRetries = 3
+
+// pushBackoff - wait before the first retry, doubled for every further one
+var pushBackoff = 2 * time.Second
+
+// pushWait - waits between push attempts
+var pushWait = time.Sleep
+
+// push - pushes the watched branch only, the clone also carries a local refs/heads/HEAD
+func (r *Repo) push() error {
+	return r.repository.Push(&git.PushOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{config.RefSpec(r.Branch + ":" + r.Branch)},
+		Auth:       r.auth,
+	})
+}
+
+// isPushRejected - the remote branch moved since the last pull
+func isPushRejected(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), "non-fast-forward") || strings.Contains(err.Error(), "fetch first"))
+}
+
+// pushWithRetry - pushes commit. If the push is rejected because someone else pushed in the
+// meantime, the checkout is reset to the new remote head, rewrite writes the files again and they
+// are committed again. On failure the checkout is reset to the remote head, so no unpushed commit
+// stays behind. fileAccessLock must be held, it is released while waiting between attempts.
+func (r *Repo) pushWithRetry(w *git.Worktree, c *committer, msg string, commit plumbing.Hash, rewrite func() error) (plumbing.Hash, error) {
+	err := r.push()
+	backoff := pushBackoff
+	for attempt := 1; isPushRejected(err) && attempt <= pushRetries; attempt++ {
+		logrus.WithFields(logrus.Fields{
+			"repo":    r.Name,
+			"branch":  r.Branch.Short(),
+			"attempt": attempt,
+			"error":   err,
+		}).Warn("repo.pushWithRetry: push rejected, re-applying changes on top of remote branch")
+		err = r.waitUnlocked(w, backoff)
+		if err != nil {
+			break
+		}
+		backoff *= 2
+
+		// the repository may have been cloned again while waiting
+		w, err = r.repository.Worktree()
+		if err != nil {
+			break
+		}
+		commit, err = r.reapply(w, c, msg, rewrite)
+		if err != nil {
+			break
+		}
+		err = r.push()
+	}
+	if err == nil {
+		return commit, nil
+	}
+
+	if resetErr := r.resetToRemote(w); resetErr != nil {
+		logrus.WithFields(logrus.Fields{
+			"repo":  r.Name,
+			"error": resetErr,
+		}).Error("repo.pushWithRetry: failed to reset checkout to remote branch")
+	}
+	if isPushRejected(err) {
+		return plumbing.ZeroHash, fmt.Errorf("push to %s rejected %d times, giving up: %s", r.Branch.Short(), pushRetries+1, err)
+	}
+	return plumbing.ZeroHash, fmt.Errorf("failed to push to %s: %s", r.Branch.Short(), err)
+}
+
+// waitUnlocked - resets the checkout to the remote head and waits for d without holding
+// fileAccessLock, so pulls and other updates of the repository are not blocked by the backoff
+func (r *Repo) waitUnlocked(w *git.Worktree, d time.Duration) error {
+	err := r.resetToRemote(w)
+	if err != nil {
+		return err
+	}
+	r.fileAccessLock.Unlock()
+	defer r.fileAccessLock.Lock()
+	pushWait(d)
+	return nil
+}
+
+// reapply - resets the checkout to the remote head, writes the files again and commits them
+func (r *Repo) reapply(w *git.Worktree, c *committer, msg string, rewrite func() error) (plumbing.Hash, error) {
+	err := r.resetToRemote(w)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	err = rewrite()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return c.commit(r.repository, w, msg)
+}
+
+// rewriteChanges - writes changes on top of the checkout when re-applying them
+func (r *Repo) rewriteChanges(changes []*Change) error {
+	for _, change := range changes {
+		if len(change.Sources) == 0 {
+			return fmt.Errorf("cannot re-apply update of %s, its source files are unknown", change.Image)
+		}
+		updated, err := r.planImageUpdate(r.readFile, change.Sources, change.Image, change.NewTag)
+		if err != nil {
+			return fmt.Errorf("cannot re-apply update of %s on top of remote branch: %s", change.Image, err)
+		}
+		for name, content := range updated {
+			err = r.writeFile(name, content)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resetToRemote - fetches the watched branch and hard resets the checkout to it
+func (r *Repo) resetToRemote(w *git.Worktree) error {
+	remoteRef := plumbing.NewRemoteReferenceName("origin", r.Branch.Short())
+	err := r.repository.Fetch(&git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{config.RefSpec("+" + r.Branch + ":" + remoteRef)},
+		Auth:       r.auth,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to fetch %s: %s", r.Branch.Short(), err)
+	}
+
+	ref, err := r.repository.Reference(remoteRef, true)
+	if err != nil {
+		return err
+	}
+	return w.Reset(&git.ResetOptions{Commit: ref.Hash(), Mode: git.HardReset})
+}
@@ -0,0 +1,96 @@
+package gitrepo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushRejectedReapply(t *testing.T) {
+	pushBackoff = time.Millisecond
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRemote(t, dir, map[string]string{
+		"web.yaml":   "image: nginx:1.0.0\n",
+		"cache.yaml": "image: redis:5.0.0\n",
+	})
+	repo := newTestRepo(t, dir, remote)
+
+	// the repository is not locked during the backoff
+	var waited bool
+	pushWait = func(time.Duration) {
+		locked := make(chan struct{})
+		go func() {
+			repo.fileAccessLock.Lock()
+			repo.fileAccessLock.Unlock()
+			close(locked)
+		}()
+		select {
+		case <-locked:
+			waited = true
+		case <-time.After(time.Second):
+			t.Errorf("repository locked while waiting to retry the push")
+		}
+	}
+	defer func() { pushWait = time.Sleep }()
+
+	// someone pushes between bow's pull and push
+	work := filepath.Join(dir, "work")
+	writeTestFile(t, filepath.Join(work, "cache.yaml"), "image: redis:5.0.1\n")
+	runGit(t, work, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-am", "update redis")
+	runGit(t, work, "push", "-q", remote, "master")
+
+	writeTestFile(t, filepath.Join(repo.LocalPath, "web.yaml"), "image: nginx:1.1.0\n")
+	commit, err := repo.CommitAndPushAll(&Change{Image: "nginx:1.0.0", Repository: "nginx", OldTag: "1.0.0", NewTag: "1.1.0", Sources: []string{"web.yaml"}})
+	if err != nil {
+		t.Fatalf("failed to commit and push: %s", err)
+	}
+
+	if !waited {
+		t.Errorf("push was not retried")
+	}
+	if head := strings.TrimSpace(runGit(t, dir, "--git-dir", remote, "rev-parse", "master")); head != commit {
+		t.Errorf("pushed commit %s, expected %s", head, commit)
+	}
+	if parent := runGit(t, dir, "--git-dir", remote, "log", "-1", "--format=%s", "master~1"); parent != "update redis\n" {
+		t.Errorf("update was not re-applied on top of the remote branch, parent is %s", parent)
+	}
+	for file, content := range map[string]string{"web.yaml": "image: nginx:1.1.0\n", "cache.yaml": "image: redis:5.0.1\n"} {
+		if got := runGit(t, dir, "--git-dir", remote, "show", "master:"+file); got != content {
+			t.Errorf("unexpected content of %s: %s", file, got)
+		}
+	}
+}
+
+func TestPushRejectedConflict(t *testing.T) {
+	pushBackoff = time.Millisecond
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRemote(t, dir, map[string]string{"web.yaml": "image: nginx:1.0.0\n"})
+	repo := newTestRepo(t, dir, remote)
+
+	// the same image was changed by hand, the update cannot be re-applied
+	work := filepath.Join(dir, "work")
+	writeTestFile(t, filepath.Join(work, "web.yaml"), "image: nginx:2.0.0\n")
+	runGit(t, work, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-am", "update nginx")
+	runGit(t, work, "push", "-q", remote, "master")
+
+	writeTestFile(t, filepath.Join(repo.LocalPath, "web.yaml"), "image: nginx:1.1.0\n")
+	_, err := repo.CommitAndPushAll(&Change{Image: "nginx:1.0.0", Repository: "nginx", OldTag: "1.0.0", NewTag: "1.1.0", Sources: []string{"web.yaml"}})
+	if err == nil {
+		t.Fatalf("expected push to fail")
+	}
+
+	// no unpushed commit is left behind
+	head, _ := repo.repository.Head()
+	if remoteHead := strings.TrimSpace(runGit(t, dir, "--git-dir", remote, "rev-parse", "master")); head.Hash().String() != remoteHead {
+		t.Errorf("checkout at %s, expected remote head %s", head.Hash(), remoteHead)
+	}
+	if content := runGit(t, dir, "--git-dir", remote, "show", "master:web.yaml"); content != "image: nginx:2.0.0\n" {
+		t.Errorf("remote was changed: %s", content)
+	}
+}
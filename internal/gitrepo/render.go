@@ -0,0 +1,110 @@
+package gitrepo
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// render modes of a repository path
+const (
+	// RenderAuto - helm if the path contains a Chart.yaml, kustomize if it contains a
+	// kustomization, plain manifests otherwise
+	RenderAuto = "auto"
+	// RenderHelm - path is a Helm chart
+	RenderHelm = "helm"
+	// RenderManifests - path is a directory tree of plain multi-document YAML files
+	RenderManifests = "manifests"
+)
+
+// RenderPath - directory in the repository that is rendered to Kubernetes resources
+type RenderPath struct {
+	Path   string `json:"path"`
+	Render string `json:"render"`
+	// flat manifest paths are not read recursively, set for discovered directories
+	flat bool
+}
+
+func validRenderMode(mode string) bool {
+	switch mode {
+	case "", RenderAuto, RenderHelm, RenderManifests, RenderKustomize:
+		return true
+	}
+	return false
+}
+
+// renderPaths - discovered and configured paths if include globs are set, otherwise the configured
+// paths or the chart path if none are set
+func (r *Repo) renderPaths() ([]RenderPath, error) {
+	if len(r.Include) > 0 {
+		return r.discoverPaths()
+	}
+	if len(r.Paths) > 0 {
+		return r.Paths, nil
+	}
+	return []RenderPath{{Path: r.ChartPath, Render: r.Render}}, nil
+}
+
+// render - renders a single path relative to the repository root
+func (r *Repo) render(p RenderPath) ([]rendered, error) {
+	mode := p.Render
+	if mode == "" || mode == RenderAuto {
+		mode = RenderManifests
+		if r.exists(path.Join(p.Path, "Chart.yaml")) {
+			mode = RenderHelm
+		} else if r.findKustomization(p.Path) != "" {
+			mode = RenderKustomize
+		}
+	}
+
+	switch mode {
+	case RenderHelm:
+		return r.renderChart(p.Path)
+	case RenderManifests:
+		return r.loadManifests(p.Path, !p.flat)
+	case RenderKustomize:
+		return r.renderKustomization(p.Path)
+	}
+	return nil, fmt.Errorf("unknown render mode '%s'", mode)
+}
+
+// loadManifests - reads every YAML file in dir (relative to the repository root), and below it if
+// recursive is set, and splits it into documents. Hidden directories are skipped.
+func (r *Repo) loadManifests(dir string, recursive bool) ([]rendered, error) {
+	var result []rendered
+	err := walkFiles(r.fs, path.Clean(dir), false, func(name string, info os.FileInfo) error {
+		ext := path.Ext(name)
+		if ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+		if !recursive && path.Dir(name) != path.Clean(dir) {
+			return nil
+		}
+
+		content, err := r.readFile(name)
+		if err != nil {
+			return err
+		}
+		for _, doc := range splitDocuments(string(content)) {
+			result = append(result, rendered{content: doc, sources: []string{name}})
+		}
+		return nil
+	})
+	return result, err
+}
+
+var documentSeparator = regexp.MustCompile(`(?m)^---[ \t]*(#.*)?$`)
+
+// splitDocuments - splits multi-document YAML, empty documents are dropped
+func splitDocuments(content string) []string {
+	var docs []string
+	for _, doc := range documentSeparator.Split(content, -1) {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
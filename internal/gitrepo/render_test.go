@@ -0,0 +1,94 @@
+package gitrepo
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const testDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: %[1]s
+spec:
+  template:
+    spec:
+      containers:
+      - name: %[1]s
+        image: %[2]s
+`
+
+func deploymentYaml(name, img string) string {
+	return fmt.Sprintf(testDeployment, name, img)
+}
+
+func resourceName(t *testing.T, obj runtime.Object) string {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		t.Fatalf("unexpected object: %s", err)
+	}
+	return accessor.GetName()
+}
+
+func TestRenderPaths(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRemote(t, dir, map[string]string{
+		"chart/Chart.yaml":                 "name: web\nversion: 0.1.0\n",
+		"chart/values.yaml":                "image:\n  repository: nginx\n  tag: 1.15.0\n",
+		"chart/templates/deployment.yaml":  deploymentYaml("web", "{{ .Values.image.repository }}:{{ .Values.image.tag }}"),
+		"plain/app.yaml":                   deploymentYaml("api", "api:1.0.0") + "---\n# comment only\n---\n" + deploymentYaml("worker", "worker:1.0.0"),
+		"plain/nested/db.yml":              deploymentYaml("db", "postgres:11"),
+		"plain/.hidden/ignored.yaml":       deploymentYaml("ignored", "ignored:1"),
+		"plain/README.md":                  "not yaml",
+		"forced/templates/deployment.yaml": deploymentYaml("forced", "forced:1.0.0"),
+	})
+	repo := newTestRepo(t, dir, remote)
+	repo.Paths = []RenderPath{
+		{Path: "chart"},
+		{Path: "plain"},
+		{Path: "forced", Render: RenderManifests},
+	}
+
+	var names []string
+	sources := make(map[string][]string)
+	manifests, err := repo.getManifests()
+	if err != nil {
+		t.Fatalf("failed to render: %s", err)
+	}
+	for _, m := range manifests {
+		gr, err := yamlToGenericResource(m.content)
+		if err != nil || gr == nil {
+			continue
+		}
+		name := resourceName(t, gr)
+		names = append(names, name)
+		sources[name] = m.sources
+	}
+	sort.Strings(names)
+
+	want := []string{"api", "db", "forced", "web", "worker"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("unexpected resources: %v", names)
+	}
+	if !reflect.DeepEqual(sources["db"], []string{"plain/nested/db.yml"}) {
+		t.Errorf("unexpected sources for db: %v", sources["db"])
+	}
+	if !reflect.DeepEqual(sources["web"], []string{"chart/templates/deployment.yaml", "chart/values.yaml"}) {
+		t.Errorf("unexpected sources for web: %v", sources["web"])
+	}
+}
+
+func TestSplitDocuments(t *testing.T) {
+	docs := splitDocuments("---\na: 1\n--- # second\nb: 2\n---\n\n")
+	if len(docs) != 2 || strings.TrimSpace(docs[0]) != "a: 1" || strings.TrimSpace(docs[1]) != "b: 2" {
+		t.Errorf("unexpected documents: %q", docs)
+	}
+}
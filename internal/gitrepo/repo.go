@@ -1,125 +1,91 @@
 package gitrepo
 
 import (
+	"errors"
 	"fmt"
-	"github.com/alwinius/bow/provider/helm"
-	"github.com/alwinius/bow/util/image"
+	"github.com/alwinius/bow/internal/forge"
 	"github.com/sirupsen/logrus"
-	cryptossh "golang.org/x/crypto/ssh"
+	"gopkg.in/src-d/go-billy.v4"
 	"gopkg.in/src-d/go-git.v4"
-	"gopkg.in/src-d/go-git.v4/config"
 	"gopkg.in/src-d/go-git.v4/plumbing"
-	"gopkg.in/src-d/go-git.v4/plumbing/object"
 	"gopkg.in/src-d/go-git.v4/plumbing/transport"
-	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
-	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
-	"io/ioutil"
 	"k8s.io/helm/pkg/manifest"
-	"os"
+	"net/url"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 )
 
+// Repo - watched GitOps repository and its local checkout
 type Repo struct {
-	ChartPath      string
-	Username       string
-	Password       string
-	URL            string
-	LocalPath      string
+	// Name identifies the repository in resource annotations
+	Name      string
+	ChartPath string
+	URL       string
+	LocalPath string
+	// Storage is either StorageDisk (default) or StorageMemory, LocalPath is unused for the latter
+	Storage string
+	// Shallow clones and pulls only the latest commit of Branch
+	Shallow        bool
+	fs             billy.Filesystem
 	auth           transport.AuthMethod
 	repository     *git.Repository
 	fileAccessLock sync.Mutex
 	Branch         plumbing.ReferenceName
+	// Render is the render mode of ChartPath, Paths replace ChartPath if set
+	Render string
+	Paths  []RenderPath
+	// Include globs (ie: apps/*) enable discovery of charts, kustomizations and manifest
+	// directories, in addition to Paths. Exclude globs skip directories and everything below them.
+	Include []string
+	Exclude []string
+	// Interval between polls of the remote, webhooks trigger a refresh in between
+	Interval time.Duration
+	// WebhookSecret is used to verify push webhooks from the forge
+	WebhookSecret string
+	// BatchWindow during which approved updates are collected into a single commit
+	BatchWindow time.Duration
+	refresh     chan struct{}
+	refreshOnce sync.Once
+	// forge is set in pull request mode, forgeKind whenever it is configured
+	forge     forge.Client
+	forgeKind string
+	committer *committer
+	// pull requests commits were pushed to by commit hash
+	pullRequests     map[string]*forge.PullRequest
+	pullRequestsLock sync.Mutex
 }
 
+// ErrIncompleteRender - at least one path of the repository could not be rendered
+var ErrIncompleteRender = errors.New("repository was not rendered completely")
+
+// DefaultInterval - default time between polls of the remote
+const DefaultInterval = 30 * time.Second
+
 const committerName = "bow"
 const committerEMail = "admin@example.com"
 
+// init - clones the repository on first use and pulls the watched branch afterwards
 func (r *Repo) init() {
-	var repository *git.Repository
-	var err error
 	r.fileAccessLock.Lock()
 	defer r.fileAccessLock.Unlock()
 	if r.repository == nil {
-		r.setupAuth()
-
-		if repository, err = git.PlainOpen(r.LocalPath); err == nil {
-			origin, err := repository.Remote("origin")
-			if err != nil {
-				logrus.Debug("cannot retrieve remote, cloning again")
-				repository, err = r.newClone()
-				if err != nil {
-					logrus.Error("error during clone: ", err)
-					return
-				}
-			} else if origin.Config().URLs[0] != r.URL {
-				logrus.Debug("repository changed, cloning again")
-				repository, err = r.newClone()
-				if err != nil {
-					logrus.Error("error during clone: ", err)
-					return
-				}
-			} else { // pulling
-				r.repository = repository
-				err = r.pull()
-				if err != nil {
-					logrus.Error(err)
-					repository, err = r.newClone()
-					if err != nil {
-						logrus.Error("error during pull and clone: ", err)
-						return
-					}
-				}
-			}
-		} else {
-			logrus.Debug("no repo found, cloning")
-			repository, err = r.newClone()
-			if err != nil {
-				logrus.Error("error during clone: ", err)
-				return
-			}
-		}
-		r.repository = repository
-	} else {
-		err = r.pull()
+		_, err := r.open()
 		if err != nil {
-			logrus.Error(err)
-			repository, err = r.newClone()
-			if err != nil {
-				logrus.Error("error during pull and clone: ", err)
-				return
-			}
+			logrus.Error("error during clone: ", err)
 		}
+		return
 	}
-}
 
-func (r *Repo) setupAuth() {
-	if r.Username != "" && r.Password != "" {
-		logrus.Debug("repo.setupAuth: setting up username/password authentication for git client")
-		r.auth = &http.BasicAuth{
-			Username: r.Username,
-			Password: r.Password,
-		}
-	} else {
-		logrus.Debug("repo.setupAuth: attempting SSH authentication for git client")
-		s := filepath.Join(os.Getenv("HOME"), ".ssh", "id_rsa")
-		sshKey, err := ioutil.ReadFile(s)
-		if err != nil {
-			logrus.WithFields(logrus.Fields{
-				"error":   err,
-				"keyPath": s,
-			}).Error("repo.setupAuth: failed to read ssh key")
-		}
-		signer, err := cryptossh.ParsePrivateKey(sshKey)
+	err := r.pull()
+	if err != nil {
+		logrus.Error(err)
+		_, err = r.newClone()
 		if err != nil {
-			logrus.WithFields(logrus.Fields{
-				"error":   err,
-				"keyPath": s,
-			}).Error("repo.setupAuth: failed to parse ssh private key")
+			logrus.Error("error during pull and clone: ", err)
 		}
-		r.auth = &ssh.PublicKeys{User: "git", Signer: signer}
 	}
 }
 
@@ -127,16 +93,24 @@ func (r *Repo) pull() error {
 	w, _ := r.repository.Worktree()
 	logrus.Info("pulling git changes")
 
-	err := w.Pull(&git.PullOptions{
+	opts := &git.PullOptions{
 		Auth:          r.auth,
 		Force:         true,
 		RemoteName:    "origin",
 		ReferenceName: r.Branch,
-	})
+		SingleBranch:  true,
+	}
+	if r.Shallow {
+		opts.Depth = 1
+	}
+	err := w.Pull(opts)
 
 	if err != nil && err != git.NoErrAlreadyUpToDate {
 		return err
 	}
+	if err == nil {
+		defer r.updateCheckoutSize()
+	}
 
 	err = w.Checkout(&git.CheckoutOptions{
 		Branch: r.Branch,
@@ -150,127 +124,250 @@ func (r *Repo) pull() error {
 	}
 }
 
-func (r *Repo) getManifests() []manifest.Manifest {
-	r.init()
+// rendered - manifest and the files it was produced from, relative to the repository root
+type rendered struct {
+	content string
+	sources []string
+	// namespace of resources that do not set one
+	namespace string
+	// app is the rendered path relative to the repository root
+	app string
+}
+
+// getManifests - renders all paths of the repository. Paths that fail to render are skipped,
+// ErrIncompleteRender is returned along with the manifests of the remaining paths.
+func (r *Repo) getManifests() ([]rendered, error) {
 	r.fileAccessLock.Lock()
 	defer r.fileAccessLock.Unlock()
 	ref, _ := r.repository.Head()
 	commit, _ := r.repository.CommitObject(ref.Hash())
 	logrus.Debug("repo.getManifests: last commit: ", commit.Message)
 
-	finalManifests, err := helm.ProcessTemplate(r.LocalPath + "/" + r.ChartPath) // because of filepath.abs in main, path is always without /
+	var result []rendered
+	var failed error
+	paths, err := r.renderPaths()
+	if err != nil {
+		// resources of apps that were not found must not be deleted
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+			"repo":  r.Name,
+		}).Error("gitrepo: failed to discover apps")
+		failed = ErrIncompleteRender
+	}
+	for _, p := range paths {
+		manifests, err := r.render(p)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+				"repo":  r.Name,
+				"path":  p.Path,
+			}).Error("gitrepo: failed to render path")
+			failed = ErrIncompleteRender
+			continue
+		}
+		for i := range manifests {
+			manifests[i].app = p.Path
+		}
+		result = append(result, manifests...)
+	}
+	return result, failed
+}
+
+// chartSources - maps rendered template (ie: mychart/charts/sub/templates/deployment.yaml) to the
+// template file and the values files of its chart
+func chartSources(chartPath string, m manifest.Manifest) []string {
+	parts := strings.SplitN(filepath.ToSlash(m.Name), "/", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+	template := path.Join(chartPath, parts[1])
+	sources := []string{template, path.Join(chartPath, "values.yaml")}
+
+	// subcharts have their own defaults
+	if i := strings.LastIndex(parts[1], "/templates/"); i > 0 {
+		sources = append(sources, path.Join(chartPath, parts[1][:i], "values.yaml"))
+	}
+	return sources
+}
+
+// head - hash of the checked out commit
+func (r *Repo) head() (plumbing.Hash, error) {
+	r.fileAccessLock.Lock()
+	defer r.fileAccessLock.Unlock()
+	if r.repository == nil {
+		return plumbing.ZeroHash, fmt.Errorf("repository %s is not cloned", r.Name)
+	}
+	ref, err := r.repository.Head()
 	if err != nil {
-		fmt.Println(err)
+		return plumbing.ZeroHash, err
+	}
+	return ref.Hash(), nil
+}
+
+// Refresh asks the watcher to pull and render the repository right away
+func (r *Repo) Refresh() {
+	select {
+	case r.refreshed() <- struct{}{}:
+	default:
+		// refresh already pending
 	}
+}
 
-	return finalManifests
+func (r *Repo) refreshed() chan struct{} {
+	r.refreshOnce.Do(func() {
+		r.refresh = make(chan struct{}, 1)
+	})
+	return r.refresh
+}
+
+// Matches checks whether a push to ref (ie: refs/heads/master) of the repository
+// at url concerns the watched branch of this repository
+func (r *Repo) Matches(url string, ref string) bool {
+	if ref != r.Branch.String() {
+		return false
+	}
+	project := forge.ProjectFromURL(url)
+	return project != "" && project == forge.ProjectFromURL(r.URL)
 }
 
-func (r *Repo) CommitAndPushAll(msg string) error {
+// CommitAndPushAll commits all changes with a message rendered from changes and pushes them to
+// the watched branch. In pull request mode the commit is pushed to a separate branch for the new
+// image and a pull request is opened instead. Returns the hash of the pushed commit, empty if
+// nothing changed. ErrPullRequestOpen is returned when the pull request for the update is open already.
+func (r *Repo) CommitAndPushAll(changes ...*Change) (string, error) {
 	r.fileAccessLock.Lock()
 	defer r.fileAccessLock.Unlock()
+	return r.commitAndPush(changes)
+}
+
+// commitAndPush - CommitAndPushAll without locking, fileAccessLock must be held
+func (r *Repo) commitAndPush(changes []*Change) (string, error) {
+	if len(changes) == 0 {
+		return "", fmt.Errorf("no changes to commit")
+	}
+
 	w, err := r.repository.Worktree()
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	changes, err := w.Status()
+	c, err := r.getCommitter()
 	if err != nil {
-		return err
+		return "", err
+	}
+	msg, err := c.messageFor(changes...)
+	if err != nil {
+		return "", err
 	}
-	if len(changes) > 0 {
-		_, err = w.Commit(msg, &git.CommitOptions{
-			All: true,
-			Author: &object.Signature{
-				Name:  committerName,
-				Email: committerEMail,
-				When:  time.Now(),
-			},
-		})
-		if err != nil {
-			return err
-		}
 
-		logrus.Debug("repo.CommitAndPushAll: pushing git commit ", msg)
-		err = r.repository.Push(&git.PushOptions{
-			Auth: r.auth,
-		})
-		if err != nil {
-			return err
-		}
-	} else {
+	status, err := w.Status()
+	if err != nil {
+		return "", err
+	}
+	if len(status) == 0 {
 		logrus.Error("repo.CommitAndPushAll: no files changed ", msg)
+		return "", nil
 	}
-	return nil
-}
 
-func (r *Repo) newClone() (*git.Repository, error) {
-	err := os.RemoveAll(r.LocalPath)
+	head, err := r.repository.Head()
 	if err != nil {
-		logrus.Warn(err)
+		return "", err
 	}
-	err = os.MkdirAll(r.LocalPath, 0755)
+
+	commit, err := c.commit(r.repository, w, msg)
 	if err != nil {
-		logrus.Warn(err)
+		return "", err
 	}
 
-	logrus.Debug("cloning git repo")
-	c, err := git.PlainClone(r.LocalPath, false, &git.CloneOptions{
-		Auth:          r.auth,
-		URL:           r.URL,
-		ReferenceName: r.Branch,
-	})
+	if r.forge != nil {
+		branch, marker, err := pullRequestTarget(changes, commit)
+		if err != nil {
+			return "", err
+		}
+		err = r.pushPullRequest(w, head.Hash(), commit, msg, branch, marker)
+		if err != nil {
+			return "", err
+		}
+		return commit.String(), nil
+	}
+
+	logrus.Debug("repo.CommitAndPushAll: pushing git commit ", msg)
+	commit, err = r.pushWithRetry(w, c, msg, commit, func() error { return r.rewriteChanges(changes) })
 	if err != nil {
-		return c, err
+		return "", err
 	}
+	return commit.String(), nil
+}
 
-	err = c.Fetch(&git.FetchOptions{
-		RefSpecs: []config.RefSpec{"refs/*:refs/*", "HEAD:refs/heads/HEAD"},
-		Auth:     r.auth,
-	})
-	return c, err
+// getCommitter - configured committer, the default identity without signing otherwise
+func (r *Repo) getCommitter() (*committer, error) {
+	if r.committer != nil {
+		return r.committer, nil
+	}
+	return newCommitter(nil)
 }
 
-func (r *Repo) GrepAndReplace(oldImage string, newTag string) {
-	r.init()
-	r.fileAccessLock.Lock()
-	defer r.fileAccessLock.Unlock()
-	ref, err := image.Parse(oldImage)
-
-	err = filepath.Walk(r.LocalPath,
-		func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if !info.IsDir() {
-				reader, err := os.Open(path)
-				if err != nil {
-					logrus.Fatal(err)
-				}
-				defer reader.Close()
-
-				var changed string
-				b, err := ioutil.ReadAll(reader)
-				if ref.Registry() == image.DefaultRegistryHostname {
-					changed = strings.ReplaceAll(string(b), oldImage, fmt.Sprintf("%s:%s", ref.ShortName(), newTag))
-				} else {
-					changed = strings.ReplaceAll(string(b), oldImage, fmt.Sprintf("%s:%s", ref.Repository(), newTag))
-				}
-
-				if changed != string(b) {
-					writer, _ := os.Create(path)
-					defer writer.Close()
-					_, err = writer.WriteString(changed)
-
-					if err != nil {
-						logrus.Fatal(err)
-					}
-				}
-			}
-
-			return err
-		})
-	if err != nil {
-		logrus.Error(err)
+// CommitMetadata - hash, pushed branch, repository URL without credentials and web link of a
+// commit made by bow, for notifications and audit logs. In pull request mode the branch is the
+// pull request branch and the pull request is linked as well.
+func (r *Repo) CommitMetadata(commit string) map[string]string {
+	if commit == "" {
+		return map[string]string{}
+	}
+	metadata := map[string]string{
+		"commit":    commit,
+		"branch":    r.Branch.Short(),
+		"repo":      r.Name,
+		"repoURL":   redactURL(r.URL),
+		"commitURL": forge.CommitURL(r.forgeKind, r.URL, commit),
+	}
+
+	r.pullRequestsLock.Lock()
+	defer r.pullRequestsLock.Unlock()
+	if pr, ok := r.pullRequests[commit]; ok {
+		metadata["branch"] = pr.Head
+		metadata["pullRequestURL"] = pr.URL
+	}
+	return metadata
+}
+
+// recordPullRequest - remembers the pull request commit was pushed to for CommitMetadata, only
+// the latest commit of a pull request is kept
+func (r *Repo) recordPullRequest(commit plumbing.Hash, pr *forge.PullRequest) {
+	r.pullRequestsLock.Lock()
+	defer r.pullRequestsLock.Unlock()
+	if r.pullRequests == nil {
+		r.pullRequests = make(map[string]*forge.PullRequest)
+	}
+	for c, recorded := range r.pullRequests {
+		if recorded.Number == pr.Number {
+			delete(r.pullRequests, c)
+		}
+	}
+	r.pullRequests[commit.String()] = pr
+}
+
+// forgetPullRequests - drops recorded pull requests which are not open anymore
+func (r *Repo) forgetPullRequests(open []*forge.PullRequest) {
+	numbers := make(map[int]bool, len(open))
+	for _, pr := range open {
+		numbers[pr.Number] = true
+	}
+	r.pullRequestsLock.Lock()
+	defer r.pullRequestsLock.Unlock()
+	for c, recorded := range r.pullRequests {
+		if !numbers[recorded.Number] {
+			delete(r.pullRequests, c)
+		}
+	}
+}
+
+// redactURL - drops user and password from http(s) URLs
+func redactURL(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.User == nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return u
 	}
+	parsed.User = nil
+	return parsed.String()
 }
@@ -0,0 +1,250 @@
+package gitrepo
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+)
+
+// revertSearchDepth - commits searched for the last update of a resource
+const revertSearchDepth = 1000
+
+// revertedCommit - body line of commits created by git revert and RevertLastUpdate
+var revertedCommit = regexp.MustCompile(`This (?:partially )?reverts commit ([0-9a-f]{40})`)
+
+// revertedFiles - trailer of partial reverts listing the reverted files, ie: of batch commits
+var revertedFiles = regexp.MustCompile(`(?m)^Reverted-Files: (.+)$`)
+
+// Revert - revert of a commit bow made for an update
+type Revert struct {
+	// Reverted is the hash of the reverted commit, Subject its first line
+	Reverted string
+	Subject  string
+	// Commit is the hash of the revert commit, pushed to a pull request branch in pull request mode
+	Commit string
+	Files  []string
+}
+
+// RevertLastUpdate reverts the newest commit authored by bow that changed one of sources, the
+// way git revert does. Commits that were reverted already, by bow or with git revert, are skipped.
+// Only sources are reverted, other files of the commit, ie: of other resources updated in the same
+// batch, are kept and the commit stays revertable for them.
+// The revert is pushed to the watched branch, in pull request mode a pull request is opened.
+// Files changed again since the reverted commit are not merged, the revert fails instead.
+func (r *Repo) RevertLastUpdate(sources []string) (*Revert, error) {
+	r.init()
+	r.fileAccessLock.Lock()
+	defer r.fileAccessLock.Unlock()
+
+	if r.repository == nil {
+		return nil, fmt.Errorf("repository %s is not cloned", r.Name)
+	}
+	c, err := r.getCommitter()
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := r.lastUpdate(c, sources)
+	if err != nil {
+		return nil, err
+	}
+	files, partial, err := r.revertFiles(target, sources)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := r.repository.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	head, err := r.repository.Head()
+	if err != nil {
+		return nil, err
+	}
+
+	subject := strings.SplitN(target.Message, "\n", 2)[0]
+	msg := fmt.Sprintf("Revert \"%s\"\n\nThis reverts commit %s.\n", subject, target.Hash)
+	if partial {
+		msg = fmt.Sprintf("Revert \"%s\" in %s\n\nThis partially reverts commit %s.\n\nReverted-Files: %s\n",
+			subject, strings.Join(files, ", "), target.Hash, strings.Join(files, ", "))
+	}
+	commit, err := c.commit(r.repository, w, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.forge != nil {
+		branch, marker := revertPullRequestTarget(target.Hash.String())
+		err = r.pushPullRequest(w, head.Hash(), commit, msg, branch, marker)
+	} else {
+		logrus.Debug("repo.RevertLastUpdate: pushing git commit ", msg)
+		commit, err = r.pushWithRetry(w, c, msg, commit, func() error {
+			_, _, err := r.revertFiles(target, sources)
+			return err
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Revert{
+		Reverted: target.Hash.String(),
+		Subject:  subject,
+		Commit:   commit.String(),
+		Files:    files,
+	}, nil
+}
+
+// lastUpdate - newest commit of c's identity changing one of sources that was not reverted yet,
+// commits reverted partially are found again for their remaining files
+func (r *Repo) lastUpdate(c *committer, sources []string) (*object.Commit, error) {
+	head, err := r.repository.Head()
+	if err != nil {
+		return nil, err
+	}
+	commits, err := r.repository.Log(&git.LogOptions{From: head.Hash(), Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return nil, err
+	}
+	defer commits.Close()
+
+	wanted := make(map[string]bool)
+	for _, name := range sources {
+		wanted[name] = true
+	}
+	reverted := make(map[string]bool)
+	// revertedPartially - files reverted by partial reverts, by reverted commit
+	revertedPartially := make(map[string]map[string]bool)
+
+	var found *object.Commit
+	var searched int
+	err = commits.ForEach(func(commit *object.Commit) error {
+		searched++
+		if searched > revertSearchDepth {
+			return storer.ErrStop
+		}
+		if m := revertedCommit.FindStringSubmatch(commit.Message); m != nil {
+			files := revertedFiles.FindStringSubmatch(commit.Message)
+			if files == nil {
+				reverted[m[1]] = true
+				return nil
+			}
+			if revertedPartially[m[1]] == nil {
+				revertedPartially[m[1]] = make(map[string]bool)
+			}
+			for _, name := range strings.Split(files[1], ", ") {
+				revertedPartially[m[1]][name] = true
+			}
+			return nil
+		}
+		if reverted[commit.Hash.String()] || commit.Author.Name != c.name || commit.Author.Email != c.email {
+			return nil
+		}
+
+		changes, err := commitChanges(commit)
+		if err != nil {
+			return err
+		}
+		done := revertedPartially[commit.Hash.String()]
+		for _, change := range changes {
+			for _, name := range []string{change.From.Name, change.To.Name} {
+				if wanted[name] && !done[name] {
+					found = commit
+					return storer.ErrStop
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search history of %s: %s", r.Name, err)
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no update by %s found for %s in the last %d commits", c.name, strings.Join(sources, ", "), revertSearchDepth)
+	}
+	return found, nil
+}
+
+// commitChanges - files changed by commit, merges and root commits have none
+func commitChanges(commit *object.Commit) (object.Changes, error) {
+	if commit.NumParents() != 1 {
+		return nil, nil
+	}
+	parent, err := commit.Parent(0)
+	if err != nil {
+		return nil, err
+	}
+	from, err := parent.Tree()
+	if err != nil {
+		return nil, err
+	}
+	to, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	return object.DiffTree(from, to)
+}
+
+// revertFiles - writes the content sources had before commit, returns their names. partial is
+// set when commit changed other files too, which are left as they are.
+func (r *Repo) revertFiles(commit *object.Commit, sources []string) (files []string, partial bool, err error) {
+	changes, err := commitChanges(commit)
+	if err != nil {
+		return nil, false, err
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range sources {
+		wanted[name] = true
+	}
+
+	reverted := make(map[string][]byte)
+	for _, change := range changes {
+		if !wanted[change.From.Name] && !wanted[change.To.Name] {
+			partial = true
+			continue
+		}
+		from, to, err := change.Files()
+		if err != nil {
+			return nil, false, err
+		}
+		if from == nil || to == nil {
+			return nil, false, fmt.Errorf("cannot revert commit %s, it adds or removes files", commit.Hash)
+		}
+		old, err := from.Contents()
+		if err != nil {
+			return nil, false, err
+		}
+		updated, err := to.Contents()
+		if err != nil {
+			return nil, false, err
+		}
+		current, err := r.readFile(to.Name)
+		if err != nil {
+			return nil, false, err
+		}
+		if string(current) != updated {
+			return nil, false, fmt.Errorf("%s was changed after commit %s, it has to be reverted manually", to.Name, commit.Hash)
+		}
+		reverted[to.Name] = []byte(old)
+		files = append(files, to.Name)
+	}
+	if len(files) == 0 {
+		return nil, false, fmt.Errorf("commit %s changes none of %s", commit.Hash, strings.Join(sources, ", "))
+	}
+
+	for name, content := range reverted {
+		err = r.writeFile(name, content)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	sort.Strings(files)
+	return files, partial, nil
+}
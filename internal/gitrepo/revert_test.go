@@ -0,0 +1,122 @@
+package gitrepo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRevertLastUpdate(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRemote(t, dir, map[string]string{
+		"web.yaml":   "image: nginx:1.0.0\n",
+		"cache.yaml": "image: redis:5.0.0\n",
+	})
+	repo := newTestRepo(t, dir, remote)
+
+	web := &Change{Image: "nginx:1.0.0", Repository: "nginx", OldTag: "1.0.0", NewTag: "1.1.0", Sources: []string{"web.yaml"}}
+	updated, _, err := repo.Apply([]*Change{web})
+	if err != nil {
+		t.Fatalf("failed to apply update: %s", err)
+	}
+	cache := &Change{Image: "redis:5.0.0", Repository: "redis", OldTag: "5.0.0", NewTag: "5.0.1", Sources: []string{"cache.yaml"}}
+	_, _, err = repo.Apply([]*Change{cache})
+	if err != nil {
+		t.Fatalf("failed to apply update: %s", err)
+	}
+
+	revert, err := repo.RevertLastUpdate([]string{"web.yaml"})
+	if err != nil {
+		t.Fatalf("failed to revert: %s", err)
+	}
+	if revert.Reverted != updated || revert.Subject != "updating nginx:1.0.0 to 1.1.0" || revert.Files[0] != "web.yaml" {
+		t.Errorf("unexpected revert: %+v", revert)
+	}
+	if head := strings.TrimSpace(runGit(t, dir, "--git-dir", remote, "rev-parse", "master")); head != revert.Commit {
+		t.Errorf("pushed commit %s, expected %s", head, revert.Commit)
+	}
+	msg := runGit(t, dir, "--git-dir", remote, "log", "-1", "--format=%B", "master")
+	if !strings.HasPrefix(msg, "Revert \"updating nginx:1.0.0 to 1.1.0\"\n\nThis reverts commit "+updated+".") {
+		t.Errorf("unexpected revert message: %s", msg)
+	}
+	for file, content := range map[string]string{"web.yaml": "image: nginx:1.0.0\n", "cache.yaml": "image: redis:5.0.1\n"} {
+		if got := runGit(t, dir, "--git-dir", remote, "show", "master:"+file); got != content {
+			t.Errorf("unexpected content of %s: %s", file, got)
+		}
+	}
+
+	// reverted updates are skipped, the initial commit was not made by bow
+	_, err = repo.RevertLastUpdate([]string{"web.yaml"})
+	if err == nil || !strings.Contains(err.Error(), "no update by bow found") {
+		t.Errorf("expected no update to be found, got %v", err)
+	}
+
+	// files changed after the update are not merged
+	work := filepath.Join(dir, "work")
+	runGit(t, work, "pull", "-q", remote, "master")
+	writeTestFile(t, filepath.Join(work, "cache.yaml"), "image: redis:5.0.2\n")
+	runGit(t, work, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-am", "update redis")
+	runGit(t, work, "push", "-q", remote, "master")
+	repo.init()
+
+	_, err = repo.RevertLastUpdate([]string{"cache.yaml"})
+	if err == nil || !strings.Contains(err.Error(), "cache.yaml was changed after commit") {
+		t.Errorf("expected conflict, got %v", err)
+	}
+}
+
+func TestRevertBatchCommit(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRemote(t, dir, map[string]string{
+		"web.yaml":   "image: nginx:1.0.0\n",
+		"cache.yaml": "image: redis:5.0.0\n",
+	})
+	repo := newTestRepo(t, dir, remote)
+
+	batch, _, err := repo.Apply([]*Change{
+		{Image: "nginx:1.0.0", Repository: "nginx", OldTag: "1.0.0", NewTag: "1.1.0", Sources: []string{"web.yaml"}},
+		{Image: "redis:5.0.0", Repository: "redis", OldTag: "5.0.0", NewTag: "5.0.1", Sources: []string{"cache.yaml"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to apply batch: %s", err)
+	}
+
+	// only the files of the rolled back resource are reverted
+	revert, err := repo.RevertLastUpdate([]string{"web.yaml"})
+	if err != nil {
+		t.Fatalf("failed to revert: %s", err)
+	}
+	if revert.Reverted != batch || len(revert.Files) != 1 || revert.Files[0] != "web.yaml" {
+		t.Errorf("unexpected revert: %+v", revert)
+	}
+	msg := runGit(t, dir, "--git-dir", remote, "log", "-1", "--format=%B", "master")
+	if !strings.Contains(msg, "This partially reverts commit "+batch+".") || !strings.Contains(msg, "Reverted-Files: web.yaml") {
+		t.Errorf("unexpected revert message: %s", msg)
+	}
+	for file, content := range map[string]string{"web.yaml": "image: nginx:1.0.0\n", "cache.yaml": "image: redis:5.0.1\n"} {
+		if got := runGit(t, dir, "--git-dir", remote, "show", "master:"+file); got != content {
+			t.Errorf("unexpected content of %s: %s", file, got)
+		}
+	}
+
+	// the batch commit is still found for the other resource, but not again for web.yaml
+	_, err = repo.RevertLastUpdate([]string{"web.yaml"})
+	if err == nil || !strings.Contains(err.Error(), "no update by bow found") {
+		t.Errorf("expected no update to be found, got %v", err)
+	}
+	revert, err = repo.RevertLastUpdate([]string{"cache.yaml"})
+	if err != nil {
+		t.Fatalf("failed to revert: %s", err)
+	}
+	if revert.Reverted != batch || len(revert.Files) != 1 || revert.Files[0] != "cache.yaml" {
+		t.Errorf("unexpected revert: %+v", revert)
+	}
+	if got := runGit(t, dir, "--git-dir", remote, "show", "master:cache.yaml"); got != "image: redis:5.0.0\n" {
+		t.Errorf("unexpected content of cache.yaml: %s", got)
+	}
+}
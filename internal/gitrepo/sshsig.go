@@ -0,0 +1,95 @@
+package gitrepo
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// sshSigNamespace - namespace git uses for commit signatures
+const sshSigNamespace = "git"
+
+// sshSigner - creates signatures in the OpenSSH SSHSIG format that git verifies
+// with gpg.format=ssh
+type sshSigner struct {
+	signer ssh.Signer
+	// rsa keys sign with rsa-sha2-512, SHA-1 signatures are rejected by ssh-keygen
+	rsa *rsa.PrivateKey
+}
+
+func newSSHSigner(key []byte, passphrase string) (*sshSigner, error) {
+	raw, err := parsePrivateKey(key, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := ssh.NewSignerFromKey(raw)
+	if err != nil {
+		return nil, err
+	}
+	s := &sshSigner{signer: signer}
+	if k, ok := raw.(*rsa.PrivateKey); ok {
+		s.rsa = k
+	}
+	return s, nil
+}
+
+// sign - returns armored SSH signature of data
+func (s *sshSigner) sign(data []byte) (string, error) {
+	digest := sha512.Sum512(data)
+
+	var signed bytes.Buffer
+	signed.WriteString("SSHSIG")
+	writeSSHString(&signed, []byte(sshSigNamespace))
+	writeSSHString(&signed, nil)
+	writeSSHString(&signed, []byte("sha512"))
+	writeSSHString(&signed, digest[:])
+
+	var sig *ssh.Signature
+	if s.rsa != nil {
+		h := sha512.Sum512(signed.Bytes())
+		blob, err := rsa.SignPKCS1v15(rand.Reader, s.rsa, crypto.SHA512, h[:])
+		if err != nil {
+			return "", err
+		}
+		sig = &ssh.Signature{Format: "rsa-sha2-512", Blob: blob}
+	} else {
+		var err error
+		sig, err = s.signer.Sign(rand.Reader, signed.Bytes())
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var blob bytes.Buffer
+	blob.WriteString("SSHSIG")
+	binary.Write(&blob, binary.BigEndian, uint32(1))
+	writeSSHString(&blob, s.signer.PublicKey().Marshal())
+	writeSSHString(&blob, []byte(sshSigNamespace))
+	writeSSHString(&blob, nil)
+	writeSSHString(&blob, []byte("sha512"))
+	writeSSHString(&blob, ssh.Marshal(sig))
+
+	encoded := base64.StdEncoding.EncodeToString(blob.Bytes())
+	var armored bytes.Buffer
+	armored.WriteString("-----BEGIN SSH SIGNATURE-----\n")
+	for len(encoded) > 70 {
+		fmt.Fprintln(&armored, encoded[:70])
+		encoded = encoded[70:]
+	}
+	fmt.Fprintln(&armored, encoded)
+	armored.WriteString("-----END SSH SIGNATURE-----\n")
+	return armored.String(), nil
+}
+
+func writeSSHString(buf *bytes.Buffer, s []byte) {
+	binary.Write(buf, binary.BigEndian, uint32(len(s)))
+	buf.Write(s)
+}
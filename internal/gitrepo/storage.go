@@ -0,0 +1,229 @@
+package gitrepo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+const (
+	// StorageDisk - checkout below LocalPath (default)
+	StorageDisk = "disk"
+	// StorageMemory - objects and worktree are kept in memory, nothing is written to disk
+	StorageMemory = "memory"
+)
+
+var checkoutSizeGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "git_checkout_size_bytes",
+		Help: "Size of the checked out files and git objects of a repository.",
+	},
+	[]string{"repository"},
+)
+
+var cloneDurationGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "git_clone_duration_seconds",
+		Help: "Duration of the last clone of a repository.",
+	},
+	[]string{"repository"},
+)
+
+func init() {
+	prometheus.MustRegister(checkoutSizeGauge)
+	prometheus.MustRegister(cloneDurationGauge)
+}
+
+func validStorage(storage string) bool {
+	return storage == "" || storage == StorageDisk || storage == StorageMemory
+}
+
+// open - opens the existing checkout or clones the repository, fileAccessLock must be held
+func (r *Repo) open() (*git.Repository, error) {
+	if r.Storage == StorageMemory {
+		return r.newClone()
+	}
+
+	repository, err := git.PlainOpen(r.LocalPath)
+	if err != nil {
+		logrus.Debug("no repo found, cloning")
+		return r.newClone()
+	}
+	origin, err := repository.Remote("origin")
+	if err != nil {
+		logrus.Debug("cannot retrieve remote, cloning again")
+		return r.newClone()
+	}
+	if origin.Config().URLs[0] != r.URL {
+		logrus.Debug("repository changed, cloning again")
+		return r.newClone()
+	}
+
+	r.repository = repository
+	r.fs = osfs.New(r.LocalPath)
+	err = r.pull()
+	if err != nil {
+		logrus.Error(err)
+		return r.newClone()
+	}
+	return repository, nil
+}
+
+// newClone - clones the watched branch only, shallow if configured
+func (r *Repo) newClone() (*git.Repository, error) {
+	opts := &git.CloneOptions{
+		Auth:          r.auth,
+		URL:           r.URL,
+		ReferenceName: r.Branch,
+		SingleBranch:  true,
+	}
+	if r.Shallow {
+		opts.Depth = 1
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"repo":    r.Name,
+		"storage": r.storage(),
+		"shallow": r.Shallow,
+	}).Debug("cloning git repo")
+	start := time.Now()
+
+	var (
+		repository *git.Repository
+		err        error
+	)
+	if r.Storage == StorageMemory {
+		r.fs = memfs.New()
+		repository, err = git.Clone(memory.NewStorage(), r.fs, opts)
+	} else {
+		err = os.RemoveAll(r.LocalPath)
+		if err != nil {
+			logrus.Warn(err)
+		}
+		err = os.MkdirAll(r.LocalPath, 0755)
+		if err != nil {
+			logrus.Warn(err)
+		}
+		r.fs = osfs.New(r.LocalPath)
+		repository, err = git.PlainClone(r.LocalPath, false, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cloneDurationGauge.With(prometheus.Labels{"repository": r.Name}).Set(time.Since(start).Seconds())
+	r.repository = repository
+	r.updateCheckoutSize()
+	return repository, nil
+}
+
+func (r *Repo) storage() string {
+	if r.Storage == "" {
+		return StorageDisk
+	}
+	return r.Storage
+}
+
+// updateCheckoutSize - sets the checkout size metric to the size of the worktree and the git objects
+func (r *Repo) updateCheckoutSize() {
+	var size int64
+	err := walkFiles(r.fs, "", true, func(name string, info os.FileInfo) error {
+		size += info.Size()
+		return nil
+	})
+	if err == nil && r.Storage == StorageMemory {
+		objects, err := r.repository.Storer.IterEncodedObjects(plumbing.AnyObject)
+		if err == nil {
+			err = objects.ForEach(func(obj plumbing.EncodedObject) error {
+				size += obj.Size()
+				return nil
+			})
+		}
+	}
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"repo":  r.Name,
+			"error": err,
+		}).Warn("repo.updateCheckoutSize: failed to determine checkout size")
+		return
+	}
+	checkoutSizeGauge.With(prometheus.Labels{"repository": r.Name}).Set(float64(size))
+}
+
+// walkFiles - calls fn for every file below root (slash separated, relative to the repository
+// root) in lexical order. Hidden directories are skipped unless hidden is set.
+func walkFiles(fs billy.Filesystem, root string, hidden bool, fn func(name string, info os.FileInfo) error) error {
+	dir := root
+	if dir == "" {
+		dir = "."
+	}
+	infos, err := fs.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	for _, info := range infos {
+		name := path.Join(root, info.Name())
+		if info.IsDir() {
+			if !hidden && strings.HasPrefix(info.Name(), ".") {
+				continue
+			}
+			err = walkFiles(fs, name, hidden, fn)
+		} else {
+			err = fn(name, info)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repo) readFile(name string) ([]byte, error) {
+	f, err := r.fs.Open(path.Clean(name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+func (r *Repo) writeFile(name string, content []byte) error {
+	f, err := r.fs.Create(path.Clean(name))
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// exists - whether name (relative to the repository root) exists
+func (r *Repo) exists(name string) bool {
+	_, err := r.fs.Stat(path.Clean(name))
+	return err == nil
+}
+
+// isDir - whether name (relative to the repository root) is a directory
+func (r *Repo) isDir(name string) (bool, error) {
+	info, err := r.fs.Stat(path.Clean(name))
+	if err != nil {
+		return false, fmt.Errorf("%s: %s", name, err)
+	}
+	return info.IsDir(), nil
+}
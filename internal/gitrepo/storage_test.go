@@ -0,0 +1,107 @@
+package gitrepo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestMemoryStorage(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRemote(t, dir, map[string]string{
+		"chart/Chart.yaml":                "name: web\nversion: 0.1.0\n",
+		"chart/.helmignore":               "templates/broken.yaml\n",
+		"chart/values.yaml":               "image:\n  repository: nginx\n  tag: 1.15.0\n",
+		"chart/templates/deployment.yaml": deploymentYaml("web", "{{ .Values.image.repository }}:{{ .Values.image.tag }}"),
+		"chart/templates/broken.yaml":     "{{ .Missing",
+		"base/deployment.yaml":            deploymentYaml("api", "api:1.0.0"),
+		"base/kustomization.yaml":         "resources:\n- deployment.yaml\n",
+		"overlay/kustomization.yaml":      "bases:\n- ../base\nnamePrefix: prod-\n",
+	})
+	repo := &Repo{
+		Name:      "test",
+		URL:       remote,
+		LocalPath: filepath.Join(dir, "checkout"),
+		Storage:   StorageMemory,
+		Branch:    plumbing.NewBranchReferenceName("master"),
+		Paths:     []RenderPath{{Path: "chart"}, {Path: "overlay"}},
+	}
+	repo.init()
+	if repo.repository == nil {
+		t.Fatalf("failed to clone %s", remote)
+	}
+	if _, err := os.Stat(repo.LocalPath); !os.IsNotExist(err) {
+		t.Errorf("memory storage must not write to %s", repo.LocalPath)
+	}
+
+	manifests, err := repo.getManifests()
+	if err != nil {
+		t.Fatalf("failed to render: %s", err)
+	}
+	sources := make(map[string][]string)
+	for _, m := range manifests {
+		gr, err := yamlToGenericResource(m.content)
+		if err != nil || gr == nil {
+			continue
+		}
+		sources[resourceName(t, gr)] = m.sources
+	}
+	want := map[string][]string{
+		"web":      {"chart/templates/deployment.yaml", "chart/values.yaml"},
+		"prod-api": {"overlay/kustomization.yaml"},
+	}
+	if !reflect.DeepEqual(sources, want) {
+		t.Errorf("unexpected resources: %v", sources)
+	}
+
+	commit, failed, err := repo.Apply([]*Change{{Image: "nginx:1.15.0", Repository: "nginx", OldTag: "1.15.0", NewTag: "1.16.0", Sources: want["web"]}})
+	if err != nil || len(failed) > 0 {
+		t.Fatalf("failed to apply: %v, %v", err, failed)
+	}
+	if head := runGit(t, dir, "--git-dir", remote, "rev-parse", "master"); strings.TrimSpace(head) != commit {
+		t.Errorf("pushed commit %s, expected %s", head, commit)
+	}
+	if content := runGit(t, dir, "--git-dir", remote, "show", "master:chart/values.yaml"); !strings.Contains(content, "tag: 1.16.0") {
+		t.Errorf("unexpected values: %s", content)
+	}
+}
+
+func TestShallowClone(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRemote(t, dir, map[string]string{"deployment.yaml": "image: nginx:1.0.0\n"})
+	work := filepath.Join(dir, "work")
+	writeTestFile(t, filepath.Join(work, "deployment.yaml"), "image: nginx:1.1.0\n")
+	runGit(t, work, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-am", "second")
+	runGit(t, work, "push", "-q", remote, "master")
+
+	repo := &Repo{
+		Name:      "test",
+		URL:       "file://" + remote,
+		LocalPath: filepath.Join(dir, "checkout"),
+		Shallow:   true,
+		Branch:    plumbing.NewBranchReferenceName("master"),
+	}
+	repo.init()
+	if repo.repository == nil {
+		t.Fatalf("failed to clone %s", remote)
+	}
+	if count := runGit(t, repo.LocalPath, "rev-list", "--count", "HEAD"); strings.TrimSpace(count) != "1" {
+		t.Errorf("expected only the latest commit, got %s", count)
+	}
+
+	_, failed, err := repo.Apply([]*Change{{Image: "nginx:1.1.0", Repository: "nginx", OldTag: "1.1.0", NewTag: "1.2.0", Sources: []string{"deployment.yaml"}}})
+	if err != nil || len(failed) > 0 {
+		t.Fatalf("failed to apply: %v, %v", err, failed)
+	}
+	if content := runGit(t, dir, "--git-dir", remote, "show", "master:deployment.yaml"); content != "image: nginx:1.2.0\n" {
+		t.Errorf("unexpected content: %s", content)
+	}
+}
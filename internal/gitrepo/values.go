@@ -0,0 +1,135 @@
+package gitrepo
+
+import (
+	"strings"
+
+	"github.com/alwinius/bow/provider/helm"
+	"github.com/alwinius/bow/util/image"
+	"github.com/alwinius/bow/util/yamledit"
+
+	"github.com/ghodss/yaml"
+)
+
+// valuesConfig - bow section of a chart values file, same format as used by the helm provider
+type valuesConfig struct {
+	Bow struct {
+		Images []helm.ImageDetails `json:"images"`
+	} `json:"bow"`
+}
+
+// editValues - updates Helm values that are combined to ref in the templates. Images listed in
+// the bow.images section of the file are resolved through their repository/tag paths, otherwise
+// sibling "repository" and "tag" keys (with an optional "registry") are detected. Only the tag
+// is rewritten, or the whole image if the mapping has no tag path.
+func editValues(content []byte, ref *image.Reference, newTag string) ([]byte, bool) {
+	scalars := make(map[string]yamledit.Scalar)
+	for _, s := range yamledit.Scalars(content) {
+		if s.Doc == 0 {
+			scalars[s.PathString()] = s
+		}
+	}
+
+	replacements := make(map[string]yamledit.Replacement)
+
+	var cfg valuesConfig
+	if err := yaml.Unmarshal(content, &cfg); err == nil {
+		for _, details := range cfg.Bow.Images {
+			repository, ok := scalars[details.RepositoryPath]
+			if !ok {
+				continue
+			}
+			if details.TagPath == "" {
+				if sameImage(repository.Value, ref) {
+					replacements[details.RepositoryPath] = yamledit.Replacement{Scalar: repository, Value: withTag(repository.Value, newTag)}
+				}
+				continue
+			}
+			tag, ok := scalars[details.TagPath]
+			if ok && sameImage(repository.Value+":"+tag.Value, ref) {
+				replacements[details.TagPath] = yamledit.Replacement{Scalar: tag, Value: newTag}
+			}
+		}
+	}
+
+	for path, tag := range scalars {
+		if tag.Key() != "tag" {
+			continue
+		}
+		if _, ok := replacements[path]; ok {
+			continue
+		}
+		parent := strings.TrimSuffix(path, "tag")
+		repository, ok := scalars[parent+"repository"]
+		if !ok {
+			continue
+		}
+		name := repository.Value
+		if registry, ok := scalars[parent+"registry"]; ok && registry.Value != "" {
+			name = strings.TrimSuffix(registry.Value, "/") + "/" + name
+		}
+		if sameImage(name+":"+tag.Value, ref) {
+			replacements[path] = yamledit.Replacement{Scalar: tag, Value: newTag}
+		}
+	}
+
+	if len(replacements) == 0 {
+		return content, false
+	}
+	var list []yamledit.Replacement
+	for _, r := range replacements {
+		list = append(list, r)
+	}
+	return yamledit.Replace(content, list...), true
+}
+
+// editLayeredValues - updates the tag of ref in values files that are merged in the given order,
+// like the chart defaults followed by -f overlays. Repository and tag may be set in different
+// files, the tag is rewritten in the last file setting it. Returns the changed files only.
+func editLayeredValues(order []string, contents map[string][]byte, ref *image.Reference, newTag string) map[string][]byte {
+	type layered struct {
+		file   string
+		scalar yamledit.Scalar
+	}
+	merged := make(map[string]layered)
+	for _, name := range order {
+		for _, s := range yamledit.Scalars(contents[name]) {
+			if s.Doc == 0 {
+				merged[s.PathString()] = layered{file: name, scalar: s}
+			}
+		}
+	}
+
+	replacements := make(map[string][]yamledit.Replacement)
+	for path, tag := range merged {
+		if tag.scalar.Key() != "tag" {
+			continue
+		}
+		parent := strings.TrimSuffix(path, "tag")
+		repository, ok := merged[parent+"repository"]
+		if !ok {
+			continue
+		}
+		name := repository.scalar.Value
+		if registry, ok := merged[parent+"registry"]; ok && registry.scalar.Value != "" {
+			name = strings.TrimSuffix(registry.scalar.Value, "/") + "/" + name
+		}
+		if sameImage(name+":"+tag.scalar.Value, ref) {
+			replacements[tag.file] = append(replacements[tag.file], yamledit.Replacement{Scalar: tag.scalar, Value: newTag})
+		}
+	}
+
+	changed := make(map[string][]byte)
+	for name, list := range replacements {
+		changed[name] = yamledit.Replace(contents[name], list...)
+	}
+	return changed
+}
+
+// sameImage - checks whether img has the same repository and tag as ref
+func sameImage(img string, ref *image.Reference) bool {
+	current, err := image.Parse(img)
+	if err != nil {
+		return false
+	}
+	return current.Repository() == ref.Repository() && current.Tag() == ref.Tag()
+}
@@ -0,0 +1,118 @@
+package gitrepo
+
+import (
+	"testing"
+
+	"github.com/alwinius/bow/util/image"
+)
+
+func TestEditValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		image   string
+		values  string
+		want    string
+		changed bool
+	}{
+		{
+			name:  "sibling keys",
+			image: "nginx:1.15.0",
+			values: `replicaCount: 1
+image:
+  repository: nginx
+  tag: 1.15.0 # app version
+  pullPolicy: IfNotPresent
+`,
+			want: `replicaCount: 1
+image:
+  repository: nginx
+  tag: 1.16.0 # app version
+  pullPolicy: IfNotPresent
+`,
+			changed: true,
+		},
+		{
+			name:  "registry key and numeric tag",
+			image: "quay.io/team/app:1.10",
+			values: `app:
+  image:
+    registry: quay.io
+    repository: team/app
+    tag: "1.10"
+`,
+			want: `app:
+  image:
+    registry: quay.io
+    repository: team/app
+    tag: "1.16.0"
+`,
+			changed: true,
+		},
+		{
+			name:  "other repository",
+			image: "nginx:1.15.0",
+			values: `image:
+  repository: mynginx
+  tag: 1.15.0
+`,
+			want: `image:
+  repository: mynginx
+  tag: 1.15.0
+`,
+		},
+		{
+			name:  "explicit mapping",
+			image: "redis:5.0.0",
+			values: `cache:
+  name: redis
+  version: 5.0.0
+bow:
+  policy: minor
+  images:
+    - repository: cache.name
+      tag: cache.version
+`,
+			want: `cache:
+  name: redis
+  version: 1.16.0
+bow:
+  policy: minor
+  images:
+    - repository: cache.name
+      tag: cache.version
+`,
+			changed: true,
+		},
+		{
+			name:  "explicit mapping without tag",
+			image: "redis:5.0.0",
+			values: `cacheImage: redis:5.0.0
+bow:
+  images:
+    - repository: cacheImage
+`,
+			want: `cacheImage: redis:1.16.0
+bow:
+  images:
+    - repository: cacheImage
+`,
+			changed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := image.Parse(tt.image)
+			if err != nil {
+				t.Fatalf("failed to parse image: %s", err)
+			}
+			got, changed := editValues([]byte(tt.values), ref, "1.16.0")
+			if changed != tt.changed {
+				t.Errorf("changed = %v, want %v", changed, tt.changed)
+			}
+			if string(got) != tt.want {
+				t.Errorf("unexpected result:\n%s", got)
+			}
+		})
+	}
+}
@@ -1,48 +1,130 @@
 package gitrepo
 
 import (
+	"github.com/alwinius/bow/internal/k8s"
 	"github.com/alwinius/bow/internal/workgroup"
+	"github.com/alwinius/bow/types"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/cache"
+	"reflect"
 	"regexp"
+	"strings"
 	"time"
 )
 
-func WatchRepo(g *workgroup.Group, repo Repo, log logrus.FieldLogger, rs ...cache.ResourceEventHandler) {
+// WatchRepo renders the repository whenever its HEAD changes, checking every Interval or when
+// Refresh is called, and registers it with g. Every resource is annotated with the repository
+// name and its source files so that updates can be routed back to it.
+func WatchRepo(g *workgroup.Group, repo *Repo, log logrus.FieldLogger, rs ...cache.ResourceEventHandler) {
 
-	watch(g, repo, log, rs...)
+	watch(g, repo, log.WithField("repo", repo.Name), rs...)
 }
 
-func watch(g *workgroup.Group, repo Repo, log logrus.FieldLogger, rs ...cache.ResourceEventHandler) {
+func watch(g *workgroup.Group, repo *Repo, log logrus.FieldLogger, rs ...cache.ResourceEventHandler) {
+	interval := repo.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
 
-	g.Add(func(stop <-chan struct{}) { // adding multiple times here doesnt matter because it will overwrite existing
+	g.Add(func(stop <-chan struct{}) {
 		log.Println("started")
 		defer log.Println("stopped")
+
+		var rendered plumbing.Hash
+		current := make(map[string]runtime.Object)
 		for {
-			finalManifests := repo.getManifests()
-
-			var properResources []runtime.Object
-			for _, m := range finalManifests {
-				if gr, err := yamlToGenericResource(m.Content); err == nil && gr != nil {
-					properResources = append(properResources, gr)
-				} else if err != nil {
-					logrus.Debug(err)
+			repo.init()
+			head, err := repo.head()
+			switch {
+			case err != nil:
+				log.WithError(err).Error("failed to get HEAD of repository")
+			case head == rendered:
+				log.Debug("HEAD unchanged, skipping render")
+			default:
+				resources, err := renderResources(repo)
+				current = notify(current, resources, err == nil, rs...)
+				if err == nil {
+					rendered = head
 				}
 			}
 
-			for _, r := range properResources {
-				for _, reh := range rs {
-					reh.OnAdd(r)
-				}
+			select {
+			case <-stop:
+				return
+			case <-repo.refreshed():
+				log.Debug("refresh requested")
+			case <-time.After(interval):
 			}
-			time.Sleep(time.Second * 30)
-
 		}
 	})
 }
 
+// renderResources - renders the repository and returns the supported resources annotated with their source
+func renderResources(repo *Repo) ([]runtime.Object, error) {
+	manifests, err := repo.getManifests()
+
+	var properResources []runtime.Object
+	for _, m := range manifests {
+		if gr, err := yamlToGenericResource(m.content); err == nil && gr != nil {
+			setSource(gr, repo.Name, m.app, m.sources)
+			setDefaultNamespace(gr, m.namespace)
+			properResources = append(properResources, gr)
+		} else if err != nil {
+			logrus.Debug(err)
+		}
+	}
+	return properResources, err
+}
+
+// notify - compares rendered resources with the previous render and sends add, update and
+// delete events accordingly. Deletes are only sent for complete renders, so that a path
+// failing to render does not remove its resources. Returns the resources now known.
+func notify(previous map[string]runtime.Object, resources []runtime.Object, complete bool, rs ...cache.ResourceEventHandler) map[string]runtime.Object {
+	next := make(map[string]runtime.Object)
+	for _, obj := range resources {
+		gr, err := k8s.NewGenericResource(obj)
+		if err != nil {
+			// not tracked by the cache, added every time
+			for _, reh := range rs {
+				reh.OnAdd(obj)
+			}
+			continue
+		}
+		id := gr.GetIdentifier()
+		next[id] = obj
+
+		old, ok := previous[id]
+		switch {
+		case !ok:
+			for _, reh := range rs {
+				reh.OnAdd(obj)
+			}
+		case !reflect.DeepEqual(old, obj):
+			for _, reh := range rs {
+				reh.OnUpdate(old, obj)
+			}
+		}
+	}
+
+	for id, old := range previous {
+		if _, ok := next[id]; ok {
+			continue
+		}
+		if !complete {
+			next[id] = old
+			continue
+		}
+		for _, reh := range rs {
+			reh.OnDelete(old)
+		}
+	}
+	return next
+}
+
 type buffer struct {
 	ev chan interface{}
 	logrus.StdLogger
@@ -131,3 +213,32 @@ func yamlToGenericResource(r string) (runtime.Object, error) {
 	}
 
 }
+
+// setDefaultNamespace - sets namespace of obj unless it has one already
+func setDefaultNamespace(obj runtime.Object, namespace string) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil || namespace == "" || accessor.GetNamespace() != "" {
+		return
+	}
+	accessor.SetNamespace(namespace)
+}
+
+// setSource - annotates rendered object with the name of the repository, the app path and the
+// files it came from
+func setSource(obj runtime.Object, name string, app string, sources []string) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	annotations := accessor.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[types.BowSourceRepoAnnotation] = name
+	if app == "" {
+		app = "."
+	}
+	annotations[types.BowSourceAppAnnotation] = app
+	annotations[types.BowSourceFilesAnnotation] = strings.Join(sources, ",")
+	accessor.SetAnnotations(annotations)
+}
@@ -0,0 +1,111 @@
+package gitrepo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alwinius/bow/internal/workgroup"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// recordingHandler - sends events as "<event> <resource name>" to a channel
+type recordingHandler struct {
+	t      *testing.T
+	events chan string
+}
+
+func (h *recordingHandler) OnAdd(obj interface{}) {
+	h.events <- "add " + resourceName(h.t, obj.(runtime.Object))
+}
+
+func (h *recordingHandler) OnUpdate(oldObj, newObj interface{}) {
+	h.events <- "update " + resourceName(h.t, newObj.(runtime.Object))
+}
+
+func (h *recordingHandler) OnDelete(obj interface{}) {
+	h.events <- "delete " + resourceName(h.t, obj.(runtime.Object))
+}
+
+func TestWatchRefresh(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRemote(t, dir, map[string]string{
+		"app/deployment.yaml": deploymentYaml("web", "nginx:1.0.0"),
+	})
+	repo := newTestRepo(t, dir, remote)
+	repo.Interval = time.Hour
+
+	h := &recordingHandler{t: t, events: make(chan string, 10)}
+
+	expect := func(want string) {
+		select {
+		case event := <-h.events:
+			if event != want {
+				t.Errorf("unexpected event: %s, want %s", event, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("missing event: %s", want)
+		}
+	}
+
+	work := filepath.Join(dir, "work")
+	commit := func(msg string) {
+		runGit(t, work, "add", "-A")
+		runGit(t, work, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", msg)
+		runGit(t, work, "push", "-q", remote, "master")
+		repo.Refresh()
+	}
+
+	var g workgroup.Group
+	WatchRepo(&g, repo, logrus.StandardLogger(), h)
+	g.Add(func(stop <-chan struct{}) {
+		expect("add web")
+
+		// HEAD unchanged, nothing is rendered
+		repo.Refresh()
+		select {
+		case event := <-h.events:
+			t.Errorf("unexpected event: %s", event)
+		case <-time.After(500 * time.Millisecond):
+		}
+
+		writeTestFile(t, filepath.Join(work, "app", "worker.yaml"), deploymentYaml("worker", "worker:1.0.0"))
+		commit("add worker")
+		expect("add worker")
+
+		writeTestFile(t, filepath.Join(work, "app", "deployment.yaml"), deploymentYaml("web", "nginx:1.1.0"))
+		os.Remove(filepath.Join(work, "app", "worker.yaml"))
+		commit("update web, remove worker")
+		expect("update web")
+		expect("delete worker")
+	})
+	g.Run()
+}
+
+func TestNotifyIncompleteRender(t *testing.T) {
+	web, _ := yamlToGenericResource(deploymentYaml("web", "nginx:1.0.0"))
+	worker, _ := yamlToGenericResource(deploymentYaml("worker", "worker:1.0.0"))
+
+	h := &recordingHandler{t: t, events: make(chan string, 10)}
+	current := notify(nil, []runtime.Object{web, worker}, true, h)
+	if len(current) != 2 || len(h.events) != 2 {
+		t.Fatalf("expected two resources and events, got %d and %d", len(current), len(h.events))
+	}
+	<-h.events
+	<-h.events
+
+	// worker is missing because its path failed to render, it must not be deleted
+	current = notify(current, []runtime.Object{web}, false, h)
+	if len(current) != 2 || len(h.events) != 0 {
+		t.Errorf("expected resources to be kept without events, got %d resources and %d events", len(current), len(h.events))
+	}
+
+	current = notify(current, []runtime.Object{web}, true, h)
+	if len(current) != 1 || <-h.events != "delete worker" {
+		t.Errorf("expected worker to be deleted")
+	}
+}
@@ -3,6 +3,8 @@ package k8s
 import (
 	"testing"
 
+	"github.com/alwinius/bow/types"
+	"github.com/sirupsen/logrus"
 	apps_v1 "k8s.io/api/apps/v1"
 	core_v1 "k8s.io/api/core/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -51,3 +53,76 @@ func TestAddGet(t *testing.T) {
 		t.Errorf("cached entry got modified: %s", stored2.Containers()[0].Image)
 	}
 }
+
+func TestSameResourceFromTwoRepos(t *testing.T) {
+	deployment := func(repo, app, image string) *apps_v1.Deployment {
+		return &apps_v1.Deployment{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Name:      "web",
+				Namespace: "default",
+				Annotations: map[string]string{
+					types.BowSourceRepoAnnotation: repo,
+					types.BowSourceAppAnnotation:  app,
+				},
+			},
+			Spec: apps_v1.DeploymentSpec{
+				Template: core_v1.PodTemplateSpec{
+					Spec: core_v1.PodSpec{
+						Containers: []core_v1.Container{{Image: image}}},
+				},
+			},
+		}
+	}
+	staging := deployment("staging", "apps/web", "nginx:1.2.0")
+	prod := deployment("prod", "apps/web", "nginx:1.1.0")
+
+	tr := &Translator{FieldLogger: logrus.New()}
+	tr.OnAdd(staging)
+	tr.OnAdd(prod)
+
+	values := tr.Values()
+	if len(values) != 2 {
+		t.Fatalf("expected 2 cached resources, got %d", len(values))
+	}
+	identifiers := map[string]string{}
+	for _, gr := range values {
+		identifiers[gr.Identifier] = gr.GetImages()[0]
+	}
+	if identifiers["staging/apps/web/deployment/default/web"] != "nginx:1.2.0" || identifiers["prod/apps/web/deployment/default/web"] != "nginx:1.1.0" {
+		t.Errorf("unexpected resources: %v", identifiers)
+	}
+
+	// identifiers without repository and app path only match a single resource
+	if gr, err := FindResource(values, "prod/apps/web/deployment/default/web"); err != nil || gr == nil || gr.GetImages()[0] != "nginx:1.1.0" {
+		t.Errorf("expected prod deployment, got %v, %v", gr, err)
+	}
+	if _, err := FindResource(values, "deployment/default/web"); err != ErrAmbiguousIdentifier {
+		t.Errorf("expected ambiguous identifier, got %v", err)
+	}
+	if gr, err := FindResource(values, "deployment/default/missing"); gr != nil || err != nil {
+		t.Errorf("expected no resource, got %v, %v", gr, err)
+	}
+
+	// deleting the staging deployment keeps the prod one
+	tr.OnDelete(staging)
+
+	values = tr.Values()
+	if len(values) != 1 || values[0].Identifier != "prod/apps/web/deployment/default/web" {
+		t.Errorf("expected only the prod deployment to remain, got %v", values)
+	}
+	if gr, err := FindResource(values, "deployment/default/web"); err != nil || gr == nil || gr.Identifier != "prod/apps/web/deployment/default/web" {
+		t.Errorf("expected old identifier to match the prod deployment, got %v, %v", gr, err)
+	}
+}
+
+func TestIdentifierWithoutSource(t *testing.T) {
+	gr, err := NewGenericResource(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "web", Namespace: "default"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create generic resource: %s", err)
+	}
+	if gr.Identifier != "deployment/default/web" {
+		t.Errorf("unexpected identifier: %s", gr.Identifier)
+	}
+}
@@ -1,10 +1,12 @@
 package k8s
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
 
+	"github.com/alwinius/bow/types"
 	apps_v1 "k8s.io/api/apps/v1"
 	v1beta1 "k8s.io/api/batch/v1beta1"
 	core_v1 "k8s.io/api/core/v1"
@@ -86,8 +88,32 @@ func (r *GenericResource) DeepCopy() *GenericResource {
 	return gr
 }
 
-// GetIdentifier returns resource identifier
+// ErrAmbiguousIdentifier - identifier without repository and app path matches several resources
+var ErrAmbiguousIdentifier = errors.New("identifier matches resources of several repositories or apps, prefix it with the repository and app path")
+
+// GetIdentifier returns resource identifier, ie: deployment/default/web. Resources rendered
+// by the git watcher are prefixed with their repository and app path (ie:
+// staging/apps/web/deployment/default/web), so the same chart rendered from several
+// repositories, branches or paths yields distinct resources.
 func (r *GenericResource) GetIdentifier() string {
+	identifier := r.GetKindIdentifier()
+	if identifier == "" {
+		return ""
+	}
+
+	annotations := r.GetAnnotations()
+	repo, ok := annotations[types.BowSourceRepoAnnotation]
+	if !ok {
+		return identifier
+	}
+	if app := annotations[types.BowSourceAppAnnotation]; app != "" && app != "." {
+		return repo + "/" + strings.Trim(app, "/") + "/" + identifier
+	}
+	return repo + "/" + identifier
+}
+
+// GetKindIdentifier returns the identifier without repository and app path, ie: deployment/default/web
+func (r *GenericResource) GetKindIdentifier() string {
 	switch obj := r.obj.(type) {
 	case *apps_v1.Deployment:
 		return getDeploymentIdentifier(obj)
@@ -101,6 +127,29 @@ func (r *GenericResource) GetIdentifier() string {
 	return ""
 }
 
+// FindResource - resource with identifier. Identifiers without repository and app path, as used
+// before resources rendered from repositories were prefixed, match when exactly one resource has
+// that kind, namespace and name, ErrAmbiguousIdentifier is returned when several do. Returns nil
+// when no resource matches.
+func FindResource(resources []*GenericResource, identifier string) (*GenericResource, error) {
+	var matches []*GenericResource
+	for _, r := range resources {
+		if r.Identifier == identifier {
+			return r, nil
+		}
+		if r.GetKindIdentifier() == identifier {
+			matches = append(matches, r)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, nil
+	case 1:
+		return matches[0], nil
+	}
+	return nil, ErrAmbiguousIdentifier
+}
+
 // GetName returns resource name
 func (r *GenericResource) GetName() string {
 	switch obj := r.obj.(type) {
@@ -260,36 +309,50 @@ func (r *GenericResource) GetImagePullSecrets() (secrets []string) {
 	return
 }
 
-// GetImages - returns images used by this resource
+// GetImages - returns images used by this resource, including init containers
 func (r *GenericResource) GetImages() (images []string) {
+	return getContainerImages(r.AllContainers())
+}
+
+// Containers - returns containers managed by this resource
+func (r *GenericResource) Containers() (containers []core_v1.Container) {
 	switch obj := r.obj.(type) {
 	case *apps_v1.Deployment:
-		return getContainerImages(obj.Spec.Template.Spec.Containers)
+		return obj.Spec.Template.Spec.Containers
 	case *apps_v1.StatefulSet:
-		return getContainerImages(obj.Spec.Template.Spec.Containers)
+		return obj.Spec.Template.Spec.Containers
 	case *apps_v1.DaemonSet:
-		return getContainerImages(obj.Spec.Template.Spec.Containers)
+		return obj.Spec.Template.Spec.Containers
 	case *v1beta1.CronJob:
-		return getContainerImages(obj.Spec.JobTemplate.Spec.Template.Spec.Containers)
+		return obj.Spec.JobTemplate.Spec.Template.Spec.Containers
 	}
 	return
 }
 
-// Containers - returns containers managed by this resource
-func (r *GenericResource) Containers() (containers []core_v1.Container) {
+// InitContainers - returns init containers managed by this resource
+func (r *GenericResource) InitContainers() (containers []core_v1.Container) {
 	switch obj := r.obj.(type) {
 	case *apps_v1.Deployment:
-		return obj.Spec.Template.Spec.Containers
+		return obj.Spec.Template.Spec.InitContainers
 	case *apps_v1.StatefulSet:
-		return obj.Spec.Template.Spec.Containers
+		return obj.Spec.Template.Spec.InitContainers
 	case *apps_v1.DaemonSet:
-		return obj.Spec.Template.Spec.Containers
+		return obj.Spec.Template.Spec.InitContainers
 	case *v1beta1.CronJob:
-		return obj.Spec.JobTemplate.Spec.Template.Spec.Containers
+		return obj.Spec.JobTemplate.Spec.Template.Spec.InitContainers
 	}
 	return
 }
 
+// AllContainers - returns containers followed by init containers in a new slice, the pod spec
+// slices are shared with the cached resource and must not be appended to
+func (r *GenericResource) AllContainers() []core_v1.Container {
+	containers, initContainers := r.Containers(), r.InitContainers()
+	all := make([]core_v1.Container, 0, len(containers)+len(initContainers))
+	all = append(all, containers...)
+	return append(all, initContainers...)
+}
+
 // UpdateContainer - updates container image
 func (r *GenericResource) UpdateContainer(index int, image string) {
 	//switch obj := r.obj.(type) {
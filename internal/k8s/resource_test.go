@@ -185,3 +185,75 @@ func TestDaemonsetlSetMultipleContainers(t *testing.T) {
 		t.Errorf("unexpected image: %s", updated.Spec.Template.Spec.Containers[0].Image)
 	}
 }
+
+func TestInitContainers(t *testing.T) {
+	d := &apps_v1.Deployment{
+		meta_v1.TypeMeta{},
+		meta_v1.ObjectMeta{
+			Name:        "dep-1",
+			Namespace:   "xxxx",
+			Annotations: map[string]string{},
+			Labels:      map[string]string{},
+		},
+		apps_v1.DeploymentSpec{
+			Template: core_v1.PodTemplateSpec{
+				Spec: core_v1.PodSpec{
+					InitContainers: []core_v1.Container{
+						{
+							Name:  "migrate",
+							Image: "gcr.io/v2-namespace/migrate:1.0.0",
+						},
+					},
+					Containers: []core_v1.Container{
+						{
+							Name:  "app",
+							Image: "gcr.io/v2-namespace/hello-world:1.1.1",
+						},
+					},
+				},
+			},
+		},
+		apps_v1.DeploymentStatus{},
+	}
+
+	gr, err := NewGenericResource(d)
+	if err != nil {
+		t.Fatalf("failed to create generic resource: %s", err)
+	}
+
+	if len(gr.InitContainers()) != 1 || gr.InitContainers()[0].Name != "migrate" {
+		t.Errorf("unexpected init containers: %v", gr.InitContainers())
+	}
+	images := gr.GetImages()
+	if len(images) != 2 || images[0] != "gcr.io/v2-namespace/hello-world:1.1.1" || images[1] != "gcr.io/v2-namespace/migrate:1.0.0" {
+		t.Errorf("unexpected images: %v", images)
+	}
+}
+
+func TestAllContainersDoesNotShareSpec(t *testing.T) {
+	containers := make([]core_v1.Container, 1, 4)
+	containers[0] = core_v1.Container{Name: "app", Image: "gcr.io/v2-namespace/hello-world:1.1.1"}
+	d := &apps_v1.Deployment{
+		Spec: apps_v1.DeploymentSpec{
+			Template: core_v1.PodTemplateSpec{
+				Spec: core_v1.PodSpec{
+					InitContainers: []core_v1.Container{{Name: "migrate", Image: "gcr.io/v2-namespace/migrate:1.0.0"}},
+					Containers:     containers,
+				},
+			},
+		},
+	}
+	gr, err := NewGenericResource(d)
+	if err != nil {
+		t.Fatalf("failed to create generic resource: %s", err)
+	}
+
+	all := gr.AllContainers()
+	if len(all) != 2 || all[1].Name != "migrate" {
+		t.Fatalf("unexpected containers: %v", all)
+	}
+	all[0].Image = "changed"
+	if containers[:2][1].Name != "" || containers[0].Image != "gcr.io/v2-namespace/hello-world:1.1.1" {
+		t.Errorf("pod spec containers were modified: %v", containers[:2])
+	}
+}
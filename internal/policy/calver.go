@@ -0,0 +1,112 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// calverTokens - calver.org format tokens, longer tokens first
+var calverTokens = []struct {
+	token   string
+	pattern string
+}{
+	{"YYYY", `\d{4}`},
+	{"MAJOR", `\d+`},
+	{"MINOR", `\d+`},
+	{"MICRO", `\d+`},
+	{"YY", `\d{1,3}`},
+	{"0Y", `\d{2,3}`},
+	{"MM", `[1-9]|1[0-2]`},
+	{"0M", `0[1-9]|1[0-2]`},
+	{"WW", `[1-9]|[1-4][0-9]|5[0-3]`},
+	{"0W", `0[1-9]|[1-4][0-9]|5[0-3]`},
+	{"DD", `[1-9]|[12][0-9]|3[01]`},
+	{"0D", `0[1-9]|[12][0-9]|3[01]`},
+}
+
+var calverNumbers = regexp.MustCompile(`\d+`)
+
+// CalverPolicy - calendar versioning, ie: calver:YYYY.MM.MICRO matches 2024.10.2. Tags are
+// compared number by number, calver without a format takes any dot separated numbers.
+type CalverPolicy struct {
+	policy string
+	regexp *regexp.Regexp
+}
+
+// NewCalverPolicy - parses calver or calver:<format>, formats consist of calver.org tokens
+// (YYYY, YY, 0Y, MM, 0M, WW, 0W, DD, 0D, MAJOR, MINOR, MICRO) separated by ., - or _
+func NewCalverPolicy(policy string) (*CalverPolicy, error) {
+	if policy == "calver" {
+		return &CalverPolicy{
+			policy: policy,
+			regexp: regexp.MustCompile(`^\d+(\.\d+)*$`),
+		}, nil
+	}
+	if !strings.HasPrefix(policy, "calver:") {
+		return nil, fmt.Errorf("invalid calver policy: %s", policy)
+	}
+
+	format := strings.TrimPrefix(policy, "calver:")
+	if format == "" {
+		return nil, fmt.Errorf("invalid calver policy: %s, format cannot be empty", policy)
+	}
+
+	pattern := "^"
+	for rest := format; rest != ""; {
+		switch rest[0] {
+		case '.', '-', '_':
+			pattern += regexp.QuoteMeta(rest[:1])
+			rest = rest[1:]
+			continue
+		}
+
+		found := false
+		for _, t := range calverTokens {
+			if strings.HasPrefix(rest, t.token) {
+				pattern += "(" + t.pattern + ")"
+				rest = rest[len(t.token):]
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid calver policy: %s, unknown format at %s", policy, rest)
+		}
+	}
+
+	return &CalverPolicy{
+		policy: policy,
+		regexp: regexp.MustCompile(pattern + "$"),
+	}, nil
+}
+
+func (p *CalverPolicy) ShouldUpdate(current, new string) (bool, error) {
+	if !p.regexp.MatchString(new) {
+		return false, nil
+	}
+	return shouldUpdateOrdered(p.key, current, new)
+}
+
+// Compare - orders tags number by number
+func (p *CalverPolicy) Compare(a, b string) int {
+	return compareTags(p.key, a, b)
+}
+
+func (p *CalverPolicy) key(tag string) ([]sortValue, error) {
+	if !p.regexp.MatchString(tag) {
+		return nil, fmt.Errorf("tag %s does not match %s", tag, p.policy)
+	}
+	var key []sortValue
+	for _, n := range calverNumbers.FindAllString(tag, -1) {
+		v, err := newSortValue(OrderNumerical, n)
+		if err != nil {
+			return nil, err
+		}
+		key = append(key, v)
+	}
+	return key, nil
+}
+
+func (p *CalverPolicy) Name() string     { return p.policy }
+func (p *CalverPolicy) Type() PolicyType { return PolicyTypeCalver }
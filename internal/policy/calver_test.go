@@ -0,0 +1,99 @@
+package policy
+
+import "testing"
+
+func TestNewCalverPolicy(t *testing.T) {
+	tests := []struct {
+		policy  string
+		wantErr bool
+	}{
+		{policy: "calver"},
+		{policy: "calver:YYYY.MM.MICRO"},
+		{policy: "calver:YY.0M.0D_MICRO"},
+		{policy: "calver:", wantErr: true},
+		{policy: "calver:YYYY.MONTH", wantErr: true},
+		{policy: "calver:v2024", wantErr: true},
+		{policy: "calvers", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			_, err := NewCalverPolicy(tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewCalverPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCalverPolicy_ShouldUpdate(t *testing.T) {
+	type args struct {
+		current string
+		new     string
+	}
+	tests := []struct {
+		name   string
+		policy string
+		args   args
+		want   bool
+	}{
+		{
+			name:   "newer micro",
+			policy: "calver:YYYY.MM.MICRO",
+			args:   args{current: "2024.10.2", new: "2024.10.10"},
+			want:   true,
+		},
+		{
+			name:   "newer month",
+			policy: "calver:YYYY.MM.MICRO",
+			args:   args{current: "2024.9.5", new: "2024.10.0"},
+			want:   true,
+		},
+		{
+			name:   "older",
+			policy: "calver:YYYY.MM.MICRO",
+			args:   args{current: "2024.10.2", new: "2024.9.8"},
+			want:   false,
+		},
+		{
+			name:   "invalid month",
+			policy: "calver:YYYY.MM.MICRO",
+			args:   args{current: "2024.10.2", new: "2024.13.0"},
+			want:   false,
+		},
+		{
+			name:   "any numbers",
+			policy: "calver",
+			args:   args{current: "2024.10.2", new: "2024.10.2.1"},
+			want:   true,
+		},
+		{
+			name:   "not calver",
+			policy: "calver",
+			args:   args{current: "2024.10.2", new: "2024.10.3-rc1"},
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewCalverPolicy(tt.policy)
+			if err != nil {
+				t.Fatalf("failed to parse policy: %s", err)
+			}
+			got, err := p.ShouldUpdate(tt.args.current, tt.args.new)
+			if err != nil {
+				t.Errorf("CalverPolicy.ShouldUpdate() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("CalverPolicy.ShouldUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalverPolicy_Compare(t *testing.T) {
+	p, _ := NewCalverPolicy("calver:YYYY.MM.MICRO")
+	if p.Compare("2024.10.10", "2024.10.9") != 1 || p.Compare("2024.1.0", "2024.10.0") != -1 || p.Compare("latest", "2024.1.0") != -1 {
+		t.Errorf("unexpected calver order")
+	}
+}
@@ -0,0 +1,236 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/alwinius/bow/types"
+	"github.com/alwinius/bow/util/timeutil"
+	"github.com/alwinius/bow/util/version"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// celVersionFields - semver fields of currentVersion and newVersion with their types
+var celVersionFields = map[string]*exprpb.Type{
+	"major":      decls.Int,
+	"minor":      decls.Int,
+	"patch":      decls.Int,
+	"prerelease": decls.String,
+	"variant":    decls.String,
+	"metadata":   decls.String,
+	"valid":      decls.Bool,
+}
+
+// celDeclarations - variables available to cel policies:
+//
+//	current, new                - current and new tags
+//	currentVersion, newVersion  - semver fields of the tags (major, minor, patch, prerelease,
+//	                              variant, metadata), valid is false for non semver tags
+//	newer                       - new is a higher semver version than current
+//	labels                      - labels of the new image, empty when not available
+//	created                     - creation time of the new image, unset when not available
+//	now                         - time of evaluation
+//
+// Version fields are declared one by one (currentVersion.major, ...), so expressions using
+// other fields are rejected when they are compiled.
+func celDeclarations() cel.EnvOption {
+	idents := []*exprpb.Decl{
+		decls.NewIdent("current", decls.String, nil),
+		decls.NewIdent("new", decls.String, nil),
+		decls.NewIdent("newer", decls.Bool, nil),
+		decls.NewIdent("labels", decls.NewMapType(decls.String, decls.String), nil),
+		decls.NewIdent("created", decls.Timestamp, nil),
+		decls.NewIdent("now", decls.Timestamp, nil),
+	}
+	for _, v := range []string{"currentVersion", "newVersion"} {
+		for field, t := range celVersionFields {
+			idents = append(idents, decls.NewIdent(v+"."+field, t, nil))
+		}
+	}
+	return cel.Declarations(idents...)
+}
+
+// celPolicies - compiled policies and compile errors by policy, policies are parsed again
+// for every event and tracked image list, compiling them is comparatively expensive
+var celPolicies = struct {
+	sync.Mutex
+	env      *cel.Env
+	compiled map[string]*celCompiled
+}{compiled: make(map[string]*celCompiled)}
+
+type celCompiled struct {
+	policy *CelPolicy
+	err    error
+}
+
+// CelPolicy - policy evaluating a CEL expression, ie:
+// cel:newer && newVersion.major == currentVersion.major && now.getHours() < 6
+type CelPolicy struct {
+	policy  string
+	program cel.Program
+	// references - variables used by the expression
+	references map[string]bool
+}
+
+// NewCelPolicy - compiles the expression of a cel:<expression> policy, the expression has
+// to return bool. Compiled policies are cached by policy.
+func NewCelPolicy(policy string) (*CelPolicy, error) {
+	celPolicies.Lock()
+	defer celPolicies.Unlock()
+
+	if c, ok := celPolicies.compiled[policy]; ok {
+		return c.policy, c.err
+	}
+	p, err := compileCelPolicy(policy)
+	celPolicies.compiled[policy] = &celCompiled{policy: p, err: err}
+	return p, err
+}
+
+func compileCelPolicy(policy string) (*CelPolicy, error) {
+	if !strings.HasPrefix(policy, "cel:") {
+		return nil, fmt.Errorf("invalid cel policy: %s", policy)
+	}
+
+	expr := strings.TrimSpace(strings.TrimPrefix(policy, "cel:"))
+	if expr == "" {
+		return nil, fmt.Errorf("invalid cel policy: %s, expression cannot be empty", policy)
+	}
+
+	if celPolicies.env == nil {
+		env, err := cel.NewEnv(celDeclarations())
+		if err != nil {
+			return nil, fmt.Errorf("invalid cel policy: %s, error: %s", policy, err)
+		}
+		celPolicies.env = env
+	}
+	env := celPolicies.env
+
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid cel policy: %s, error: %s", policy, issues.Err())
+	}
+	if !proto.Equal(ast.ResultType(), decls.Bool) && !proto.Equal(ast.ResultType(), decls.Dyn) {
+		return nil, fmt.Errorf("invalid cel policy: %s, expression returns %s instead of bool", policy, ast.ResultType())
+	}
+
+	checked, err := cel.AstToCheckedExpr(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cel policy: %s, error: %s", policy, err)
+	}
+	references := make(map[string]bool)
+	for _, ref := range checked.ReferenceMap {
+		if ref.Name != "" {
+			references[ref.Name] = true
+		}
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cel policy: %s, error: %s", policy, err)
+	}
+
+	return &CelPolicy{
+		policy:     policy,
+		program:    program,
+		references: references,
+	}, nil
+}
+
+// ShouldUpdate - evaluates the expression without image metadata
+func (p *CelPolicy) ShouldUpdate(current, new string) (bool, error) {
+	return p.ShouldUpdateWithMetadata(current, new, nil)
+}
+
+// ShouldUpdateWithMetadata - evaluates the expression, tags equal to current are never updated
+func (p *CelPolicy) ShouldUpdateWithMetadata(current, new string, metadata *types.ImageMetadata) (bool, error) {
+	if current == new {
+		return false, nil
+	}
+
+	currentVersion, currentErr := version.GetVersion(current)
+	newVersion, newErr := version.GetVersion(new)
+	newer := false
+	if currentErr == nil && newErr == nil {
+		cmp, err := version.Compare(currentVersion, newVersion)
+		if err != nil {
+			return false, err
+		}
+		newer = cmp < 0
+	}
+
+	now, err := ptypes.TimestampProto(timeutil.Now())
+	if err != nil {
+		return false, err
+	}
+	vars := map[string]interface{}{
+		"current": current,
+		"new":     new,
+		"newer":   newer,
+		"labels":  map[string]string{},
+		"now":     now,
+	}
+	for field, value := range celVersion(currentVersion, currentErr) {
+		vars["currentVersion."+field] = value
+	}
+	for field, value := range celVersion(newVersion, newErr) {
+		vars["newVersion."+field] = value
+	}
+	if metadata != nil {
+		if metadata.Labels != nil {
+			vars["labels"] = metadata.Labels
+		}
+		if !metadata.Created.IsZero() {
+			created, err := ptypes.TimestampProto(metadata.Created)
+			if err != nil {
+				return false, err
+			}
+			vars["created"] = created
+		}
+	}
+
+	out, _, err := p.program.Eval(vars)
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate %s: %s", p.policy, err)
+	}
+	update, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("failed to evaluate %s: expected bool, got %v", p.policy, out.Value())
+	}
+	return update, nil
+}
+
+// NeedsMetadata - expression uses labels or the creation time of the image
+func (p *CelPolicy) NeedsMetadata() bool {
+	return p.references["labels"] || p.references["created"]
+}
+
+func (p *CelPolicy) Name() string     { return p.policy }
+func (p *CelPolicy) Type() PolicyType { return PolicyTypeCel }
+
+func celVersion(v *types.Version, err error) map[string]interface{} {
+	if err != nil {
+		return map[string]interface{}{
+			"major":      int64(0),
+			"minor":      int64(0),
+			"patch":      int64(0),
+			"prerelease": "",
+			"variant":    "",
+			"metadata":   "",
+			"valid":      false,
+		}
+	}
+	return map[string]interface{}{
+		"major":      v.Major,
+		"minor":      v.Minor,
+		"patch":      v.Patch,
+		"prerelease": v.PreRelease,
+		"variant":    v.Variant,
+		"metadata":   v.Metadata,
+		"valid":      true,
+	}
+}
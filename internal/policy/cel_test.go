@@ -0,0 +1,195 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alwinius/bow/types"
+	"github.com/alwinius/bow/util/timeutil"
+)
+
+func TestNewCelPolicy(t *testing.T) {
+	tests := []struct {
+		policy  string
+		wantErr bool
+	}{
+		{policy: "cel:newer"},
+		{policy: `cel:new.endsWith("-hotfix") || newVersion.major == currentVersion.major`},
+		{policy: `cel: labels["org.example.qa"] == "passed"`},
+		{policy: "cel:", wantErr: true},
+		{policy: "cel:  ", wantErr: true},
+		{policy: "cel:new", wantErr: true},
+		{policy: "cel:tag == 1", wantErr: true},
+		{policy: "cels:newer", wantErr: true},
+		{policy: "cel:newVersion.build > 1", wantErr: true},
+		{policy: `cel:newVersion.major == "1"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			_, err := NewCelPolicy(tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewCelPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCelPolicy_ShouldUpdate(t *testing.T) {
+	// Wednesday
+	timeutil.Now = func() time.Time {
+		return time.Date(2024, time.October, 9, 14, 0, 0, 0, time.UTC)
+	}
+	defer func() { timeutil.Now = time.Now }()
+
+	type args struct {
+		current  string
+		new      string
+		metadata *types.ImageMetadata
+	}
+	tests := []struct {
+		name    string
+		policy  string
+		args    args
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:   "newer",
+			policy: "cel:newer",
+			args:   args{current: "1.4.2", new: "1.5.0"},
+			want:   true,
+		},
+		{
+			name:   "same tag",
+			policy: "cel:true",
+			args:   args{current: "1.4.2", new: "1.4.2"},
+			want:   false,
+		},
+		{
+			name:   "same major on weekdays",
+			policy: "cel:newer && newVersion.major == currentVersion.major && now.getDayOfWeek() >= 1 && now.getDayOfWeek() <= 5",
+			args:   args{current: "1.4.2", new: "1.5.0"},
+			want:   true,
+		},
+		{
+			name:   "other major",
+			policy: "cel:newer && newVersion.major == currentVersion.major",
+			args:   args{current: "1.4.2", new: "2.0.0"},
+			want:   false,
+		},
+		{
+			name:   "hotfix tag",
+			policy: `cel:new.endsWith("-hotfix") || newer`,
+			args:   args{current: "1.4.2", new: "feature-x-hotfix"},
+			want:   true,
+		},
+		{
+			name:   "non semver tags",
+			policy: "cel:newer || newVersion.valid",
+			args:   args{current: "latest", new: "feature-x"},
+			want:   false,
+		},
+		{
+			name:   "variant",
+			policy: `cel:newer && newVersion.variant == currentVersion.variant`,
+			args:   args{current: "1.4.2-alpine", new: "1.4.3-alpine"},
+			want:   true,
+		},
+		{
+			name:   "outside maintenance window",
+			policy: `cel:now.getHours() < 6`,
+			args:   args{current: "1.4.2", new: "1.5.0"},
+			want:   false,
+		},
+		{
+			name:   "label passed",
+			policy: `cel:newer && labels["org.example.qa"] == "passed"`,
+			args: args{current: "1.4.2", new: "1.5.0", metadata: &types.ImageMetadata{
+				Labels: map[string]string{"org.example.qa": "passed"},
+			}},
+			want: true,
+		},
+		{
+			name:   "missing label",
+			policy: `cel:!("org.example.qa" in labels) || labels["org.example.qa"] != "failed"`,
+			args:   args{current: "1.4.2", new: "1.5.0"},
+			want:   true,
+		},
+		{
+			name:    "required label without metadata",
+			policy:  `cel:labels["org.example.qa"] == "passed"`,
+			args:    args{current: "1.4.2", new: "1.5.0"},
+			wantErr: true,
+		},
+		{
+			name:   "built a day ago",
+			policy: `cel:int(now) - int(created) > 86400`,
+			args: args{current: "1.4.2", new: "1.5.0", metadata: &types.ImageMetadata{
+				Created: time.Date(2024, time.October, 7, 14, 0, 0, 0, time.UTC),
+			}},
+			want: true,
+		},
+		{
+			name:    "invalid pattern",
+			policy:  `cel:new.matches("[")`,
+			args:    args{current: "1.4.2", new: "1.5.0"},
+			wantErr: true,
+		},
+		{
+			name:   "berlin time",
+			policy: `cel:now.getHours("Europe/Berlin") == 16`,
+			args:   args{current: "1.4.2", new: "1.5.0"},
+			want:   true,
+		},
+		{
+			name:    "creation time unknown",
+			policy:  `cel:created < now`,
+			args:    args{current: "1.4.2", new: "1.5.0"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewCelPolicy(tt.policy)
+			if err != nil {
+				t.Fatalf("NewCelPolicy() error = %v", err)
+			}
+			got, err := p.ShouldUpdateWithMetadata(tt.args.current, tt.args.new, tt.args.metadata)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CelPolicy.ShouldUpdateWithMetadata() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("CelPolicy.ShouldUpdateWithMetadata() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCelPolicyCached(t *testing.T) {
+	first, err := NewCelPolicy("cel:newVersion.minor > currentVersion.minor")
+	if err != nil {
+		t.Fatalf("NewCelPolicy() error = %v", err)
+	}
+	second, err := NewCelPolicy("cel:newVersion.minor > currentVersion.minor")
+	if err != nil {
+		t.Fatalf("NewCelPolicy() error = %v", err)
+	}
+	if first != second {
+		t.Errorf("expected compiled policy to be reused")
+	}
+}
+
+func TestCelPolicy_NeedsMetadata(t *testing.T) {
+	for policy, want := range map[string]bool{
+		"cel:newer":                         false,
+		`cel:"qa" in labels`:                true,
+		`cel:created < now`:                 true,
+		`cel:new.endsWith("-hotfix")`:       false,
+		`cel:newer || labels["qa"] == "ok"`: true,
+	} {
+		if got := NeedsMetadata(GetPolicy(policy, &Options{})); got != want {
+			t.Errorf("NeedsMetadata(%s) = %v, want %v", policy, got, want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/Masterminds/semver"
+	"github.com/alwinius/bow/types"
+	"github.com/alwinius/bow/util/version"
+)
+
+// SemverConstraintPolicy - semver constraint based policy, ie: semver:~1.4 or semver:>=2.1 <3
+type SemverConstraintPolicy struct {
+	constraint  string // normalized constraint, without prefix
+	constraints *semver.Constraints
+}
+
+// NewSemverConstraintPolicy - parses policies such as semver:~1.4, semver:^2 or
+// semver:>=2.1 <3 || 4.x. Constraints can be separated by commas or spaces.
+func NewSemverConstraintPolicy(policy string) (*SemverConstraintPolicy, error) {
+	if !strings.HasPrefix(policy, "semver:") {
+		return nil, fmt.Errorf("invalid semver constraint policy: %s", policy)
+	}
+
+	constraint := normalizeConstraint(strings.TrimPrefix(policy, "semver:"))
+	if constraint == "" {
+		return nil, fmt.Errorf("invalid semver constraint policy: %s, constraint cannot be empty", policy)
+	}
+
+	constraints, err := semver.NewConstraint(exclusiveUpperBounds(constraint))
+	if err != nil {
+		return nil, fmt.Errorf("invalid semver constraint policy: %s, error: %s", policy, err)
+	}
+
+	return &SemverConstraintPolicy{
+		constraint:  constraint,
+		constraints: constraints,
+	}, nil
+}
+
+// ShouldUpdate - new version has to satisfy the constraint, be higher than current and
+// keep the variant of current, ie: alpine in 1.4.2-alpine
+func (p *SemverConstraintPolicy) ShouldUpdate(current, new string) (bool, error) {
+	newVersion, err := version.GetVersion(new)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse new version: %s", err)
+	}
+
+	// variants are not pre-releases, the constraint is checked without them
+	checked, err := semver.NewVersion(types.Version{
+		Major:      newVersion.Major,
+		Minor:      newVersion.Minor,
+		Patch:      newVersion.Patch,
+		PreRelease: newVersion.PreRelease,
+	}.String())
+	if err != nil {
+		return false, fmt.Errorf("failed to parse new version: %s", err)
+	}
+	if !p.constraints.Check(checked) {
+		return false, nil
+	}
+
+	if current == "latest" {
+		return newVersion.Variant == "", nil
+	}
+
+	currentVersion, err := version.GetVersion(current)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse current version: %s", err)
+	}
+
+	if currentVersion.Variant != newVersion.Variant {
+		return false, nil
+	}
+
+	cmp, err := version.Compare(currentVersion, newVersion)
+	if err != nil {
+		return false, err
+	}
+	return cmp < 0, nil
+}
+
+// Constraint - parsed constraint, ie: >=2.1, <3
+func (p *SemverConstraintPolicy) Constraint() string { return p.constraint }
+
+func (p *SemverConstraintPolicy) Name() string     { return "semver:" + p.constraint }
+func (p *SemverConstraintPolicy) Type() PolicyType { return PolicyTypeSemver }
+
+// normalizeConstraint - Masterminds semver only accepts commas between constraints which
+// all have to match, ">=2.1 <3" and ">= 2.1, < 3" both become ">=2.1, <3"
+func normalizeConstraint(constraint string) string {
+	var or []string
+	for _, group := range strings.Split(constraint, "||") {
+		and := splitConstraints(group)
+		if len(and) == 0 {
+			// keeps empty groups, so "~1.4 ||" fails to parse
+			or = append(or, "")
+			continue
+		}
+		or = append(or, strings.Join(and, ", "))
+	}
+	if len(or) == 1 && or[0] == "" {
+		return ""
+	}
+	return strings.Join(or, " || ")
+}
+
+// partialUpperBound - ie: <3 or <2.1
+var partialUpperBound = regexp.MustCompile(`(^|[\s,|])<\s*(v?\d+(\.\d+)?)(\s*($|[,|]))`)
+
+// exclusiveUpperBounds - Masterminds semver treats <3 like <3.x, so 3.1.0 satisfies it.
+// Partial versions after < are completed with zeros, <3 becomes <3.0.0.
+func exclusiveUpperBounds(constraint string) string {
+	return partialUpperBound.ReplaceAllStringFunc(constraint, func(m string) string {
+		parts := partialUpperBound.FindStringSubmatch(m)
+		version := parts[2]
+		for i := strings.Count(version, "."); i < 2; i++ {
+			version += ".0"
+		}
+		return parts[1] + "<" + version + parts[4]
+	})
+}
+
+func splitConstraints(group string) []string {
+	fields := strings.FieldsFunc(group, func(r rune) bool {
+		return unicode.IsSpace(r) || r == ','
+	})
+
+	var constraints []string
+	operator := ""
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		switch {
+		case field == "-" && len(constraints) > 0 && i+1 < len(fields):
+			// hyphen range, ie: 1.2 - 1.4
+			constraints[len(constraints)-1] += " - " + fields[i+1]
+			i++
+		case strings.Trim(field, "=<>!~^") == "":
+			// operator separated from its version, ie: >= 2.1
+			operator += field
+		default:
+			constraints = append(constraints, operator+field)
+			operator = ""
+		}
+	}
+	if operator != "" {
+		constraints = append(constraints, operator)
+	}
+	return constraints
+}
@@ -0,0 +1,139 @@
+package policy
+
+import "testing"
+
+func TestNewSemverConstraintPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		want    string
+		wantErr bool
+	}{
+		{name: "tilde", policy: "semver:~1.4", want: "~1.4"},
+		{name: "space separated", policy: "semver:>=2.1 <3", want: ">=2.1, <3"},
+		{name: "comma separated", policy: "semver:>= 2.1, < 3", want: ">=2.1, <3"},
+		{name: "or", policy: "semver:~1.4 || >=2.1 <3", want: "~1.4 || >=2.1, <3"},
+		{name: "hyphen range", policy: "semver:1.2 - 1.4.5", want: "1.2 - 1.4.5"},
+		{name: "empty", policy: "semver:", wantErr: true},
+		{name: "invalid version", policy: "semver:~foo", wantErr: true},
+		{name: "missing version", policy: "semver:>=2.1 <", wantErr: true},
+		{name: "empty or", policy: "semver:~1.4 ||", wantErr: true},
+		{name: "no prefix", policy: "~1.4", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewSemverConstraintPolicy(tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewSemverConstraintPolicy() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got.Constraint() != tt.want {
+				t.Errorf("NewSemverConstraintPolicy() constraint = %v, want %v", got.Constraint(), tt.want)
+			}
+			if got.Name() != "semver:"+tt.want {
+				t.Errorf("NewSemverConstraintPolicy() name = %v, want semver:%v", got.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSemverConstraintPolicy_ShouldUpdate(t *testing.T) {
+	type args struct {
+		current string
+		new     string
+	}
+	tests := []struct {
+		name    string
+		policy  string
+		args    args
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:   "tilde patch",
+			policy: "semver:~1.4",
+			args:   args{current: "1.4.1", new: "1.4.2"},
+			want:   true,
+		},
+		{
+			name:   "tilde minor",
+			policy: "semver:~1.4",
+			args:   args{current: "1.4.1", new: "1.5.0"},
+			want:   false,
+		},
+		{
+			name:   "range",
+			policy: "semver:>=2.1 <3",
+			args:   args{current: "2.1.0", new: "2.9.9"},
+			want:   true,
+		},
+		{
+			name:   "range major",
+			policy: "semver:>=2.1 <3",
+			args:   args{current: "2.1.0", new: "3.0.0"},
+			want:   false,
+		},
+		{
+			name:   "partial upper bound",
+			policy: "semver:<3 || ~4.1",
+			args:   args{current: "2.1.0", new: "3.1.0"},
+			want:   false,
+		},
+		{
+			name:   "lower version",
+			policy: "semver:<3",
+			args:   args{current: "2.1.0", new: "2.0.0"},
+			want:   false,
+		},
+		{
+			name:   "prerelease",
+			policy: "semver:~1.4",
+			args:   args{current: "1.4.1", new: "1.4.2-rc.1"},
+			want:   false,
+		},
+		{
+			name:   "latest",
+			policy: "semver:^1.4",
+			args:   args{current: "latest", new: "1.9.0"},
+			want:   true,
+		},
+		{
+			name:   "variant",
+			policy: "semver:~1.4",
+			args:   args{current: "1.4.1-alpine", new: "1.4.2-alpine"},
+			want:   true,
+		},
+		{
+			name:   "other variant",
+			policy: "semver:~1.4",
+			args:   args{current: "1.4.1-alpine", new: "1.4.2-slim"},
+			want:   false,
+		},
+		{
+			name:    "not semver",
+			policy:  "semver:^1.4",
+			args:    args{current: "1.4.0", new: "alpine"},
+			want:    false,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewSemverConstraintPolicy(tt.policy)
+			if err != nil {
+				t.Fatalf("failed to parse policy: %s", err)
+			}
+			got, err := p.ShouldUpdate(tt.args.current, tt.args.new)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SemverConstraintPolicy.ShouldUpdate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("SemverConstraintPolicy.ShouldUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
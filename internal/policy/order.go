@@ -0,0 +1,176 @@
+package policy
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/alwinius/bow/types"
+)
+
+// Ordered - policies which sort tags by a key taken from the tag, the multi tag poll
+// watcher applies the highest matching tag instead of the highest semver one
+type Ordered interface {
+	// Compare returns -1, 0 or 1 when a sorts before, equal to or after b, tags
+	// without a key sort first
+	Compare(a, b string) int
+}
+
+// AsOrdered - policy sorts tags, regexp policies only with an order
+func AsOrdered(p types.Policy) (Ordered, bool) {
+	if rp, ok := p.(*RegexpPolicy); ok && len(rp.orders) == 0 {
+		return nil, false
+	}
+	o, ok := p.(Ordered)
+	return o, ok
+}
+
+// Order - how values of a capture group are compared
+type Order int
+
+// available orders
+const (
+	OrderAlphabetical Order = iota
+	OrderNumerical
+	OrderTimestamp
+)
+
+func (o Order) String() string {
+	switch o {
+	case OrderNumerical:
+		return "numerical"
+	case OrderTimestamp:
+		return "timestamp"
+	default:
+		return "alphabetical"
+	}
+}
+
+// parseOrder - num, time or alpha
+func parseOrder(name string) (Order, error) {
+	switch name {
+	case "num":
+		return OrderNumerical, nil
+	case "time":
+		return OrderTimestamp, nil
+	case "alpha":
+		return OrderAlphabetical, nil
+	}
+	return OrderAlphabetical, fmt.Errorf("unknown order '%s', expected num, time or alpha", name)
+}
+
+// timestampLayouts - layouts tried for timestamp groups, ie: 20241003.1532
+var timestampLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15-04-05Z",
+	"20060102T150405Z",
+	"20060102150405",
+	"200601021504",
+	"20060102.150405",
+	"20060102.1504",
+	"20060102-150405",
+	"20060102-1504",
+	"2006-01-02",
+	"20060102",
+}
+
+func parseTimestamp(value string) (time.Time, error) {
+	for _, layout := range timestampLayouts {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown timestamp format: %s", value)
+}
+
+func parseNumber(value string) (*big.Rat, error) {
+	n, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil, fmt.Errorf("not a number: %s", value)
+	}
+	return n, nil
+}
+
+// sortValue - value of a capture group parsed according to its order
+type sortValue struct {
+	order  Order
+	text   string
+	number *big.Rat
+	time   time.Time
+}
+
+func newSortValue(order Order, value string) (sortValue, error) {
+	v := sortValue{order: order, text: value}
+	var err error
+	switch order {
+	case OrderNumerical:
+		v.number, err = parseNumber(value)
+	case OrderTimestamp:
+		v.time, err = parseTimestamp(value)
+	}
+	return v, err
+}
+
+func (v sortValue) compare(other sortValue) int {
+	switch v.order {
+	case OrderNumerical:
+		return v.number.Cmp(other.number)
+	case OrderTimestamp:
+		switch {
+		case v.time.Before(other.time):
+			return -1
+		case v.time.After(other.time):
+			return 1
+		}
+		return 0
+	default:
+		return strings.Compare(v.text, other.text)
+	}
+}
+
+// compareKeys - compares keys value by value, a key which is a prefix of the other sorts first
+func compareKeys(a, b []sortValue) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := a[i].compare(b[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
+
+// compareTags - tags without a key (keyFn fails) sort first
+func compareTags(keyFn func(string) ([]sortValue, error), a, b string) int {
+	ka, errA := keyFn(a)
+	kb, errB := keyFn(b)
+	switch {
+	case errA != nil && errB != nil:
+		return 0
+	case errA != nil:
+		return -1
+	case errB != nil:
+		return 1
+	}
+	return compareKeys(ka, kb)
+}
+
+// shouldUpdateOrdered - new tag needs a key and has to sort after current. Tags without
+// a key, ie: latest, are updated to any tag with a key.
+func shouldUpdateOrdered(keyFn func(string) ([]sortValue, error), current, new string) (bool, error) {
+	newKey, err := keyFn(new)
+	if err != nil {
+		return false, err
+	}
+	currentKey, err := keyFn(current)
+	if err != nil {
+		return true, nil
+	}
+	return compareKeys(currentKey, newKey) < 0, nil
+}
@@ -1,6 +1,7 @@
 package policy
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/alwinius/bow/types"
@@ -16,6 +17,8 @@ const (
 	PolicyTypeForce
 	PolicyTypeGlob
 	PolicyTypeRegexp
+	PolicyTypeCalver
+	PolicyTypeCel
 )
 
 type Policy interface {
@@ -30,6 +33,27 @@ func (np *NilPolicy) ShouldUpdate(c, n string) (bool, error) { return false, nil
 func (np *NilPolicy) Name() string                           { return "nil policy" }
 func (np *NilPolicy) Type() PolicyType                       { return PolicyTypeNone }
 
+// MetadataPolicy - policies which can also decide on labels and creation time of the new image
+type MetadataPolicy interface {
+	// NeedsMetadata - whether triggers should fetch image metadata from the registry
+	NeedsMetadata() bool
+	ShouldUpdateWithMetadata(current, new string, metadata *types.ImageMetadata) (bool, error)
+}
+
+// NeedsMetadata - whether plc decides on image metadata
+func NeedsMetadata(plc types.Policy) bool {
+	mp, ok := plc.(MetadataPolicy)
+	return ok && mp.NeedsMetadata()
+}
+
+// ShouldUpdate - passes metadata to policies using it, metadata can be nil
+func ShouldUpdate(plc types.Policy, current, new string, metadata *types.ImageMetadata) (bool, error) {
+	if mp, ok := plc.(MetadataPolicy); ok {
+		return mp.ShouldUpdateWithMetadata(current, new, metadata)
+	}
+	return plc.ShouldUpdate(current, new)
+}
+
 // GetPolicyFromLabelsOrAnnotations - gets policy from k8s labels or annotations
 func GetPolicyFromLabelsOrAnnotations(labels map[string]string, annotations map[string]string) Policy {
 
@@ -46,6 +70,19 @@ func GetPolicyFromLabelsOrAnnotations(labels map[string]string, annotations map[
 	return GetPolicy(policyNameL, &Options{MatchTag: getMatchTag(labels)})
 }
 
+// GetContainerPolicy - gets the policy of a container from bow/policy.<container> annotations
+// or labels, ok is false when the container uses the policy of the resource
+func GetContainerPolicy(container string, labels map[string]string, annotations map[string]string) (plc Policy, ok bool) {
+	key := types.ContainerAnnotation(types.BowPolicyLabel, container)
+	if name, ok := annotations[key]; ok {
+		return GetPolicy(name, &Options{MatchTag: getMatchTag(annotations)}), true
+	}
+	if name, ok := labels[key]; ok {
+		return GetPolicy(name, &Options{MatchTag: getMatchTag(labels)}), true
+	}
+	return nil, false
+}
+
 // Options - additional options when parsing policy
 type Options struct {
 	MatchTag bool
@@ -75,6 +112,36 @@ func GetPolicy(policyName string, options *Options) Policy {
 			return &NilPolicy{}
 		}
 		return p
+	case strings.HasPrefix(policyName, "semver:"):
+		p, err := NewSemverConstraintPolicy(policyName)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"policy": policyName,
+			}).Error("failed to parse semver constraint policy, check your deployment configuration")
+			return &NilPolicy{}
+		}
+		return p
+	case policyName == "calver" || strings.HasPrefix(policyName, "calver:"):
+		p, err := NewCalverPolicy(policyName)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"policy": policyName,
+			}).Error("failed to parse calver policy, check your deployment configuration")
+			return &NilPolicy{}
+		}
+		return p
+	case strings.HasPrefix(policyName, "cel:"):
+		p, err := NewCelPolicy(policyName)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"policy": policyName,
+			}).Error("failed to parse cel policy, check your deployment configuration")
+			return &NilPolicy{}
+		}
+		return p
 	}
 
 	switch policyName {
@@ -91,6 +158,30 @@ func GetPolicy(policyName string, options *Options) Policy {
 	return &NilPolicy{}
 }
 
+// Validate - checks whether policyName is a known and well formed policy
+func Validate(policyName string) error {
+	var err error
+	switch {
+	case strings.HasPrefix(policyName, "glob:"):
+		_, err = NewGlobPolicy(policyName)
+	case strings.HasPrefix(policyName, "regexp:"):
+		_, err = NewRegexpPolicy(policyName)
+	case strings.HasPrefix(policyName, "semver:"):
+		_, err = NewSemverConstraintPolicy(policyName)
+	case policyName == "calver" || strings.HasPrefix(policyName, "calver:"):
+		_, err = NewCalverPolicy(policyName)
+	case strings.HasPrefix(policyName, "cel:"):
+		_, err = NewCelPolicy(policyName)
+	default:
+		switch policyName {
+		case "all", "major", "minor", "patch", "force", "", "never":
+		default:
+			err = fmt.Errorf("unknown policy: %s", policyName)
+		}
+	}
+	return err
+}
+
 // ParseSemverPolicy - parse policy type
 func ParseSemverPolicy(policy string) Policy {
 	switch policy {
@@ -82,6 +82,11 @@ func TestGetPolicy(t *testing.T) {
 			args: args{policyName: "force", options: &Options{MatchTag: true}},
 			want: NewForcePolicy(true),
 		},
+		{
+			name: "invalid semver constraint",
+			args: args{policyName: "semver:~foo", options: &Options{}},
+			want: &NilPolicy{},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -92,6 +97,26 @@ func TestGetPolicy(t *testing.T) {
 	}
 }
 
+func TestGetPolicySemverConstraint(t *testing.T) {
+	p := GetPolicy("semver:>=2.1 <3", &Options{})
+	if p.Type() != PolicyTypeSemver || p.Name() != "semver:>=2.1, <3" {
+		t.Errorf("unexpected policy: %s", p.Name())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, name := range []string{"", "never", "all", "patch", "force", "glob:1.*", "regexp:^1", "semver:~1.4", "calver", "calver:YYYY.MM.MICRO", `cel:new.endsWith("-hotfix")`} {
+		if err := Validate(name); err != nil {
+			t.Errorf("Validate(%s) error = %s", name, err)
+		}
+	}
+	for _, name := range []string{"minors", "regexp:(", "semver:", "semver:~foo", "calver:YYYY.MONTH", "cel:", "cel:newer +", "cel:current"} {
+		if err := Validate(name); err == nil {
+			t.Errorf("expected Validate(%s) to fail", name)
+		}
+	}
+}
+
 func TestGetPolicyFromLabelsOrAnnotations(t *testing.T) {
 	type args struct {
 		labels      map[string]string
@@ -127,3 +152,25 @@ func TestGetPolicyFromLabelsOrAnnotations(t *testing.T) {
 		})
 	}
 }
+
+func TestGetContainerPolicy(t *testing.T) {
+	annotations := map[string]string{
+		types.BowPolicyLabel:            "patch",
+		types.BowPolicyLabel + ".envoy": "minor",
+	}
+	labels := map[string]string{
+		types.BowPolicyLabel + ".logs": "never",
+	}
+
+	plc, ok := GetContainerPolicy("envoy", labels, annotations)
+	if !ok || !reflect.DeepEqual(plc, NewSemverPolicy(SemverPolicyTypeMinor)) {
+		t.Errorf("unexpected envoy policy: %v", plc)
+	}
+	plc, ok = GetContainerPolicy("logs", labels, annotations)
+	if !ok || plc.Type() != PolicyTypeNone {
+		t.Errorf("unexpected logs policy: %v", plc)
+	}
+	if _, ok = GetContainerPolicy("app", labels, annotations); ok {
+		t.Errorf("app should use the policy of the resource")
+	}
+}
@@ -14,6 +14,8 @@ var (
 		"PolicyTypeForce":  PolicyTypeForce,
 		"PolicyTypeGlob":   PolicyTypeGlob,
 		"PolicyTypeRegexp": PolicyTypeRegexp,
+		"PolicyTypeCalver": PolicyTypeCalver,
+		"PolicyTypeCel":    PolicyTypeCel,
 	}
 
 	_PolicyTypeValueToName = map[PolicyType]string{
@@ -22,6 +24,8 @@ var (
 		PolicyTypeForce:  "PolicyTypeForce",
 		PolicyTypeGlob:   "PolicyTypeGlob",
 		PolicyTypeRegexp: "PolicyTypeRegexp",
+		PolicyTypeCalver: "PolicyTypeCalver",
+		PolicyTypeCel:    "PolicyTypeCel",
 	}
 )
 
@@ -34,6 +38,8 @@ func init() {
 			interface{}(PolicyTypeForce).(fmt.Stringer).String():  PolicyTypeForce,
 			interface{}(PolicyTypeGlob).(fmt.Stringer).String():   PolicyTypeGlob,
 			interface{}(PolicyTypeRegexp).(fmt.Stringer).String(): PolicyTypeRegexp,
+			interface{}(PolicyTypeCalver).(fmt.Stringer).String(): PolicyTypeCalver,
+			interface{}(PolicyTypeCel).(fmt.Stringer).String():    PolicyTypeCel,
 		}
 	}
 }
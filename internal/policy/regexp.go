@@ -6,26 +6,56 @@ import (
 	"strings"
 )
 
-// RegexpPolicy - regular expression based pattern
+// regexpOrderOption - separates the order of the capture groups from the pattern
+const regexpOrderOption = ";order="
+
+// RegexpPolicy - regular expression based pattern. With an order, ie:
+// regexp:^main-(\d{8}\.\d{4})-;order=time, tags are compared by their capture groups and
+// only tags sorting after the current one are updated to. Orders are num, time or alpha,
+// either one for every group or a single one for all of them.
 type RegexpPolicy struct {
 	policy string
 	regexp *regexp.Regexp
+	// orders - orders of the capture groups, empty when tags are only matched
+	orders []Order
 }
 
 func NewRegexpPolicy(policy string) (*RegexpPolicy, error) {
 	if strings.Contains(policy, ":") {
 		parts := strings.Split(policy, ":")
 		if len(parts) == 2 {
+			pattern, orders := parts[1], ""
+			if idx := strings.LastIndex(pattern, regexpOrderOption); idx >= 0 {
+				pattern, orders = pattern[:idx], pattern[idx+len(regexpOrderOption):]
+			}
 
-			rx, err := regexp.Compile(parts[1])
+			rx, err := regexp.Compile(pattern)
 			if err != nil {
 				return nil, fmt.Errorf("failed to parse regexp pattern, error: %s", err)
 			}
 
-			return &RegexpPolicy{
+			p := &RegexpPolicy{
 				regexp: rx,
 				policy: policy,
-			}, nil
+			}
+			if orders == "" {
+				return p, nil
+			}
+			if rx.NumSubexp() == 0 {
+				return nil, fmt.Errorf("invalid regexp policy: %s, ordering needs a capture group", policy)
+			}
+			names := strings.Split(orders, ",")
+			if len(names) != 1 && len(names) != rx.NumSubexp() {
+				return nil, fmt.Errorf("invalid regexp policy: %s, expected one order or one per capture group, got %d for %d groups", policy, len(names), rx.NumSubexp())
+			}
+			for i := 0; i < rx.NumSubexp(); i++ {
+				order, err := parseOrder(strings.TrimSpace(names[i%len(names)]))
+				if err != nil {
+					return nil, fmt.Errorf("invalid regexp policy: %s, %s", policy, err)
+				}
+				p.orders = append(p.orders, order)
+			}
+			return p, nil
 		}
 	}
 
@@ -33,7 +63,34 @@ func NewRegexpPolicy(policy string) (*RegexpPolicy, error) {
 }
 
 func (p *RegexpPolicy) ShouldUpdate(current, new string) (bool, error) {
-	return p.regexp.MatchString(new), nil
+	if !p.regexp.MatchString(new) {
+		return false, nil
+	}
+	if len(p.orders) == 0 {
+		return true, nil
+	}
+	return shouldUpdateOrdered(p.key, current, new)
+}
+
+// Compare - orders tags by their capture groups
+func (p *RegexpPolicy) Compare(a, b string) int {
+	return compareTags(p.key, a, b)
+}
+
+func (p *RegexpPolicy) key(tag string) ([]sortValue, error) {
+	m := p.regexp.FindStringSubmatch(tag)
+	if m == nil {
+		return nil, fmt.Errorf("tag %s does not match %s", tag, p.regexp)
+	}
+	key := make([]sortValue, len(p.orders))
+	for i, order := range p.orders {
+		v, err := newSortValue(order, m[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s group of tag %s: %s", order, tag, err)
+		}
+		key[i] = v
+	}
+	return key, nil
 }
 
 func (p *RegexpPolicy) Name() string     { return p.policy }
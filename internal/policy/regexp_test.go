@@ -0,0 +1,136 @@
+package policy
+
+import "testing"
+
+func TestRegexpPolicy_ShouldUpdate(t *testing.T) {
+	type args struct {
+		current string
+		new     string
+	}
+	tests := []struct {
+		name    string
+		policy  string
+		args    args
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:   "match without groups",
+			policy: `regexp:^main-`,
+			args:   args{current: "main-20241003.1532-abc123", new: "main-20240101.0000-def456"},
+			want:   true,
+		},
+		{
+			name:   "named groups without order only match",
+			policy: `regexp:^main-(?P<ts>\d{8}\.\d{4})-[a-f0-9]+$`,
+			args:   args{current: "main-20241003.1532-abc123", new: "main-20241003.1200-def456"},
+			want:   true,
+		},
+		{
+			name:   "timestamp newer",
+			policy: `regexp:^main-(\d{8}\.\d{4})-[a-f0-9]+$;order=time`,
+			args:   args{current: "main-20241003.1532-abc123", new: "main-20241004.0910-def456"},
+			want:   true,
+		},
+		{
+			name:   "timestamp older",
+			policy: `regexp:^main-(\d{8}\.\d{4})-[a-f0-9]+$;order=time`,
+			args:   args{current: "main-20241003.1532-abc123", new: "main-20241003.1200-def456"},
+			want:   false,
+		},
+		{
+			name:   "numerical",
+			policy: `regexp:^build-(\d+)$;order=num`,
+			args:   args{current: "build-9", new: "build-10"},
+			want:   true,
+		},
+		{
+			name:   "alphabetical",
+			policy: `regexp:^release-([a-z]+)$;order=alpha`,
+			args:   args{current: "release-bionic", new: "release-focal"},
+			want:   true,
+		},
+		{
+			name:   "composite key",
+			policy: `regexp:^(\d+)-(\d+)$;order=num`,
+			args:   args{current: "2-10", new: "2-9"},
+			want:   false,
+		},
+		{
+			name:   "order per group",
+			policy: `regexp:^([a-z]+)-(\d+)$;order=alpha,num`,
+			args:   args{current: "beta-10", new: "beta-9"},
+			want:   false,
+		},
+		{
+			name:   "not matching",
+			policy: `regexp:^build-(\d+)$;order=num`,
+			args:   args{current: "build-9", new: "main-10"},
+			want:   false,
+		},
+		{
+			name:   "current without key",
+			policy: `regexp:^build-(\d+)$;order=num`,
+			args:   args{current: "latest", new: "build-1"},
+			want:   true,
+		},
+		{
+			name:    "invalid timestamp",
+			policy:  `regexp:^main-(\d+)$;order=time`,
+			args:    args{current: "main-20241003", new: "main-123"},
+			want:    false,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewRegexpPolicy(tt.policy)
+			if err != nil {
+				t.Fatalf("failed to parse policy: %s", err)
+			}
+			got, err := p.ShouldUpdate(tt.args.current, tt.args.new)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RegexpPolicy.ShouldUpdate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("RegexpPolicy.ShouldUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRegexpPolicyInvalidOrder(t *testing.T) {
+	for _, policy := range []string{
+		`regexp:^main-;order=num`,
+		`regexp:^main-(\d+)$;order=size`,
+		`regexp:^(\d+)-(\d+)-(\d+)$;order=num,alpha`,
+	} {
+		if _, err := NewRegexpPolicy(policy); err == nil {
+			t.Errorf("expected %s to be rejected", policy)
+		}
+	}
+}
+
+func TestAsOrdered(t *testing.T) {
+	if _, ok := AsOrdered(mustParseRegexp(`regexp:^main-`)); ok {
+		t.Errorf("regexp policy without order should not be ordered")
+	}
+	if _, ok := AsOrdered(mustParseRegexp(`regexp:^main-(?P<ts>\d{8})`)); ok {
+		t.Errorf("regexp policy with named groups but without order should not be ordered")
+	}
+	if _, ok := AsOrdered(mustParseRegexp(`regexp:^main-(\d{8});order=time`)); !ok {
+		t.Errorf("regexp policy with order should be ordered")
+	}
+	if _, ok := AsOrdered(NewSemverPolicy(SemverPolicyTypeAll)); ok {
+		t.Errorf("semver policy should not be ordered")
+	}
+}
+
+func mustParseRegexp(r string) *RegexpPolicy {
+	p, err := NewRegexpPolicy(r)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
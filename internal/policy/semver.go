@@ -3,9 +3,8 @@ package policy
 import (
 	"errors"
 	"fmt"
-	"strings"
 
-	"github.com/Masterminds/semver"
+	"github.com/alwinius/bow/util/version"
 )
 
 // SemverPolicyType - policy type
@@ -61,32 +60,38 @@ func (sp *SemverPolicy) Name() string {
 
 func (sp *SemverPolicy) Type() PolicyType { return PolicyTypeSemver }
 
+// shouldUpdate - versions are parsed with version.GetVersion, updates never switch the
+// variant of an image (1.21.3-alpine only updates to other -alpine tags) and only the
+// all policy switches pre-releases
 func shouldUpdate(spt SemverPolicyType, current, new string) (bool, error) {
 	if current == "latest" {
 		return true, nil
 	}
 
-	parts := strings.SplitN(new, ".", 3)
-	if len(parts) != 3 {
-		return false, ErrNoMajorMinorPatchElementsFound
-	}
-
-	currentVersion, err := semver.NewVersion(current)
+	currentVersion, err := version.GetVersion(current)
 	if err != nil {
 		return false, fmt.Errorf("failed to parse current version: %s", err)
 	}
 
-	newVersion, err := semver.NewVersion(new)
+	newVersion, err := version.GetVersion(new)
 	if err != nil {
 		return false, fmt.Errorf("failed to parse new version: %s", err)
 	}
 
-	if currentVersion.Prerelease() != newVersion.Prerelease() && spt != SemverPolicyTypeAll {
+	if currentVersion.Variant != newVersion.Variant {
+		return false, nil
+	}
+
+	if currentVersion.PreRelease != newVersion.PreRelease && spt != SemverPolicyTypeAll {
 		return false, nil
 	}
 
 	// new version is not higher than current - do nothing
-	if !currentVersion.LessThan(newVersion) {
+	cmp, err := version.Compare(currentVersion, newVersion)
+	if err != nil {
+		return false, err
+	}
+	if cmp >= 0 {
 		return false, nil
 	}
 
@@ -94,9 +99,9 @@ func shouldUpdate(spt SemverPolicyType, current, new string) (bool, error) {
 	case SemverPolicyTypeAll, SemverPolicyTypeMajor:
 		return true, nil
 	case SemverPolicyTypeMinor:
-		return newVersion.Major() == currentVersion.Major(), nil
+		return newVersion.Major == currentVersion.Major, nil
 	case SemverPolicyTypePatch:
-		return newVersion.Major() == currentVersion.Major() && newVersion.Minor() == currentVersion.Minor(), nil
+		return newVersion.Major == currentVersion.Major && newVersion.Minor == currentVersion.Minor, nil
 	}
 	return false, nil
 }
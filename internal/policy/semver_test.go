@@ -176,6 +176,66 @@ func Test_shouldUpdate(t *testing.T) {
 			want:    false,
 			wantErr: false,
 		},
+		{
+			name: "variant patch increase, policy patch",
+			args: args{
+				current: "1.21.3-alpine",
+				new:     "1.21.4-alpine",
+				spt:     SemverPolicyTypePatch,
+			},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name: "other variant, policy all",
+			args: args{
+				current: "1.21.3-alpine",
+				new:     "1.22.0-slim",
+				spt:     SemverPolicyTypeAll,
+			},
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name: "no variant, policy all",
+			args: args{
+				current: "1.21.3-alpine",
+				new:     "1.22.0",
+				spt:     SemverPolicyTypeAll,
+			},
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name: "pre-release of variant, policy all",
+			args: args{
+				current: "1.21.3-alpine",
+				new:     "1.22.0-rc.1-alpine",
+				spt:     SemverPolicyTypeAll,
+			},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name: "two part versions, policy minor",
+			args: args{
+				current: "v1.20",
+				new:     "v1.21",
+				spt:     SemverPolicyTypeMinor,
+			},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name: "two part to three part, policy patch",
+			args: args{
+				current: "1.21",
+				new:     "1.21.1",
+				spt:     SemverPolicyTypePatch,
+			},
+			want:    true,
+			wantErr: false,
+		},
 		{
 			name: "number",
 			args: args{
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/alwinius/bow/internal/k8s"
 	"github.com/alwinius/bow/pkg/store"
 	"github.com/alwinius/bow/types"
 )
@@ -87,27 +88,29 @@ func (s *TriggerServer) approvalSetHandler(resp http.ResponseWriter, req *http.R
 		return
 	}
 
-	for _, v := range s.grc.Values() {
-		if v.Identifier == approvalUpdateRequest.Identifier {
-
-			labels := v.GetLabels()
-			delete(labels, types.BowMinimumApprovalsLabel)
-			v.SetLabels(labels)
+	v, err := k8s.FindResource(s.grc.Values(), approvalUpdateRequest.Identifier)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusConflict)
+		return
+	}
+	if v == nil {
+		resp.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(resp, "resource with identifier '%s' not found", approvalUpdateRequest.Identifier)
+		return
+	}
 
-			ann := v.GetAnnotations()
-			ann[types.BowMinimumApprovalsLabel] = strconv.Itoa(approvalUpdateRequest.VotesRequired)
+	labels := v.GetLabels()
+	delete(labels, types.BowMinimumApprovalsLabel)
+	v.SetLabels(labels)
 
-			v.SetAnnotations(ann)
+	ann := v.GetAnnotations()
+	ann[types.BowMinimumApprovalsLabel] = strconv.Itoa(approvalUpdateRequest.VotesRequired)
 
-			//err := s.kubernetesClient.Update(v)
+	v.SetAnnotations(ann)
 
-			response(&APIResponse{Status: "updated"}, 200, err, resp, req)
-			return
-		}
-	}
+	//err := s.kubernetesClient.Update(v)
 
-	resp.WriteHeader(http.StatusNotFound)
-	fmt.Fprintf(resp, "resource with identifier '%s' not found", approvalUpdateRequest.Identifier)
+	response(&APIResponse{Status: "updated"}, 200, err, resp, req)
 }
 
 func (s *TriggerServer) approvalApproveHandler(resp http.ResponseWriter, req *http.Request) {
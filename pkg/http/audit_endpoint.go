@@ -2,12 +2,16 @@ package http
 
 import (
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/alwinius/bow/types"
 )
 
+// commitPattern - full or abbreviated commit hash accepted by the commit filter
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{4,40}$`)
+
 func (s *TriggerServer) adminAuditLogHandler(resp http.ResponseWriter, req *http.Request) {
 
 	query := &types.AuditLogQuery{}
@@ -38,6 +42,15 @@ func (s *TriggerServer) adminAuditLogHandler(resp http.ResponseWriter, req *http
 		query.Email = strings.TrimSpace(emailFilter)
 	}
 
+	commitFilter := strings.ToLower(strings.TrimSpace(req.URL.Query().Get("commit")))
+	if commitFilter != "" {
+		if !commitPattern.MatchString(commitFilter) {
+			http.Error(resp, "commit has to be a (short) commit hash", http.StatusBadRequest)
+			return
+		}
+		query.Commit = commitFilter
+	}
+
 	entries, err := s.store.GetAuditLogs(query)
 	if err != nil {
 		response(nil, 500, err, resp, req)
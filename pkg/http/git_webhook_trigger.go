@@ -0,0 +1,180 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/alwinius/bow/internal/gitrepo"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var newGitWebhooksCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "git_webhook_requests_total",
+		Help: "How many /v1/webhooks/git requests processed, partitioned by forge and repository.",
+	},
+	[]string{"forge", "repository"},
+)
+
+func init() {
+	prometheus.MustRegister(newGitWebhooksCounter)
+}
+
+// maxGitWebhookBody - push events larger than this are rejected before they are verified
+const maxGitWebhookBody = 1 << 20
+
+// gitPushWebhook - fields of GitHub, Gitea and GitLab push events that identify the pushed branch
+type gitPushWebhook struct {
+	Ref        string `json:"ref"`
+	Repository struct {
+		CloneURL string `json:"clone_url"`
+		SSHURL   string `json:"ssh_url"`
+		HTMLURL  string `json:"html_url"`
+		// GitLab
+		GitHTTPURL string `json:"git_http_url"`
+		GitSSHURL  string `json:"git_ssh_url"`
+	} `json:"repository"`
+	Project struct {
+		GitHTTPURL string `json:"git_http_url"`
+		GitSSHURL  string `json:"git_ssh_url"`
+		WebURL     string `json:"web_url"`
+	} `json:"project"`
+}
+
+func (w *gitPushWebhook) urls() []string {
+	var urls []string
+	for _, u := range []string{w.Repository.CloneURL, w.Repository.SSHURL, w.Repository.HTMLURL,
+		w.Repository.GitHTTPURL, w.Repository.GitSSHURL, w.Project.GitHTTPURL, w.Project.GitSSHURL, w.Project.WebURL} {
+		if u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// gitForge - detects the sender of a webhook from its event header
+func gitForge(req *http.Request) (forge string, event string) {
+	switch {
+	case req.Header.Get("X-Gitea-Event") != "":
+		return "gitea", req.Header.Get("X-Gitea-Event")
+	case req.Header.Get("X-GitHub-Event") != "":
+		return "github", req.Header.Get("X-GitHub-Event")
+	case req.Header.Get("X-Gitlab-Event") != "":
+		return "gitlab", req.Header.Get("X-Gitlab-Event")
+	}
+	return "", ""
+}
+
+// verifyGitWebhook - checks the signature (GitHub, Gitea) or token (GitLab) of the request against secret
+func verifyGitWebhook(forge string, req *http.Request, body []byte, secret string) bool {
+	if secret == "" {
+		return false
+	}
+
+	switch forge {
+	case "github":
+		if signature := req.Header.Get("X-Hub-Signature-256"); signature != "" {
+			return validHMAC(sha256.New, secret, body, strings.TrimPrefix(signature, "sha256="))
+		}
+		if signature := req.Header.Get("X-Hub-Signature"); signature != "" {
+			return validHMAC(sha1.New, secret, body, strings.TrimPrefix(signature, "sha1="))
+		}
+	case "gitea":
+		return validHMAC(sha256.New, secret, body, req.Header.Get("X-Gitea-Signature"))
+	case "gitlab":
+		token := req.Header.Get("X-Gitlab-Token")
+		return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+	}
+	return false
+}
+
+func validHMAC(h func() hash.Hash, secret string, body []byte, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// gitWebhookHandler - refreshes watched repositories when their branch was pushed to
+func (s *TriggerServer) gitWebhookHandler(resp http.ResponseWriter, req *http.Request) {
+	forge, event := gitForge(req)
+	if forge == "" {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "unknown forge, expected GitHub, GitLab or Gitea event header")
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(resp, req.Body, maxGitWebhookBody))
+	if err != nil {
+		resp.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if event != "push" && event != "Push Hook" {
+		// ping and other events
+		resp.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var push gitPushWebhook
+	if err := json.Unmarshal(body, &push); err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"forge": forge,
+		}).Error("trigger.gitWebhookHandler: failed to decode request")
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var matched []*gitrepo.Repo
+	for _, repo := range s.repos {
+		for _, u := range push.urls() {
+			if repo.Matches(u, push.Ref) {
+				matched = append(matched, repo)
+				break
+			}
+		}
+	}
+	// unknown repositories get the same answer as bad signatures so that callers without the
+	// secret cannot probe which repositories and branches are watched
+	refreshed := 0
+	for _, repo := range matched {
+		if !verifyGitWebhook(forge, req, body, repo.WebhookSecret) {
+			log.WithFields(log.Fields{
+				"forge": forge,
+				"repo":  repo.Name,
+			}).Warn("trigger.gitWebhookHandler: invalid signature or no webhook secret configured")
+			continue
+		}
+		repo.Refresh()
+		refreshed++
+		newGitWebhooksCounter.With(prometheus.Labels{"forge": forge, "repository": repo.Name}).Inc()
+	}
+
+	if refreshed == 0 {
+		log.WithFields(log.Fields{
+			"forge":   forge,
+			"ref":     push.Ref,
+			"matched": len(matched),
+		}).Debug("trigger.gitWebhookHandler: no watched repository refreshed")
+		resp.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintf(resp, "invalid signature")
+		return
+	}
+	resp.WriteHeader(http.StatusOK)
+}
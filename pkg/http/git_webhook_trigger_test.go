@@ -0,0 +1,145 @@
+package http
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alwinius/bow/internal/gitrepo"
+)
+
+var githubPush = []byte(`{
+	"ref": "refs/heads/master",
+	"repository": {
+		"clone_url": "https://github.com/team/deployment.git",
+		"ssh_url": "git@github.com:team/deployment.git"
+	}
+}`)
+
+var gitlabPush = []byte(`{
+	"ref": "refs/heads/master",
+	"project": {
+		"git_ssh_url": "git@gitlab.com:group/deployment.git",
+		"git_http_url": "https://gitlab.com/group/deployment.git"
+	}
+}`)
+
+var featurePush = bytes.Replace(githubPush, []byte("refs/heads/master"), []byte("refs/heads/feature"), 1)
+
+func sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newGitWebhookServer(t *testing.T) *TriggerServer {
+	github, err := gitrepo.NewRepo(gitrepo.RepoConfig{URL: "https://github.com/team/deployment.git", WebhookSecret: "secret"}, "/tmp")
+	if err != nil {
+		t.Fatalf("failed to create repo: %s", err)
+	}
+	gitlab, err := gitrepo.NewRepo(gitrepo.RepoConfig{URL: "https://gitlab.com/group/deployment", WebhookSecret: "token"}, "/tmp")
+	if err != nil {
+		t.Fatalf("failed to create repo: %s", err)
+	}
+	return NewTriggerServer(&Opts{Repos: []*gitrepo.Repo{github, gitlab}})
+}
+
+func TestGitWebhookHandler(t *testing.T) {
+	srv := newGitWebhookServer(t)
+
+	tests := []struct {
+		name   string
+		body   []byte
+		header map[string]string
+		want   int
+	}{
+		{
+			name:   "github signed",
+			body:   githubPush,
+			header: map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": "sha256=" + sign(githubPush, "secret")},
+			want:   http.StatusOK,
+		},
+		{
+			name:   "github wrong signature",
+			body:   githubPush,
+			header: map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": "sha256=" + sign(githubPush, "other")},
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "github unsigned",
+			body:   githubPush,
+			header: map[string]string{"X-GitHub-Event": "push"},
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "gitea signed",
+			body:   githubPush,
+			header: map[string]string{"X-Gitea-Event": "push", "X-Gitea-Signature": sign(githubPush, "secret")},
+			want:   http.StatusOK,
+		},
+		{
+			name:   "gitlab token",
+			body:   gitlabPush,
+			header: map[string]string{"X-Gitlab-Event": "Push Hook", "X-Gitlab-Token": "token"},
+			want:   http.StatusOK,
+		},
+		{
+			name:   "gitlab wrong token",
+			body:   gitlabPush,
+			header: map[string]string{"X-Gitlab-Event": "Push Hook", "X-Gitlab-Token": "secret"},
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "other branch",
+			body:   featurePush,
+			header: map[string]string{"X-GitHub-Event": "push"},
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "other branch signed",
+			body:   featurePush,
+			header: map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": "sha256=" + sign(featurePush, "secret")},
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "too large",
+			body:   append(append([]byte{}, githubPush...), bytes.Repeat([]byte(" "), maxGitWebhookBody)...),
+			header: map[string]string{"X-GitHub-Event": "push"},
+			want:   http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "ping",
+			body:   []byte(`{"zen": "Keep it logically awesome."}`),
+			header: map[string]string{"X-GitHub-Event": "ping"},
+			want:   http.StatusOK,
+		},
+		{
+			name: "unknown forge",
+			body: githubPush,
+			want: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/v1/webhooks/git", bytes.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("failed to create req: %s", err)
+			}
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+
+			rec := httptest.NewRecorder()
+			srv.gitWebhookHandler(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("unexpected status code: %d, want %d (%s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/urfave/negroni"
 
 	"github.com/alwinius/bow/approvals"
+	"github.com/alwinius/bow/internal/gitrepo"
 	"github.com/alwinius/bow/internal/k8s"
 	"github.com/alwinius/bow/pkg/auth"
 	"github.com/alwinius/bow/pkg/store"
@@ -44,6 +45,9 @@ type Opts struct {
 	UIDir string
 
 	AuthenticatedWebhooks bool
+
+	// Repos are refreshed by push webhooks
+	Repos []*gitrepo.Repo
 }
 
 // TriggerServer - webhook trigger & healthcheck server
@@ -62,6 +66,8 @@ type TriggerServer struct {
 	uiDir string
 
 	authenticatedWebhooks bool
+
+	repos []*gitrepo.Repo
 }
 
 // NewTriggerServer - create new HTTP trigger based server
@@ -76,6 +82,7 @@ func NewTriggerServer(opts *Opts) *TriggerServer {
 		store:                 opts.Store,
 		uiDir:                 opts.UIDir,
 		authenticatedWebhooks: opts.AuthenticatedWebhooks,
+		repos:                 opts.Repos,
 	}
 }
 
@@ -143,6 +150,10 @@ func (s *TriggerServer) registerRoutes(mux *mux.Router) {
 
 		// available resources
 		mux.HandleFunc("/v1/resources", s.requireAdminAuthorization(s.resourcesHandler)).Methods("GET", "OPTIONS")
+		// reverting the last update of a resource, identifiers contain slashes
+		mux.HandleFunc("/v1/resources/{identifier:.+}/rollback", s.requireAdminAuthorization(s.rollbackHandler)).Methods("POST", "OPTIONS")
+		// updates planned in dry run mode
+		mux.HandleFunc("/v1/plans", s.requireAdminAuthorization(s.plansHandler)).Methods("GET", "OPTIONS")
 
 		mux.HandleFunc("/v1/policies", s.requireAdminAuthorization(s.policyUpdateHandler)).Methods("PUT", "OPTIONS")
 
@@ -172,6 +183,9 @@ func (s *TriggerServer) registerRoutes(mux *mux.Router) {
 
 func (s *TriggerServer) registerWebhookRoutes(mux *mux.Router) {
 
+	// git push events carry their own signature, see verifyGitWebhook
+	mux.HandleFunc("/v1/webhooks/git", s.gitWebhookHandler).Methods("POST", "OPTIONS")
+
 	if s.authenticatedWebhooks {
 		mux.HandleFunc("/v1/webhooks/native", s.requireAdminAuthorization(s.nativeHandler)).Methods("POST", "OPTIONS")
 		mux.HandleFunc("/v1/webhooks/dockerhub", s.requireAdminAuthorization(s.dockerHubHandler)).Methods("POST", "OPTIONS")
@@ -0,0 +1,17 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/alwinius/bow/provider"
+	"github.com/alwinius/bow/types"
+)
+
+func (s *TriggerServer) plansHandler(resp http.ResponseWriter, req *http.Request) {
+	plans := []*types.PlannedUpdate{}
+	if planner, ok := s.providers.(provider.Planner); ok {
+		plans = append(plans, planner.Plans()...)
+	}
+
+	response(&plans, 200, nil, resp, req)
+}
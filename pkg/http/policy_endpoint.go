@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/alwinius/bow/internal/k8s"
+	"github.com/alwinius/bow/internal/policy"
 	"github.com/alwinius/bow/types"
 )
 
@@ -31,26 +33,33 @@ func (s *TriggerServer) policyUpdateHandler(resp http.ResponseWriter, req *http.
 		return
 	}
 
-	for _, v := range s.grc.Values() {
-		if v.Identifier == policyRequest.Identifier {
+	err = policy.Validate(policyRequest.Policy)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-			labels := v.GetLabels()
-			delete(labels, types.BowPolicyLabel)
-			v.SetLabels(labels)
+	v, err := k8s.FindResource(s.grc.Values(), policyRequest.Identifier)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusConflict)
+		return
+	}
+	if v == nil {
+		resp.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(resp, "resource with identifier '%s' not found", policyRequest.Identifier)
+		return
+	}
 
-			ann := v.GetAnnotations()
-			ann[types.BowPolicyLabel] = policyRequest.Policy
+	labels := v.GetLabels()
+	delete(labels, types.BowPolicyLabel)
+	v.SetLabels(labels)
 
-			v.SetAnnotations(ann)
+	ann := v.GetAnnotations()
+	ann[types.BowPolicyLabel] = policyRequest.Policy
 
-			//err := s.kubernetesClient.Update(v)
+	v.SetAnnotations(ann)
 
-			response(&APIResponse{Status: "updated"}, 200, err, resp, req)
-			return
-		}
-	}
+	//err := s.kubernetesClient.Update(v)
 
-	resp.WriteHeader(http.StatusNotFound)
-	fmt.Fprintf(resp, "resource with identifier '%s' not found", policyRequest.Identifier)
-	return
+	response(&APIResponse{Status: "updated"}, 200, err, resp, req)
 }
@@ -0,0 +1,39 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/alwinius/bow/internal/k8s"
+	"github.com/alwinius/bow/pkg/auth"
+	"github.com/alwinius/bow/provider"
+
+	"github.com/gorilla/mux"
+)
+
+func (s *TriggerServer) rollbackHandler(resp http.ResponseWriter, req *http.Request) {
+	identifier := mux.Vars(req)["identifier"]
+
+	rollbacker, ok := s.providers.(provider.Rollbacker)
+	if !ok {
+		http.Error(resp, "rollback is not supported by the providers", http.StatusNotImplemented)
+		return
+	}
+
+	user := "unknown"
+	if account := auth.GetAccountFromCtx(req.Context()); account != nil {
+		user = account.Username
+	}
+
+	rollback, err := rollbacker.Rollback(identifier, user)
+	if err == provider.ErrResourceNotFound {
+		http.Error(resp, fmt.Sprintf("resource '%s' not found", identifier), http.StatusNotFound)
+		return
+	}
+	if err == k8s.ErrAmbiguousIdentifier {
+		http.Error(resp, err.Error(), http.StatusConflict)
+		return
+	}
+
+	response(rollback, 200, err, resp, req)
+}
@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/alwinius/bow/internal/k8s"
+	"github.com/alwinius/bow/internal/policy"
 	"github.com/alwinius/bow/types"
 )
 
@@ -16,7 +18,9 @@ type trackedImage struct {
 	Provider     string `json:"provider"`
 	Namespace    string `json:"namespace"`
 	Policy       string `json:"policy"`
-	Registry     string `json:"registry"`
+	// Constraint is the parsed constraint of semver:... policies
+	Constraint string `json:"constraint,omitempty"`
+	Registry   string `json:"registry"`
 }
 
 func (s *TriggerServer) trackedHandler(resp http.ResponseWriter, req *http.Request) {
@@ -25,14 +29,18 @@ func (s *TriggerServer) trackedHandler(resp http.ResponseWriter, req *http.Reque
 	var imgs []trackedImage
 
 	for _, img := range trackedImages {
-		imgs = append(imgs, trackedImage{
+		ti := trackedImage{
 			Image:        img.Image.Name(),
 			Trigger:      img.Trigger.String(),
 			PollSchedule: img.PollSchedule,
 			Provider:     img.Provider,
 			Policy:       img.Policy.Name(),
 			Registry:     img.Image.Registry(),
-		})
+		}
+		if plc, ok := img.Policy.(*policy.SemverConstraintPolicy); ok {
+			ti.Constraint = plc.Constraint()
+		}
+		imgs = append(imgs, ti)
 	}
 
 	response(&imgs, 200, err, resp, req)
@@ -85,26 +93,28 @@ func (s *TriggerServer) trackSetHandler(resp http.ResponseWriter, req *http.Requ
 		trackReq.Schedule = types.BowPollDefaultSchedule
 	}
 
-	for _, v := range s.grc.Values() {
-		if v.Identifier == trackReq.Identifier {
-
-			labels := v.GetLabels()
-			delete(labels, types.BowTriggerLabel)
-			v.SetLabels(labels)
+	v, err := k8s.FindResource(s.grc.Values(), trackReq.Identifier)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusConflict)
+		return
+	}
+	if v == nil {
+		resp.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(resp, "resource with identifier '%s' not found", trackReq.Identifier)
+		return
+	}
 
-			ann := v.GetAnnotations()
-			ann[types.BowTriggerLabel] = trackReq.Trigger
-			ann[types.BowPollScheduleAnnotation] = trackReq.Schedule
+	labels := v.GetLabels()
+	delete(labels, types.BowTriggerLabel)
+	v.SetLabels(labels)
 
-			v.SetAnnotations(ann)
+	ann := v.GetAnnotations()
+	ann[types.BowTriggerLabel] = trackReq.Trigger
+	ann[types.BowPollScheduleAnnotation] = trackReq.Schedule
 
-			//err := s.kubernetesClient.Update(v)
+	v.SetAnnotations(ann)
 
-			response(&APIResponse{Status: "updated"}, 200, err, resp, req)
-			return
-		}
-	}
+	//err := s.kubernetesClient.Update(v)
 
-	resp.WriteHeader(http.StatusNotFound)
-	fmt.Fprintf(resp, "resource with identifier '%s' not found", trackReq.Identifier)
+	response(&APIResponse{Status: "updated"}, 200, err, resp, req)
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/alwinius/bow/types"
 	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
 )
 
 // CreateAuditLog - create new audit log entry
@@ -41,12 +42,13 @@ func (s *SQLStore) GetAuditLogs(query *types.AuditLogQuery) (logs []*types.Audit
 		query.Order = "created_at desc"
 	}
 
+	db := s.filterCommit(query)
 	if len(query.ResourceKindFilter) == 1 && query.ResourceKindFilter[0] == "*" {
-		err = s.db.Order(query.Order).Limit(query.Limit).Offset(query.Offset).Find(&logs).Error
+		err = db.Order(query.Order).Limit(query.Limit).Offset(query.Offset).Find(&logs).Error
 	} else if query.Username != "" {
-		err = s.db.Order(query.Order).Where("resource_kind in (?)", query.ResourceKindFilter).Limit(query.Limit).Offset(query.Offset).Where("username = ?", query.Username).Find(&logs).Error
+		err = db.Order(query.Order).Where("resource_kind in (?)", query.ResourceKindFilter).Limit(query.Limit).Offset(query.Offset).Where("username = ?", query.Username).Find(&logs).Error
 	} else {
-		err = s.db.Order(query.Order).Where("resource_kind in (?)", query.ResourceKindFilter).Limit(query.Limit).Offset(query.Offset).Find(&logs).Error
+		err = db.Order(query.Order).Where("resource_kind in (?)", query.ResourceKindFilter).Limit(query.Limit).Offset(query.Offset).Find(&logs).Error
 	}
 
 	return logs, err
@@ -56,16 +58,33 @@ func (s *SQLStore) AuditLogsCount(query *types.AuditLogQuery) (int, error) {
 	var err error
 	var count int
 
+	db := s.filterCommit(query)
 	if len(query.ResourceKindFilter) == 1 && query.ResourceKindFilter[0] == "*" {
-		err = s.db.Model(&types.AuditLog{}).Count(&count).Error
+		err = db.Model(&types.AuditLog{}).Count(&count).Error
 	} else if query.Username != "" {
-		err = s.db.Model(&types.AuditLog{}).Where("resource_kind in (?)", query.ResourceKindFilter).Where("username = ?", query.Username).Count(&count).Error
+		err = db.Model(&types.AuditLog{}).Where("resource_kind in (?)", query.ResourceKindFilter).Where("username = ?", query.Username).Count(&count).Error
 	} else {
-		err = s.db.Model(&types.AuditLog{}).Where("resource_kind in (?)", query.ResourceKindFilter).Count(&count).Error
+		err = db.Model(&types.AuditLog{}).Where("resource_kind in (?)", query.ResourceKindFilter).Count(&count).Error
 	}
 	return count, err
 }
 
+// filterCommit - restricts the query to entries with query.Commit as commit or reverted commit,
+// abbreviated hashes match as prefix. query.Commit has to be hexadecimal, LIKE wildcards are not
+// escaped.
+func (s *SQLStore) filterCommit(query *types.AuditLogQuery) *gorm.DB {
+	if query.Commit == "" {
+		return s.db
+	}
+	if len(query.Commit) == fullCommitLength {
+		return s.db.Where("commit_hash = ? OR reverted_hash = ?", query.Commit, query.Commit)
+	}
+	return s.db.Where("commit_hash LIKE ? OR reverted_hash LIKE ?", query.Commit+"%", query.Commit+"%")
+}
+
+// fullCommitLength - length of a full SHA-1 commit hash
+const fullCommitLength = 40
+
 var logsWeeklyStats = `SELECT day, COALESCE(updates, 0) AS updates, COALESCE(approved, 0) as approved
 FROM  (SELECT ? - d AS day FROM generate_series (0, 6) d) d  -- 6, not 7
 LEFT   JOIN (
@@ -0,0 +1,82 @@
+package sql
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/alwinius/bow/types"
+)
+
+func newTestStore(t *testing.T) (*SQLStore, func()) {
+	dir, err := ioutil.TempDir("", "sqlstoretest")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	store, err := New(Opts{DatabaseType: "sqlite3", URI: filepath.Join(dir, "gorm.db")})
+	if err != nil {
+		t.Fatalf("failed to create store: %s", err)
+	}
+	return store, func() {
+		store.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestGetAuditLogsByCommit(t *testing.T) {
+	store, teardown := newTestStore(t)
+	defer teardown()
+
+	const update = "1a2b3c4d5e6f7a8b9c0d1a2b3c4d5e6f7a8b9c0d"
+	const rollback = "9f8e7d6c5b4a39281706f5e4d3c2b1a098765432"
+	entries := map[string]map[string]string{
+		"update":   {"commit": update, "branch": "master"},
+		"rollback": {"commit": rollback, "reverted": update},
+		// the hash of the update appears in the metadata, but not as commit
+		"message": {"commit": "0000000000000000000000000000000000000000", "note": `"commit":"` + update},
+		"other":   {"name": "web"},
+	}
+	for message, metadata := range entries {
+		entry := &types.AuditLog{Action: "deployment update", ResourceKind: "deployment", Message: message}
+		entry.SetMetadata(metadata)
+		if _, err := store.CreateAuditLog(entry); err != nil {
+			t.Fatalf("failed to create audit log: %s", err)
+		}
+	}
+
+	tests := []struct {
+		commit string
+		want   []string
+	}{
+		{commit: update, want: []string{"rollback", "update"}},
+		{commit: update[:7], want: []string{"rollback", "update"}},
+		{commit: rollback, want: []string{"rollback"}},
+		{commit: update[1:], want: nil},
+		{commit: "", want: []string{"message", "other", "rollback", "update"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.commit, func(t *testing.T) {
+			query := &types.AuditLogQuery{ResourceKindFilter: []string{"*"}, Order: "account", Commit: tt.commit}
+			logs, err := store.GetAuditLogs(query)
+			if err != nil {
+				t.Fatalf("failed to get audit logs: %s", err)
+			}
+			var got []string
+			for _, l := range logs {
+				got = append(got, l.Message)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetAuditLogs() = %v, want %v", got, tt.want)
+			}
+
+			count, err := store.AuditLogsCount(query)
+			if err != nil || count != len(tt.want) {
+				t.Errorf("AuditLogsCount() = %d, %v, want %d", count, err, len(tt.want))
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package sql
+
+import (
+	"fmt"
+
+	"github.com/alwinius/bow/types"
+)
+
+// SavePendingPromotion - creates or replaces the pending promotion with the same ID
+func (s *SQLStore) SavePendingPromotion(pending *types.PendingPromotion) error {
+	if pending.ID == "" {
+		return fmt.Errorf("ID not specified")
+	}
+	return s.db.Save(pending).Error
+}
+
+func (s *SQLStore) ListPendingPromotions() ([]*types.PendingPromotion, error) {
+	var pending []*types.PendingPromotion
+	err := s.db.Order("deployed asc").Find(&pending).Error
+	return pending, err
+}
+
+func (s *SQLStore) DeletePendingPromotion(id string) error {
+	if id == "" {
+		return fmt.Errorf("ID not specified")
+	}
+	return s.db.Delete(&types.PendingPromotion{ID: id}).Error
+}
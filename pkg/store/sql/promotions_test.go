@@ -0,0 +1,53 @@
+package sql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alwinius/bow/types"
+)
+
+func TestPendingPromotions(t *testing.T) {
+	store, teardown := newTestStore(t)
+	defer teardown()
+
+	deployed := time.Now().Add(-time.Hour)
+	for _, pending := range []*types.PendingPromotion{
+		{ID: "prod/web", Promotion: "prod", Repository: "web", Tag: "1.1.2", Deployed: deployed},
+		{ID: "prod/api", Promotion: "prod", Repository: "api", Tag: "2.0.0", Deployed: deployed.Add(time.Minute)},
+		// replaces the pending version of web
+		{ID: "prod/web", Promotion: "prod", Repository: "web", Tag: "1.1.3", Deployed: deployed.Add(2 * time.Minute)},
+	} {
+		err := store.SavePendingPromotion(pending)
+		if err != nil {
+			t.Fatalf("failed to save pending promotion: %s", err)
+		}
+	}
+
+	stored, err := store.ListPendingPromotions()
+	if err != nil {
+		t.Fatalf("failed to list pending promotions: %s", err)
+	}
+	if len(stored) != 2 {
+		t.Fatalf("expected 2 pending promotions, got %d", len(stored))
+	}
+	if stored[0].ID != "prod/api" || stored[1].ID != "prod/web" || stored[1].Tag != "1.1.3" {
+		t.Errorf("unexpected pending promotions: %s %s, %s %s", stored[0].ID, stored[0].Tag, stored[1].ID, stored[1].Tag)
+	}
+
+	err = store.DeletePendingPromotion("prod/web")
+	if err != nil {
+		t.Fatalf("failed to delete pending promotion: %s", err)
+	}
+	stored, err = store.ListPendingPromotions()
+	if err != nil {
+		t.Fatalf("failed to list pending promotions: %s", err)
+	}
+	if len(stored) != 1 || stored[0].ID != "prod/api" {
+		t.Errorf("expected only prod/api to be left, got %d pending promotions", len(stored))
+	}
+
+	if err := store.SavePendingPromotion(&types.PendingPromotion{Promotion: "prod"}); err == nil {
+		t.Errorf("expected error for pending promotion without ID")
+	}
+}
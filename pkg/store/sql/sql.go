@@ -34,6 +34,7 @@ func New(opts Opts) (*SQLStore, error) {
 	err = db.AutoMigrate(
 		&types.Approval{},
 		&types.AuditLog{},
+		&types.PendingPromotion{},
 	).Error
 	if err != nil {
 		log.WithFields(log.Fields{
@@ -18,6 +18,10 @@ type Store interface {
 	ListApprovals(q *types.GetApprovalQuery) ([]*types.Approval, error)
 	DeleteApproval(approval *types.Approval) error
 
+	SavePendingPromotion(pending *types.PendingPromotion) error
+	ListPendingPromotions() ([]*types.PendingPromotion, error)
+	DeletePendingPromotion(id string) error
+
 	OK() bool
 	Close() error
 }
@@ -18,21 +18,41 @@ package helm
 
 import (
 	"fmt"
+	"path/filepath"
+
+	"github.com/Masterminds/semver"
+	"github.com/ghodss/yaml"
 	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/engine"
 	"k8s.io/helm/pkg/manifest"
 	"k8s.io/helm/pkg/proto/hapi/chart"
 	"k8s.io/helm/pkg/renderutil"
+	"k8s.io/helm/pkg/strvals"
 	"k8s.io/helm/pkg/timeconv"
-	"path/filepath"
+	tversion "k8s.io/helm/pkg/version"
 )
 
-var defaultKubeVersion = fmt.Sprintf("%s.%s", chartutil.DefaultKubeVersion.Major, chartutil.DefaultKubeVersion.Minor)
+// defaultReleaseName - the release is needed to process the templates, but it does not have an effect on the images
+const defaultReleaseName = "whatever"
+
+// RenderOptions - settings a chart is rendered with, like the flags of `helm template`. Empty
+// fields fall back to the defaults.
+type RenderOptions struct {
+	ReleaseName string
+	Namespace   string
+	// Values are YAML documents merged in order on top of the chart defaults, like -f
+	Values [][]byte
+	// Set are overrides in --set format (ie: image.tag=1.2.3), applied after Values
+	Set []string
+	// KubeVersion is the Kubernetes version reported to the templates, ie: 1.14
+	KubeVersion string
+	// APIVersions are reported in .Capabilities.APIVersions in addition to v1
+	APIVersions []string
+}
 
 func ProcessTemplate(path string) ([]manifest.Manifest, error) {
 	chartPath, _ := filepath.Abs(path)
-	namespace := "default"
 	var vFiles = valueFiles{path + "/values.yaml"}
-	releaseName := "whatever" // the release is needed to process the templates, but it does not have an effect on the images
 
 	// get combined values and create config
 	rawVals, err := vals(vFiles, "", "", "")
@@ -47,27 +67,96 @@ func ProcessTemplate(path string) ([]manifest.Manifest, error) {
 		return nil, err
 	}
 
-	renderOpts := renderutil.Options{
-		ReleaseOptions: chartutil.ReleaseOptions{
-			Name:      releaseName,
-			IsInstall: true,
-			IsUpgrade: false,
-			Time:      timeconv.Now(),
-			Namespace: namespace,
-		},
-		KubeVersion: defaultKubeVersion,
+	return renderChart(c, config, RenderOptions{})
+}
+
+// ProcessChartFiles - renders the chart made of files (paths relative to the chart root), used for
+// charts that are not on disk
+func ProcessChartFiles(files []*chartutil.BufferedFile, opts RenderOptions) ([]manifest.Manifest, error) {
+	c, err := chartutil.LoadFiles(files)
+	if err != nil {
+		return nil, err
 	}
 
-	renderedTemplates, err := renderutil.Render(c, config, renderOpts)
+	base := map[string]interface{}{}
+	for i, values := range opts.Values {
+		currentMap := map[string]interface{}{}
+		if err := yaml.Unmarshal(values, &currentMap); err != nil {
+			return nil, fmt.Errorf("failed to parse values file %d: %s", i+1, err)
+		}
+		base = mergeValues(base, currentMap)
+	}
+	for _, value := range opts.Set {
+		if err := strvals.ParseInto(value, base); err != nil {
+			return nil, fmt.Errorf("failed to parse set value '%s': %s", value, err)
+		}
+	}
+	rawVals, err := yaml.Marshal(base)
 	if err != nil {
 		return nil, err
 	}
+	config := &chart.Config{Raw: string(rawVals), Values: map[string]*chart.Value{}}
 
-	listManifests := manifest.SplitManifests(renderedTemplates)
-	var manifestsToRender []manifest.Manifest
+	return renderChart(c, config, opts)
+}
 
-	// render all manifests in the chart
-	manifestsToRender = listManifests
+// renderChart - same as renderutil.Render, but with additional API versions in the capabilities
+func renderChart(c *chart.Chart, config *chart.Config, opts RenderOptions) ([]manifest.Manifest, error) {
+	releaseOpts := chartutil.ReleaseOptions{
+		Name:      defaultReleaseName,
+		IsInstall: true,
+		IsUpgrade: false,
+		Time:      timeconv.Now(),
+		Namespace: "default",
+	}
+	if opts.ReleaseName != "" {
+		releaseOpts.Name = opts.ReleaseName
+	}
+	if opts.Namespace != "" {
+		releaseOpts.Namespace = opts.Namespace
+	}
+
+	if req, err := chartutil.LoadRequirements(c); err == nil {
+		if err := renderutil.CheckDependencies(c, req); err != nil {
+			return nil, err
+		}
+	} else if err != chartutil.ErrRequirementsNotFound {
+		return nil, fmt.Errorf("cannot load requirements: %v", err)
+	}
+	err := chartutil.ProcessRequirementsEnabled(c, config)
+	if err != nil {
+		return nil, err
+	}
+	err = chartutil.ProcessRequirementsImportValues(c)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeVersion := *chartutil.DefaultKubeVersion
+	if opts.KubeVersion != "" {
+		kv, err := semver.NewVersion(opts.KubeVersion)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse a kubernetes version: %v", err)
+		}
+		kubeVersion.Major = fmt.Sprint(kv.Major())
+		kubeVersion.Minor = fmt.Sprint(kv.Minor())
+		kubeVersion.GitVersion = fmt.Sprintf("v%d.%d.0", kv.Major(), kv.Minor())
+	}
+	caps := &chartutil.Capabilities{
+		APIVersions:   chartutil.NewVersionSet(append([]string{"v1"}, opts.APIVersions...)...),
+		KubeVersion:   &kubeVersion,
+		TillerVersion: tversion.GetVersionProto(),
+	}
 
-	return manifestsToRender, nil
+	vals, err := chartutil.ToRenderValuesCaps(c, config, releaseOpts, caps)
+	if err != nil {
+		return nil, err
+	}
+	renderedTemplates, err := engine.New().Render(c, vals)
+	if err != nil {
+		return nil, err
+	}
+
+	// render all manifests in the chart
+	return manifest.SplitManifests(renderedTemplates), nil
 }
@@ -76,7 +76,7 @@ func checkRelease(repo *types.Repository, namespace, name string, chart *hapi_ch
 			continue
 		}
 
-		shouldUpdate, err := bowCfg.Plc.ShouldUpdate(imageRef.Tag(), eventRepoRef.Tag())
+		shouldUpdate, err := policy.ShouldUpdate(bowCfg.Plc, imageRef.Tag(), eventRepoRef.Tag(), repo.Metadata)
 		if err != nil {
 			log.WithFields(log.Fields{
 				"error":           err,
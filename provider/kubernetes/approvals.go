@@ -2,9 +2,12 @@ package kubernetes
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/alwinius/bow/internal/gitrepo"
 	"github.com/alwinius/bow/pkg/store"
 	"github.com/alwinius/bow/types"
 
@@ -40,6 +43,17 @@ func (p *Provider) updateComplete(plan *UpdatePlan) error {
 	return p.approvalManager.Archive(getApprovalIdentifier(plan.Resource.Identifier, plan.NewVersion))
 }
 
+// getApprovers - voters of the approval for plan, if approvals were required
+func (p *Provider) getApprovers(plan *UpdatePlan) []string {
+	approval, err := p.approvalManager.Get(getApprovalIdentifier(plan.Resource.Identifier, plan.NewVersion))
+	if err != nil {
+		return nil
+	}
+	approvers := approval.GetVoters()
+	sort.Strings(approvers)
+	return approvers
+}
+
 func getInt(key string, labels map[string]string, annotations map[string]string) (int, error) {
 
 	var (
@@ -116,6 +130,7 @@ func (p *Provider) isApproved(event *types.Event, plan *UpdatePlan) (bool, error
 				VotesReceived:  0,
 				Rejected:       false,
 				Deadline:       time.Now().Add(time.Duration(deadline) * time.Hour),
+				Diff:           p.plansDiff([]*UpdatePlan{plan}),
 			}
 
 			approval.Message = fmt.Sprintf("New image is available for resource %s/%s (%s).",
@@ -142,5 +157,44 @@ func (p *Provider) isApproved(event *types.Event, plan *UpdatePlan) (bool, error
 	// 	"new":      event.Repository.Digest,
 	// }).Info("digests match")
 
+	// votes were given for a different change, ie: the branch was rebased in between
+	if diff := p.plansDiff([]*UpdatePlan{plan}); diff != existing.Diff {
+		log.WithFields(log.Fields{
+			"name":      plan.Resource.Name,
+			"namespace": plan.Resource.Namespace,
+			"approval":  identifier,
+		}).Info("provider.kubernetes: diff of approved change changed, resetting votes")
+		existing.Diff = diff
+		return false, p.approvalManager.Reset(existing)
+	}
+
 	return existing.Status() == types.ApprovalStatusApproved, nil
 }
+
+// plansDiff - unified diff of the repository files plans would change, empty if they cannot
+// be written. The diff is taken from the checkout as last refreshed by the watcher, nothing is
+// pulled.
+func (p *Provider) plansDiff(plans []*UpdatePlan) string {
+	var repos []*gitrepo.Repo
+	byRepo := make(map[*gitrepo.Repo][]*UpdatePlan)
+	for _, plan := range plans {
+		repo, err := p.getRepo(plan.Resource)
+		if err != nil {
+			continue
+		}
+		if _, ok := byRepo[repo]; !ok {
+			repos = append(repos, repo)
+		}
+		byRepo[repo] = append(byRepo[repo], plan)
+	}
+
+	var diff strings.Builder
+	for _, repo := range repos {
+		changes, _ := p.changesFor(byRepo[repo])
+		planned, _ := repo.PlanCheckout(changes)
+		for _, c := range planned {
+			diff.WriteString(c.Diff)
+		}
+	}
+	return diff.String()
+}
@@ -1,6 +1,9 @@
 package kubernetes
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,17 +16,6 @@ import (
 )
 
 func TestCheckRequestedApproval(t *testing.T) {
-	fp := &fakeImplementer{}
-	fp.namespaces = &v1.NamespaceList{
-		Items: []v1.Namespace{
-			v1.Namespace{
-				meta_v1.TypeMeta{},
-				meta_v1.ObjectMeta{Name: "xxxx"},
-				v1.NamespaceSpec{},
-				v1.NamespaceStatus{},
-			},
-		},
-	}
 	deployments := []*apps_v1.Deployment{
 		{
 			meta_v1.TypeMeta{},
@@ -52,8 +44,9 @@ func TestCheckRequestedApproval(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	approver := approver()
-	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeSender{}, approver, grc)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -84,17 +77,6 @@ func TestCheckRequestedApproval(t *testing.T) {
 }
 
 func TestCheckRequestedApprovalAnnotation(t *testing.T) {
-	fp := &fakeImplementer{}
-	fp.namespaces = &v1.NamespaceList{
-		Items: []v1.Namespace{
-			v1.Namespace{
-				meta_v1.TypeMeta{},
-				meta_v1.ObjectMeta{Name: "xxxx"},
-				v1.NamespaceSpec{},
-				v1.NamespaceStatus{},
-			},
-		},
-	}
 	deployments := []*apps_v1.Deployment{
 		{
 			meta_v1.TypeMeta{},
@@ -127,8 +109,9 @@ func TestCheckRequestedApprovalAnnotation(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	approver := approver()
-	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeSender{}, approver, grc)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -166,17 +149,6 @@ func TestCheckRequestedApprovalAnnotation(t *testing.T) {
 }
 
 func TestApprovedCheck(t *testing.T) {
-	fp := &fakeImplementer{}
-	fp.namespaces = &v1.NamespaceList{
-		Items: []v1.Namespace{
-			v1.Namespace{
-				meta_v1.TypeMeta{},
-				meta_v1.ObjectMeta{Name: "xxxx"},
-				v1.NamespaceSpec{},
-				v1.NamespaceStatus{},
-			},
-		},
-	}
 	deployments := []*apps_v1.Deployment{
 		{
 			meta_v1.TypeMeta{},
@@ -184,7 +156,7 @@ func TestApprovedCheck(t *testing.T) {
 				Name:        "dep-1",
 				Namespace:   "xxxx",
 				Labels:      map[string]string{types.BowPolicyLabel: "all", types.BowMinimumApprovalsLabel: "1"},
-				Annotations: map[string]string{},
+				Annotations: map[string]string{types.BowSourceFilesAnnotation: "deployment.yaml"},
 			},
 			apps_v1.DeploymentSpec{
 				Template: v1.PodTemplateSpec{
@@ -204,38 +176,37 @@ func TestApprovedCheck(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	approver := approver()
-	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	gitRepo, remote := newTestRepo(t, map[string]string{"deployment.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: dep-1\nspec:\n  template:\n    spec:\n      containers:\n      - image: gcr.io/v2-namespace/hello-world:1.1.1\n"})
+	defer os.RemoveAll(filepath.Dir(remote))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeSender{}, approver, grc, gitRepo)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
 
-	// approving event
-	err = provider.approvalManager.Create(&types.Approval{
-		Identifier:    "deployment/xxxx/dep-1:1.1.2",
-		VotesReceived: 2,
-		VotesRequired: 2,
-		Deadline:      time.Now().Add(10 * time.Second),
-	})
+	// creating "new version" event, requests the approval
+	repo := types.Repository{
+		Name: "gcr.io/v2-namespace/hello-world",
+		Tag:  "1.1.2",
+	}
+
+	_, err = provider.processEvent(&types.Event{Repository: repo})
 	if err != nil {
-		t.Fatalf("failed to create approval: %s", err)
+		t.Fatalf("failed to process event: %s", err)
 	}
 
-	appr, err := provider.approvalManager.Get("deployment/xxxx/dep-1:1.1.2")
+	// approving event
+	appr, err := provider.approvalManager.Approve("deployment/xxxx/dep-1:1.1.2", "bob")
 	if err != nil {
-		t.Fatalf("failed to get approval: %s", err)
+		t.Fatalf("failed to approve: %s", err)
 	}
 	if appr.Status() != types.ApprovalStatusApproved {
 		t.Fatalf("approval not approved")
 	}
 
-	// creating "new version" event
-	repo := types.Repository{
-		Name: "gcr.io/v2-namespace/hello-world",
-		Tag:  "1.1.2",
-	}
-
-	deps, err := provider.processEvent(&types.Event{Repository: repo})
+	deps, err := provider.processEvent(&types.Event{Repository: repo, TriggerName: types.TriggerTypeApproval.String()})
 	if err != nil {
 		t.Errorf("failed to get deployments: %s", err)
 	}
@@ -246,17 +217,6 @@ func TestApprovedCheck(t *testing.T) {
 }
 
 func TestApprovalsCleanup(t *testing.T) {
-	fp := &fakeImplementer{}
-	fp.namespaces = &v1.NamespaceList{
-		Items: []v1.Namespace{
-			v1.Namespace{
-				meta_v1.TypeMeta{},
-				meta_v1.ObjectMeta{Name: "xxxx"},
-				v1.NamespaceSpec{},
-				v1.NamespaceStatus{},
-			},
-		},
-	}
 	deployments := []*apps_v1.Deployment{
 		{
 			meta_v1.TypeMeta{},
@@ -264,7 +224,7 @@ func TestApprovalsCleanup(t *testing.T) {
 				Name:        "dep-1",
 				Namespace:   "xxxx",
 				Labels:      map[string]string{types.BowPolicyLabel: "all", types.BowMinimumApprovalsLabel: "1"},
-				Annotations: map[string]string{},
+				Annotations: map[string]string{types.BowSourceFilesAnnotation: "deployment.yaml"},
 			},
 			apps_v1.DeploymentSpec{
 				Template: v1.PodTemplateSpec{
@@ -284,38 +244,37 @@ func TestApprovalsCleanup(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	approver := approver()
-	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	gitRepo, remote := newTestRepo(t, map[string]string{"deployment.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: dep-1\nspec:\n  template:\n    spec:\n      containers:\n      - image: gcr.io/v2-namespace/hello-world:1.1.1\n"})
+	defer os.RemoveAll(filepath.Dir(remote))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeSender{}, approver, grc, gitRepo)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
 
-	// approving event
-	err = provider.approvalManager.Create(&types.Approval{
-		Identifier:    "deployment/xxxx/dep-1:1.1.2",
-		VotesReceived: 2,
-		VotesRequired: 2,
-		Deadline:      time.Now().Add(10 * time.Second),
-	})
+	// creating "new version" event, requests the approval
+	repo := types.Repository{
+		Name: "gcr.io/v2-namespace/hello-world",
+		Tag:  "1.1.2",
+	}
+
+	_, err = provider.processEvent(&types.Event{Repository: repo})
 	if err != nil {
-		t.Fatalf("failed to create approval: %s", err)
+		t.Fatalf("failed to process event: %s", err)
 	}
 
-	appr, err := provider.approvalManager.Get("deployment/xxxx/dep-1:1.1.2")
+	// approving event
+	appr, err := provider.approvalManager.Approve("deployment/xxxx/dep-1:1.1.2", "bob")
 	if err != nil {
-		t.Fatalf("failed to get approval: %s", err)
+		t.Fatalf("failed to approve: %s", err)
 	}
 	if appr.Status() != types.ApprovalStatusApproved {
 		t.Fatalf("approval not approved")
 	}
 
-	// creating "new version" event
-	repo := types.Repository{
-		Name: "gcr.io/v2-namespace/hello-world",
-		Tag:  "1.1.2",
-	}
-
-	deps, err := provider.processEvent(&types.Event{Repository: repo})
+	deps, err := provider.processEvent(&types.Event{Repository: repo, TriggerName: types.TriggerTypeApproval.String()})
 	if err != nil {
 		t.Errorf("failed to get deployments: %s", err)
 	}
@@ -324,14 +283,94 @@ func TestApprovalsCleanup(t *testing.T) {
 		t.Errorf("expected to find 1 updated deployment but found %d", len(deps))
 	}
 
-	// no approvals expected
+	// no pending approvals expected, the fulfilled one is archived
 
 	approvals, err := provider.approvalManager.List()
 	if err != nil {
 		t.Fatalf("failed to get a list of approvals: %s", err)
 	}
 
-	if len(approvals) != 0 {
-		t.Errorf("expected to find 0 but found %d", len(approvals))
+	pending := 0
+	for _, approval := range approvals {
+		if !approval.Archived {
+			pending++
+		}
+	}
+	if pending != 0 {
+		t.Errorf("expected to find 0 but found %d", pending)
+	}
+}
+
+func TestApprovalVotesResetWhenDiffChanges(t *testing.T) {
+	// both images are close enough for the diff of one to show the other as context
+	gitRepo, remote := newTestRepo(t, map[string]string{
+		"images.yaml": "web:\n  image: gcr.io/v2-namespace/web:1.1.1\napi:\n  image: gcr.io/v2-namespace/api:1.1.1\n",
+	})
+	defer os.RemoveAll(filepath.Dir(remote))
+
+	web := sourcedDeployment("web", "gcr.io/v2-namespace/web:1.1.1", "images.yaml")
+	labels := web.GetLabels()
+	labels[types.BowMinimumApprovalsLabel] = "2"
+	web.SetLabels(labels)
+	api := sourcedDeployment("api", "gcr.io/v2-namespace/api:1.1.1", "images.yaml")
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(web, api)
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeSender{}, approver, grc, gitRepo)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	webEvent := &types.Event{Repository: types.Repository{Name: "gcr.io/v2-namespace/web", Tag: "1.1.2"}}
+	identifier := getApprovalIdentifier(web.Identifier, "1.1.2")
+
+	_, err = provider.processEvent(webEvent)
+	if err != nil {
+		t.Fatalf("failed to process event: %s", err)
+	}
+	_, err = approver.Approve(identifier, "alice")
+	if err != nil {
+		t.Fatalf("failed to approve: %s", err)
+	}
+
+	// same diff, the vote is kept
+	_, err = provider.processEvent(webEvent)
+	if err != nil {
+		t.Fatalf("failed to process event: %s", err)
+	}
+	approval, err := approver.Get(identifier)
+	if err != nil {
+		t.Fatalf("failed to get approval: %s", err)
+	}
+	if approval.VotesReceived != 1 {
+		t.Errorf("expected vote to be kept, got %d votes", approval.VotesReceived)
+	}
+	if !strings.Contains(approval.Diff, "+  image: gcr.io/v2-namespace/web:1.1.2") {
+		t.Errorf("expected diff with the new image, got: %s", approval.Diff)
+	}
+	previous := approval.Diff
+
+	// api is updated without approvals, the context of the web diff changes
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{Name: "gcr.io/v2-namespace/api", Tag: "1.2.0"}})
+	if err != nil {
+		t.Fatalf("failed to process event: %s", err)
+	}
+
+	_, err = provider.processEvent(webEvent)
+	if err != nil {
+		t.Fatalf("failed to process event: %s", err)
+	}
+	approval, err = approver.Get(identifier)
+	if err != nil {
+		t.Fatalf("failed to get approval: %s", err)
+	}
+	if approval.VotesReceived != 0 {
+		t.Errorf("expected votes to be reset, got %d votes", approval.VotesReceived)
+	}
+	if approval.Diff == previous || !strings.Contains(approval.Diff, "gcr.io/v2-namespace/api:1.2.0") {
+		t.Errorf("expected diff against the updated api, got: %s", approval.Diff)
 	}
 }
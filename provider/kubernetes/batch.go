@@ -0,0 +1,72 @@
+package kubernetes
+
+import (
+	"time"
+
+	"github.com/alwinius/bow/internal/gitrepo"
+	"github.com/alwinius/bow/internal/k8s"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// batch - approved plans of a repository waiting to be committed together
+type batch struct {
+	repo    *gitrepo.Repo
+	plans   []*UpdatePlan
+	started time.Time
+}
+
+// queue - adds plan to the pending batch of repo, the first plan of a batch starts its window
+func (p *Provider) queue(repo *gitrepo.Repo, plan *UpdatePlan) {
+	b, ok := p.batches[repo.Name]
+	if !ok {
+		b = &batch{repo: repo, started: time.Now()}
+		p.batches[repo.Name] = b
+		time.AfterFunc(repo.BatchWindow, func() {
+			select {
+			case p.flushes <- repo.Name:
+			case <-p.stop:
+			}
+		})
+	}
+
+	// a newer version for an already queued update replaces it
+	for i, queued := range b.plans {
+		if queued.Resource.Identifier == plan.Resource.Identifier && queued.CurrentVersion == plan.CurrentVersion {
+			b.plans[i] = plan
+			return
+		}
+	}
+	b.plans = append(b.plans, plan)
+
+	log.WithFields(log.Fields{
+		"repo":    repo.Name,
+		"name":    plan.Resource.Name,
+		"kind":    plan.Resource.Kind(),
+		"update":  plan.String(),
+		"pending": len(b.plans),
+	}).Debug("provider.kubernetes: update queued for batch commit")
+}
+
+// flush - commits the pending batch of a repository
+func (p *Provider) flush(name string) []*k8s.GenericResource {
+	b, ok := p.batches[name]
+	if !ok {
+		return nil
+	}
+	delete(p.batches, name)
+
+	log.WithFields(log.Fields{
+		"repo":    name,
+		"updates": len(b.plans),
+		"waited":  time.Since(b.started).String(),
+	}).Info("provider.kubernetes: committing batched updates")
+	return p.applyPlans(b.repo, b.plans)
+}
+
+// flushAll - commits all pending batches, ie: on shutdown
+func (p *Provider) flushAll() {
+	for name := range p.batches {
+		p.flush(name)
+	}
+}
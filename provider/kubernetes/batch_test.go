@@ -0,0 +1,139 @@
+package kubernetes
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alwinius/bow/internal/k8s"
+	"github.com/alwinius/bow/types"
+)
+
+func TestBatchReplacesQueuedUpdate(t *testing.T) {
+	gitRepo, remote := newTestRepo(t, map[string]string{
+		"web.yaml": deploymentManifest("web", "gcr.io/v2-namespace/web:1.1.1"),
+		"api.yaml": deploymentManifest("api", "gcr.io/v2-namespace/api:1.1.1"),
+	})
+	defer os.RemoveAll(filepath.Dir(remote))
+	// the window does not elapse during the test
+	gitRepo.BatchWindow = time.Hour
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(
+		sourcedDeployment("web", "gcr.io/v2-namespace/web:1.1.1", "web.yaml"),
+		sourcedDeployment("api", "gcr.io/v2-namespace/api:1.1.1", "api.yaml"),
+	)
+
+	am, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeSender{}, am, grc, gitRepo)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+	defer provider.Stop()
+
+	for _, repo := range []types.Repository{
+		{Name: "gcr.io/v2-namespace/web", Tag: "1.1.2"},
+		{Name: "gcr.io/v2-namespace/api", Tag: "1.2.0"},
+		{Name: "gcr.io/v2-namespace/web", Tag: "1.1.3"},
+	} {
+		updated, err := provider.processEvent(&types.Event{Repository: repo})
+		if err != nil {
+			t.Fatalf("failed to process event: %s", err)
+		}
+		if len(updated) != 0 {
+			t.Errorf("expected update to be queued, got %d updated resources", len(updated))
+		}
+	}
+
+	b, ok := provider.batches[gitRepo.Name]
+	if !ok {
+		t.Fatalf("expected a pending batch for %s", gitRepo.Name)
+	}
+	if len(b.plans) != 2 {
+		t.Fatalf("expected 2 queued updates, got %d", len(b.plans))
+	}
+	// the newer version of web replaces the queued one in place
+	if b.plans[0].Resource.Name != "web" || b.plans[0].NewVersion != "1.1.3" {
+		t.Errorf("expected web 1.1.1->1.1.3 to be queued first, got %s", b.plans[0])
+	}
+	if b.plans[1].Resource.Name != "api" || b.plans[1].NewVersion != "1.2.0" {
+		t.Errorf("expected api 1.1.1->1.2.0 to be queued second, got %s", b.plans[1])
+	}
+
+	if count := strings.TrimSpace(runGit(t, remote, "rev-list", "--count", "master")); count != "1" {
+		t.Errorf("expected nothing to be committed before the flush, got %s commits", count)
+	}
+}
+
+func TestBatchFlushAfterWindow(t *testing.T) {
+	gitRepo, remote := newTestRepo(t, map[string]string{
+		"web.yaml": deploymentManifest("web", "gcr.io/v2-namespace/web:1.1.1"),
+		"api.yaml": deploymentManifest("api", "gcr.io/v2-namespace/api:1.1.1"),
+	})
+	defer os.RemoveAll(filepath.Dir(remote))
+	gitRepo.BatchWindow = 50 * time.Millisecond
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(
+		sourcedDeployment("web", "gcr.io/v2-namespace/web:1.1.1", "web.yaml"),
+		sourcedDeployment("api", "gcr.io/v2-namespace/api:1.1.1", "api.yaml"),
+	)
+
+	am, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeSender{}, am, grc, gitRepo)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+	defer provider.Stop()
+
+	start := time.Now()
+	for _, repo := range []types.Repository{
+		{Name: "gcr.io/v2-namespace/web", Tag: "1.1.2"},
+		{Name: "gcr.io/v2-namespace/api", Tag: "1.2.0"},
+	} {
+		_, err := provider.processEvent(&types.Event{Repository: repo})
+		if err != nil {
+			t.Fatalf("failed to process event: %s", err)
+		}
+	}
+
+	// only the first update of the batch starts the window
+	select {
+	case name := <-provider.flushes:
+		if name != gitRepo.Name {
+			t.Errorf("expected flush of %s, got %s", gitRepo.Name, name)
+		}
+		if waited := time.Since(start); waited < gitRepo.BatchWindow {
+			t.Errorf("expected flush after the batch window, got it after %s", waited)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("batch was not flushed")
+	}
+	select {
+	case name := <-provider.flushes:
+		t.Errorf("expected a single flush, got another one of %s", name)
+	case <-time.After(4 * gitRepo.BatchWindow):
+	}
+
+	updated := provider.flush(gitRepo.Name)
+	if len(updated) != 2 {
+		t.Fatalf("expected 2 updated resources, got %d", len(updated))
+	}
+	if _, ok := provider.batches[gitRepo.Name]; ok {
+		t.Errorf("expected batch to be removed after the flush")
+	}
+
+	if count := strings.TrimSpace(runGit(t, remote, "rev-list", "--count", "master")); count != "2" {
+		t.Errorf("expected both updates in a single commit, got %s commits", count)
+	}
+	if web := remoteFile(t, remote, "web.yaml"); !strings.Contains(web, "gcr.io/v2-namespace/web:1.1.2") {
+		t.Errorf("expected web to be updated, got: %s", web)
+	}
+	if api := remoteFile(t, remote, "api.yaml"); !strings.Contains(api, "gcr.io/v2-namespace/api:1.2.0") {
+		t.Errorf("expected api to be updated, got: %s", api)
+	}
+}
@@ -0,0 +1,126 @@
+package kubernetes
+
+import (
+	"sort"
+	"time"
+
+	"github.com/alwinius/bow/internal/gitrepo"
+	"github.com/alwinius/bow/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SetDryRun - plans every update without committing it, otherwise only resources with the
+// bow/dry-run annotation are planned. Must be called before Start.
+func (p *Provider) SetDryRun(enabled bool) {
+	p.dryRun = enabled
+}
+
+func (p *Provider) isDryRun(plan *UpdatePlan) bool {
+	return p.dryRun || plan.Resource.GetAnnotations()[types.BowDryRunAnnotation] == "true"
+}
+
+// Plans - updates found for dry run resources, the latest per resource and image
+func (p *Provider) Plans() []*types.PlannedUpdate {
+	p.plansLock.RLock()
+	defer p.plansLock.RUnlock()
+
+	plans := make([]*types.PlannedUpdate, 0, len(p.plans))
+	for _, plan := range p.plans {
+		plans = append(plans, plan)
+	}
+	sort.Slice(plans, func(i, j int) bool {
+		if plans[i].Identifier != plans[j].Identifier {
+			return plans[i].Identifier < plans[j].Identifier
+		}
+		return plans[i].Image < plans[j].Image
+	})
+	return plans
+}
+
+// planDryRun - edits the sources of dry run plans in a scratch copy of their repository and
+// keeps the result for Plans, the plans to commit are returned. Dry run plans skip approvals.
+func (p *Provider) planDryRun(plans []*UpdatePlan) (remaining []*UpdatePlan) {
+	var repos []*gitrepo.Repo
+	byRepo := make(map[*gitrepo.Repo][]*UpdatePlan)
+	for _, plan := range plans {
+		if !p.isDryRun(plan) {
+			remaining = append(remaining, plan)
+			continue
+		}
+		if plan.CurrentVersion == plan.NewVersion {
+			continue
+		}
+		repo, err := p.getRepo(plan.Resource)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":      err,
+				"deployment": plan.Resource.Name,
+				"kind":       plan.Resource.Kind(),
+			}).Error("provider.kubernetes: failed to find repository for resource")
+			continue
+		}
+		if _, ok := byRepo[repo]; !ok {
+			repos = append(repos, repo)
+		}
+		byRepo[repo] = append(byRepo[repo], plan)
+	}
+
+	for _, repo := range repos {
+		changes, planOf := p.changesFor(byRepo[repo])
+		planned, failed := repo.Plan(changes)
+		for _, c := range planned {
+			p.storePlan(repo, planOf[c.Change], c.Change, c.Files, c.Diff, nil)
+		}
+		for change, err := range failed {
+			p.storePlan(repo, planOf[change], change, nil, "", err)
+		}
+	}
+	return remaining
+}
+
+func (p *Provider) storePlan(repo *gitrepo.Repo, plan *UpdatePlan, change *gitrepo.Change, files []string, diff string, err error) {
+	resource := plan.Resource
+	planned := &types.PlannedUpdate{
+		Provider:       p.GetName(),
+		Repository:     repo.Name,
+		Identifier:     resource.Identifier,
+		ResourceKind:   resource.Kind(),
+		Namespace:      resource.Namespace,
+		Name:           resource.Name,
+		Image:          change.Image,
+		CurrentVersion: plan.CurrentVersion,
+		NewVersion:     plan.NewVersion,
+		Trigger:        plan.Trigger,
+		Files:          files,
+		Diff:           diff,
+		CreatedAt:      time.Now(),
+	}
+	if err != nil {
+		planned.Error = err.Error()
+	}
+
+	log.WithFields(log.Fields{
+		"name":      resource.Name,
+		"kind":      resource.Kind(),
+		"namespace": resource.Namespace,
+		"update":    plan.String(),
+		"files":     files,
+		"error":     err,
+	}).Info("provider.kubernetes: dry run, update planned")
+
+	p.plansLock.Lock()
+	p.plans[resource.Identifier+" "+change.Repository] = planned
+	p.plansLock.Unlock()
+}
+
+// forgetPlans - drops the plans of a resource that was updated
+func (p *Provider) forgetPlans(identifier string) {
+	p.plansLock.Lock()
+	defer p.plansLock.Unlock()
+	for key, plan := range p.plans {
+		if plan.Identifier == identifier {
+			delete(p.plans, key)
+		}
+	}
+}
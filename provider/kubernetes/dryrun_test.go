@@ -0,0 +1,145 @@
+package kubernetes
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alwinius/bow/internal/k8s"
+	"github.com/alwinius/bow/types"
+)
+
+func TestDryRunAnnotation(t *testing.T) {
+	gitRepo, remote := newTestRepo(t, map[string]string{
+		"web.yaml": deploymentManifest("web", "gcr.io/v2-namespace/web:1.1.1"),
+		"api.yaml": deploymentManifest("api", "gcr.io/v2-namespace/api:1.1.1"),
+	})
+	defer os.RemoveAll(filepath.Dir(remote))
+
+	web := sourcedDeployment("web", "gcr.io/v2-namespace/web:1.1.1", "web.yaml")
+	annotations := web.GetAnnotations()
+	annotations[types.BowDryRunAnnotation] = "true"
+	web.SetAnnotations(annotations)
+	api := sourcedDeployment("api", "gcr.io/v2-namespace/api:1.1.1", "api.yaml")
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(web, api)
+
+	am, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeSender{}, am, grc, gitRepo)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	for _, repo := range []types.Repository{
+		{Name: "gcr.io/v2-namespace/web", Tag: "1.1.2"},
+		{Name: "gcr.io/v2-namespace/api", Tag: "1.2.0"},
+	} {
+		_, err := provider.processEvent(&types.Event{Repository: repo})
+		if err != nil {
+			t.Fatalf("failed to process event: %s", err)
+		}
+	}
+
+	if content := remoteFile(t, remote, "web.yaml"); !strings.Contains(content, "gcr.io/v2-namespace/web:1.1.1") {
+		t.Errorf("expected dry run resource to be left alone, got: %s", content)
+	}
+	if content := remoteFile(t, remote, "api.yaml"); !strings.Contains(content, "gcr.io/v2-namespace/api:1.2.0") {
+		t.Errorf("expected api to be updated, got: %s", content)
+	}
+
+	plans := provider.Plans()
+	if len(plans) != 1 {
+		t.Fatalf("expected 1 planned update, got %d", len(plans))
+	}
+	plan := plans[0]
+	if plan.Identifier != web.Identifier || plan.CurrentVersion != "1.1.1" || plan.NewVersion != "1.1.2" {
+		t.Errorf("unexpected plan: %s %s->%s", plan.Identifier, plan.CurrentVersion, plan.NewVersion)
+	}
+	if len(plan.Files) != 1 || plan.Files[0] != "web.yaml" {
+		t.Errorf("expected web.yaml to be changed, got: %v", plan.Files)
+	}
+	if !strings.Contains(plan.Diff, "+        image: gcr.io/v2-namespace/web:1.1.2") {
+		t.Errorf("expected diff with the new image, got: %s", plan.Diff)
+	}
+}
+
+func TestDryRunSkipsApprovals(t *testing.T) {
+	gitRepo, remote := newTestRepo(t, map[string]string{
+		"web.yaml": deploymentManifest("web", "gcr.io/v2-namespace/web:1.1.1"),
+	})
+	defer os.RemoveAll(filepath.Dir(remote))
+
+	web := sourcedDeployment("web", "gcr.io/v2-namespace/web:1.1.1", "web.yaml")
+	labels := web.GetLabels()
+	labels[types.BowMinimumApprovalsLabel] = "1"
+	web.SetLabels(labels)
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(web)
+
+	am, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeSender{}, am, grc, gitRepo)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+	provider.SetDryRun(true)
+
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{Name: "gcr.io/v2-namespace/web", Tag: "1.1.2"}})
+	if err != nil {
+		t.Fatalf("failed to process event: %s", err)
+	}
+
+	if len(provider.Plans()) != 1 {
+		t.Errorf("expected 1 planned update, got %d", len(provider.Plans()))
+	}
+	approvals, err := am.List()
+	if err != nil {
+		t.Fatalf("failed to list approvals: %s", err)
+	}
+	if len(approvals) != 0 {
+		t.Errorf("expected no approval to be requested, got %d", len(approvals))
+	}
+	if count := strings.TrimSpace(runGit(t, remote, "rev-list", "--count", "master")); count != "1" {
+		t.Errorf("expected nothing to be committed, got %s commits", count)
+	}
+}
+
+func TestForgetPlansAfterUpdate(t *testing.T) {
+	gitRepo, remote := newTestRepo(t, map[string]string{
+		"web.yaml": deploymentManifest("web", "gcr.io/v2-namespace/web:1.1.1"),
+	})
+	defer os.RemoveAll(filepath.Dir(remote))
+
+	web := sourcedDeployment("web", "gcr.io/v2-namespace/web:1.1.1", "web.yaml")
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(web)
+
+	am, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeSender{}, am, grc, gitRepo)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	provider.SetDryRun(true)
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{Name: "gcr.io/v2-namespace/web", Tag: "1.1.2"}})
+	if err != nil {
+		t.Fatalf("failed to process event: %s", err)
+	}
+	if len(provider.Plans()) != 1 {
+		t.Fatalf("expected 1 planned update, got %d", len(provider.Plans()))
+	}
+
+	provider.SetDryRun(false)
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{Name: "gcr.io/v2-namespace/web", Tag: "1.1.2"}})
+	if err != nil {
+		t.Fatalf("failed to process event: %s", err)
+	}
+	if len(provider.Plans()) != 0 {
+		t.Errorf("expected plans of the updated resource to be dropped, got %d", len(provider.Plans()))
+	}
+}
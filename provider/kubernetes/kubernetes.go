@@ -5,6 +5,7 @@ import (
 	"github.com/alwinius/bow/internal/gitrepo"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Masterminds/semver"
@@ -15,7 +16,7 @@ import (
 	"github.com/alwinius/bow/approvals"
 	"github.com/alwinius/bow/extension/notification"
 	"github.com/alwinius/bow/internal/k8s"
-	"github.com/alwinius/bow/internal/policy"
+	"github.com/alwinius/bow/pkg/store"
 	"github.com/alwinius/bow/types"
 	"github.com/alwinius/bow/util/image"
 	"github.com/alwinius/bow/util/policies"
@@ -69,6 +70,11 @@ type UpdatePlan struct {
 	CurrentVersion string
 	// New version that's already in the deployment
 	NewVersion string
+	// Trigger is the name of the trigger that produced the new version
+	Trigger string
+	// Containers whose images are updated, containers of other images or with other
+	// policies keep their versions
+	Containers []string
 }
 
 func (p *UpdatePlan) String() string {
@@ -80,7 +86,8 @@ func (p *UpdatePlan) String() string {
 
 // Provider - kubernetes provider for auto update
 type Provider struct {
-	repo gitrepo.Repo
+	// watched repositories by name
+	repos map[string]*gitrepo.Repo
 
 	sender notification.Sender
 
@@ -88,22 +95,77 @@ type Provider struct {
 
 	cache GenericResourceCache
 
+	// pending batches by repository name, only touched by the provider goroutine
+	batches map[string]*batch
+	flushes chan string
+
+	// promotion pipelines and their pending versions by pipeline and image, only touched by
+	// the provider goroutine
+	promotions []*gitrepo.Promotion
+	pending    map[string]*pendingPromotion
+	// store keeps pending promotions across restarts, optional
+	store store.Store
+
+	// dry run plans by resource and image, read by the API
+	dryRun    bool
+	plans     map[string]*types.PlannedUpdate
+	plansLock sync.RWMutex
+
+	// versions resources were rolled back from by resource identifier and tag
+	blocked     map[string]map[string]bool
+	blockedLock sync.RWMutex
+
 	events chan *types.Event
 	stop   chan struct{}
 }
 
 // NewProvider - create new kubernetes based provider
-func NewProvider(sender notification.Sender, approvalManager approvals.Manager, cache GenericResourceCache, repo gitrepo.Repo) (*Provider, error) {
+func NewProvider(sender notification.Sender, approvalManager approvals.Manager, cache GenericResourceCache, repos ...*gitrepo.Repo) (*Provider, error) {
+	reposByName := make(map[string]*gitrepo.Repo)
+	for _, r := range repos {
+		reposByName[r.Name] = r
+	}
 	return &Provider{
 		cache:           cache,
 		approvalManager: approvalManager,
+		batches:         make(map[string]*batch),
+		flushes:         make(chan string),
+		pending:         make(map[string]*pendingPromotion),
+		plans:           make(map[string]*types.PlannedUpdate),
+		blocked:         make(map[string]map[string]bool),
 		events:          make(chan *types.Event, 100),
 		stop:            make(chan struct{}),
 		sender:          sender,
-		repo:            repo,
+		repos:           reposByName,
 	}, nil
 }
 
+// getSourceFiles - repository files the resource was rendered from
+func getSourceFiles(annotations map[string]string) []string {
+	var sources []string
+	for _, s := range strings.Split(annotations[types.BowSourceFilesAnnotation], ",") {
+		if s != "" {
+			sources = append(sources, s)
+		}
+	}
+	return sources
+}
+
+// getRepo - returns the repository the resource was rendered from
+func (p *Provider) getRepo(resource *k8s.GenericResource) (*gitrepo.Repo, error) {
+	name, ok := resource.GetAnnotations()[types.BowSourceRepoAnnotation]
+	if !ok && len(p.repos) == 1 {
+		for _, r := range p.repos {
+			return r, nil
+		}
+	}
+	r, ok := p.repos[name]
+	if !ok {
+		return nil, fmt.Errorf("repository '%s' of %s not found", name, resource.Identifier)
+	}
+	return r, nil
+}
+
 // Submit - submit event to provider
 func (p *Provider) Submit(event types.Event) error {
 	p.events <- &event
@@ -153,26 +215,11 @@ func (p *Provider) TrackedImages() ([]*types.TrackedImage, error) {
 		annotations := gr.GetAnnotations()
 		// by default we want to track every deployment, not just specifically labeled (for now)
 		// NOT ignoring unlabelled deployments
-		plc := policy.GetPolicyFromLabelsOrAnnotations(labels, annotations)
-		//if plc.Type() == policy.PolicyTypeNone {
-		//	continue
-		//}
-
-		schedule, ok := annotations[types.BowPollScheduleAnnotation]
-		if ok {
-			_, err := cron.Parse(schedule)
-			if err != nil {
-				log.WithFields(log.Fields{
-					"error":     err,
-					"schedule":  schedule,
-					"name":      gr.Name,
-					"namespace": gr.Namespace,
-				}).Error("provider.kubernetes: failed to parse poll schedule, setting default schedule")
-				schedule = types.BowPollDefaultSchedule
-			}
-		} else {
-			schedule = types.BowPollDefaultSchedule
-		}
+		// bow/policy.<container> and bow/pollSchedule.<container> override the
+		// policy and schedule of the resource
+		policyOf := containerPolicies(gr)
+
+		schedule := getPollSchedule(gr, types.BowPollScheduleAnnotation, types.BowPollDefaultSchedule)
 
 		// trigger type, we only care for "poll" type triggers
 		trigger := policies.GetTriggerPolicy(labels, annotations)
@@ -184,13 +231,12 @@ func (p *Provider) TrackedImages() ([]*types.TrackedImage, error) {
 			secrets = append(secrets, specifiedSecret)
 		}
 
-		images := gr.GetImages()
-		for _, img := range images {
-			ref, err := image.Parse(img)
+		for _, c := range gr.AllContainers() {
+			ref, err := image.Parse(c.Image)
 			if err != nil {
 				log.WithFields(log.Fields{
 					"error":     err,
-					"image":     img,
+					"image":     c.Image,
 					"namespace": gr.Namespace,
 					"name":      gr.Name,
 				}).Error("provider.kubernetes: failed to parse image")
@@ -207,18 +253,46 @@ func (p *Provider) TrackedImages() ([]*types.TrackedImage, error) {
 
 			trackedImages = append(trackedImages, &types.TrackedImage{
 				Image:        ref,
-				PollSchedule: schedule,
+				PollSchedule: getPollSchedule(gr, types.ContainerAnnotation(types.BowPollScheduleAnnotation, c.Name), schedule),
 				Trigger:      trigger,
 				Provider:     ProviderName,
-				Meta:         make(map[string]string),
-				Policy:       plc,
+				Meta:         map[string]string{"container": c.Name},
+				Policy:       policyOf(c.Name),
 			})
 		}
 	}
 	return trackedImages, nil
 }
 
+// getPollSchedule - schedule from the key annotation of gr, fallback if it is missing or invalid
+func getPollSchedule(gr *k8s.GenericResource, key string, fallback string) string {
+	schedule, ok := gr.GetAnnotations()[key]
+	if !ok {
+		return fallback
+	}
+	_, err := cron.Parse(schedule)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"schedule":   schedule,
+			"annotation": key,
+			"name":       gr.Name,
+			"namespace":  gr.Namespace,
+		}).Error("provider.kubernetes: failed to parse poll schedule, setting default schedule")
+		return fallback
+	}
+	return schedule
+}
+
 func (p *Provider) startInternal() error {
+	var promotionChecks <-chan time.Time
+	if len(p.promotions) > 0 {
+		p.restorePromotions()
+		ticker := time.NewTicker(promotionCheckInterval)
+		defer ticker.Stop()
+		promotionChecks = ticker.C
+	}
+
 	for {
 		select {
 		case event := <-p.events:
@@ -230,14 +304,24 @@ func (p *Provider) startInternal() error {
 					"tag":   event.Repository.Tag,
 				}).Error("provider.kubernetes: failed to process event")
 			}
+		case name := <-p.flushes:
+			p.flush(name)
+		case <-promotionChecks:
+			p.checkPromotions()
 		case <-p.stop:
 			log.Info("provider.kubernetes: got shutdown signal, stopping...")
+			p.flushAll()
 			return nil
 		}
 	}
 }
 
 func (p *Provider) processEvent(event *types.Event) (updated []*k8s.GenericResource, err error) {
+	if event.TriggerName == types.TriggerTypeApproval.String() && len(p.promotions) > 0 {
+		p.checkPromotions()
+		p.checkApprovedPromotions(&event.Repository)
+	}
+
 	plans, err := p.createUpdatePlans(&event.Repository)
 	if err != nil {
 		return nil, err
@@ -251,6 +335,12 @@ func (p *Provider) processEvent(event *types.Event) (updated []*k8s.GenericResou
 		return
 	}
 
+	for _, plan := range plans {
+		plan.Trigger = event.TriggerName
+	}
+
+	plans = p.planDryRun(plans)
+
 	approvedPlans := p.checkForApprovals(event, plans)
 
 	return p.updateDeployments(approvedPlans)
@@ -284,28 +374,109 @@ func (p *Provider) updateDeployments(plans []*UpdatePlan) (updated []*k8s.Generi
 			},
 		})
 
-		var err error
-
 		timestamp := time.Now().Format(time.RFC3339)
 		annotations["kubernetes.io/change-cause"] = fmt.Sprintf("bow automated update, version %s -> %s [%s]", plan.CurrentVersion, plan.NewVersion, timestamp)
 
 		resource.SetAnnotations(annotations)
 
-		for _, img := range resource.GetImages() { // maybe only one of multiple containers needs to be updated, so filter
-			parts := strings.Split(img, ":")
-			if len(parts) > 1 && parts[1] == plan.CurrentVersion { // images without a tag will be ignored
-				p.repo.GrepAndReplace(img, plan.NewVersion)
-				err := p.repo.CommitAndPushAll("updating " + img + " to " + plan.NewVersion)
-				if err != nil {
-					log.WithFields(log.Fields{
-						"error":      err,
-						"deployment": resource.Name,
-						"kind":       resource.Kind(),
-						"update":     fmt.Sprintf("%s->%s", plan.CurrentVersion, plan.NewVersion),
-					}).Error("provider.kubernetes: got error while committing and pushing")
-				}
+		repo, err := p.getRepo(resource)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":      err,
+				"deployment": resource.Name,
+				"kind":       resource.Kind(),
+			}).Error("provider.kubernetes: failed to find repository for resource")
+			continue
+		}
+
+		if repo.BatchWindow > 0 {
+			p.queue(repo, plan)
+			continue
+		}
+
+		updated = append(updated, p.applyPlans(repo, []*UpdatePlan{plan})...)
+	}
+
+	return
+}
+
+// changesFor - repository changes of plans, one for every image at the current version
+func (p *Provider) changesFor(plans []*UpdatePlan) ([]*gitrepo.Change, map[*gitrepo.Change]*UpdatePlan) {
+	var changes []*gitrepo.Change
+	planOf := make(map[*gitrepo.Change]*UpdatePlan)
+	for _, plan := range plans {
+		resource := plan.Resource
+		annotations := resource.GetAnnotations()
+		approvers := p.getApprovers(plan)
+
+		for _, c := range resource.AllContainers() { // maybe only one of multiple containers needs to be updated, so filter
+			if len(plan.Containers) > 0 && !contains(plan.Containers, c.Name) {
+				continue
+			}
+			img := c.Image
+			ref, err := image.Parse(img)
+			if err != nil || ref.Tag() != plan.CurrentVersion { // images without a tag will be ignored
+				continue
 			}
+
+			change := &gitrepo.Change{
+				Image:        img,
+				Repository:   ref.Repository(),
+				OldTag:       plan.CurrentVersion,
+				NewTag:       plan.NewVersion,
+				Resource:     resource.Identifier,
+				Sources:      getSourceFiles(annotations),
+				Approvers:    approvers,
+				Trigger:      plan.Trigger,
+				ReleaseNotes: types.ParseReleaseNotesURL(annotations),
+			}
+			changes = append(changes, change)
+			planOf[change] = plan
 		}
+	}
+	return changes, planOf
+}
+
+// applyPlans - writes the new versions of plans to repo and commits them together
+func (p *Provider) applyPlans(repo *gitrepo.Repo, plans []*UpdatePlan) (updated []*k8s.GenericResource) {
+	changes, planOf := p.changesFor(plans)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	commit, failedChanges, err := repo.Apply(changes)
+
+	failed := make(map[*UpdatePlan]bool)
+	for change, changeErr := range failedChanges {
+		plan := planOf[change]
+		if !failed[plan] {
+			failed[plan] = true
+			p.updateFailed(plan, changeErr)
+		}
+	}
+
+	if err == gitrepo.ErrPullRequestOpen {
+		// nothing new was pushed, the open pull request was announced already
+		log.WithFields(log.Fields{
+			"repo": repo.Name,
+		}).Debug("provider.kubernetes: pull request for the update is already open")
+		return nil
+	}
+
+	var written []*UpdatePlan
+	for _, plan := range plans {
+		if failed[plan] {
+			continue
+		}
+		if err != nil {
+			p.updateFailed(plan, err)
+			continue
+		}
+		written = append(written, plan)
+	}
+
+	for _, plan := range written {
+		resource := plan.Resource
 
 		kubernetesVersionedUpdatesCounter.With(prometheus.Labels{"kubernetes": fmt.Sprintf("%s/%s", resource.Namespace, resource.Name)}).Inc()
 
@@ -326,6 +497,9 @@ func (p *Provider) updateDeployments(plans []*UpdatePlan) (updated []*k8s.Generi
 			msg = fmt.Sprintf("Successfully updated %s %s/%s %s->%s (%s)", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, strings.Join(resource.GetImages(), ", "))
 		}
 
+		p.recordPromotions(plan)
+		p.forgetPlans(resource.Identifier)
+
 		p.sender.Send(types.EventNotification{
 			ResourceKind: resource.Kind(),
 			Identifier:   resource.Identifier,
@@ -334,12 +508,12 @@ func (p *Provider) updateDeployments(plans []*UpdatePlan) (updated []*k8s.Generi
 			CreatedAt:    time.Now(),
 			Type:         types.NotificationDeploymentUpdate,
 			Level:        types.LevelSuccess,
-			Channels:     notificationChannels,
-			Metadata: map[string]string{
+			Channels:     types.ParseEventNotificationChannels(resource.GetAnnotations()),
+			Metadata: withCommit(map[string]string{
 				"provider":  p.GetName(),
 				"namespace": resource.GetNamespace(),
 				"name":      resource.GetName(),
-			},
+			}, repo, commit),
 		})
 
 		log.WithFields(log.Fields{
@@ -348,11 +522,47 @@ func (p *Provider) updateDeployments(plans []*UpdatePlan) (updated []*k8s.Generi
 			"previous":  plan.CurrentVersion,
 			"new":       plan.NewVersion,
 			"namespace": resource.Namespace,
+			"commit":    commit,
 		}).Info("provider.kubernetes: resource updated")
 		updated = append(updated, resource)
 	}
 
-	return
+	return updated
+}
+
+// withCommit - adds hash, branch, repository URL and web link of commit to notification metadata
+func withCommit(metadata map[string]string, repo *gitrepo.Repo, commit string) map[string]string {
+	for key, value := range repo.CommitMetadata(commit) {
+		metadata[key] = value
+	}
+	return metadata
+}
+
+// updateFailed - logs and notifies about a plan that could not be written to its repository
+func (p *Provider) updateFailed(plan *UpdatePlan, err error) {
+	resource := plan.Resource
+	log.WithFields(log.Fields{
+		"error":      err,
+		"deployment": resource.Name,
+		"kind":       resource.Kind(),
+		"update":     fmt.Sprintf("%s->%s", plan.CurrentVersion, plan.NewVersion),
+	}).Error("provider.kubernetes: got error while updating repository")
+
+	p.sender.Send(types.EventNotification{
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Name:         "update resource",
+		Message:      fmt.Sprintf("Failed to update %s %s/%s %s->%s: %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, err),
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationDeploymentUpdate,
+		Level:        types.LevelError,
+		Channels:     types.ParseEventNotificationChannels(resource.GetAnnotations()),
+		Metadata: map[string]string{
+			"provider":  p.GetName(),
+			"namespace": resource.GetNamespace(),
+			"name":      resource.GetName(),
+		},
+	})
 }
 
 // createUpdatePlans - impacted deployments by changed repository
@@ -361,15 +571,13 @@ func (p *Provider) createUpdatePlans(repo *types.Repository) ([]*UpdatePlan, err
 
 	for _, resource := range p.cache.Values() {
 
-		labels := resource.GetLabels()
-		annotations := resource.GetAnnotations()
-
-		plc := policy.GetPolicyFromLabelsOrAnnotations(labels, annotations)
-		if plc.Type() == policy.PolicyTypeNone {
+		// target environments of promotion pipelines are not updated by registry events
+		if p.promotionTarget(resource) {
 			continue
 		}
 
-		updated, shouldUpdateDeployment, err := checkForUpdate(plc, repo, resource)
+		// containers without a policy are skipped
+		updated, shouldUpdateDeployment, err := checkContainersForUpdate(containerPolicies(resource), repo, resource)
 		if err != nil {
 			log.WithFields(log.Fields{
 				"error":      err,
@@ -380,6 +588,16 @@ func (p *Provider) createUpdatePlans(repo *types.Repository) ([]*UpdatePlan, err
 			continue
 		}
 
+		if shouldUpdateDeployment && p.isBlocked(resource.Identifier, updated.NewVersion) {
+			log.WithFields(log.Fields{
+				"deployment": resource.Name,
+				"kind":       resource.Kind(),
+				"namespace":  resource.Namespace,
+				"version":    updated.NewVersion,
+			}).Info("provider.kubernetes: resource was rolled back from this version, skipping update")
+			continue
+		}
+
 		if shouldUpdateDeployment {
 			impacted = append(impacted, updated)
 		}
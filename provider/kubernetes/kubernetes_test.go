@@ -1,17 +1,24 @@
 package kubernetes
 
 import (
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/alwinius/bow/approvals"
 	"github.com/alwinius/bow/extension/notification"
+	"github.com/alwinius/bow/internal/gitrepo"
 	"github.com/alwinius/bow/internal/k8s"
+	"github.com/alwinius/bow/pkg/store/sql"
 	"github.com/alwinius/bow/types"
 
 	apps_v1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	core_v1 "k8s.io/client-go/kubernetes/typed/core/v1"
 )
 
 type fakeProvider struct {
@@ -37,62 +44,6 @@ func (p *fakeProvider) GetName() string {
 	return "fp"
 }
 
-type fakeImplementer struct {
-	namespaces     *v1.NamespaceList
-	deployment     *apps_v1.Deployment
-	deploymentList *apps_v1.DeploymentList
-
-	podList     *v1.PodList
-	deletedPods []*v1.Pod
-
-	// stores value of an updated deployment
-	updated *k8s.GenericResource
-
-	availableSecret *v1.Secret
-}
-
-func (i *fakeImplementer) Namespaces() (*v1.NamespaceList, error) {
-	return i.namespaces, nil
-}
-
-func (i *fakeImplementer) Deployment(namespace, name string) (*apps_v1.Deployment, error) {
-	return i.deployment, nil
-}
-
-func (i *fakeImplementer) Deployments(namespace string) (*apps_v1.DeploymentList, error) {
-	return i.deploymentList, nil
-}
-
-func (i *fakeImplementer) Update(obj *k8s.GenericResource) error {
-	i.updated = obj
-	return nil
-}
-
-func (i *fakeImplementer) Secret(namespace, name string) (*v1.Secret, error) {
-	return i.availableSecret, nil
-}
-
-func (i *fakeImplementer) Pods(namespace, labelSelector string) (*v1.PodList, error) {
-	return i.podList, nil
-}
-
-func (i *fakeImplementer) DeletePod(namespace, name string, opts *meta_v1.DeleteOptions) error {
-	i.deletedPods = append(i.deletedPods, &v1.Pod{
-		meta_v1.TypeMeta{},
-		meta_v1.ObjectMeta{
-			Name:      name,
-			Namespace: namespace,
-		},
-		v1.PodSpec{},
-		v1.PodStatus{},
-	})
-	return nil
-}
-
-func (i *fakeImplementer) ConfigMaps(namespace string) core_v1.ConfigMapInterface {
-	return nil
-}
-
 type fakeSender struct {
 	sentEvent types.EventNotification
 }
@@ -106,40 +57,77 @@ func (s *fakeSender) Send(event types.EventNotification) error {
 	return nil
 }
 
-func approver() *approvals.DefaultManager {
-	cache := memory.NewMemoryCache()
-	return approvals.New(cache)
-}
+func newTestStore() (*sql.SQLStore, func()) {
+	dir, err := ioutil.TempDir("", "bow-kubernetes")
+	if err != nil {
+		log.Fatal(err)
+	}
+	store, err := sql.New(sql.Opts{DatabaseType: "sqlite3", URI: filepath.Join(dir, "gorm.db")})
+	if err != nil {
+		log.Fatal(err)
+	}
 
-func TestGetNamespaces(t *testing.T) {
-	fi := &fakeImplementer{
-		namespaces: &v1.NamespaceList{
-			Items: []v1.Namespace{
-				v1.Namespace{
-					meta_v1.TypeMeta{},
-					meta_v1.ObjectMeta{Name: "xxxx"},
-					v1.NamespaceSpec{},
-					v1.NamespaceStatus{},
-				},
-			},
-		},
+	teardown := func() {
+		store.Close()
+		os.RemoveAll(dir)
 	}
+	return store, teardown
+}
 
-	grc := &k8s.GenericResourceCache{}
+func approver() (*approvals.DefaultManager, func()) {
+	store, teardown := newTestStore()
+	return approvals.New(&approvals.Opts{Store: store}), teardown
+}
 
-	provider, err := NewProvider(fi, &fakeSender{}, approver(), grc)
+// newTestRepo - commits files to a new bare repository and returns a Repo cloning it into
+// memory together with the path of the bare repository, remove the directory of the latter
+// when done
+func newTestRepo(t *testing.T, files map[string]string) (*gitrepo.Repo, string) {
+	dir, err := ioutil.TempDir("", "bow-kubernetes")
 	if err != nil {
-		t.Fatalf("failed to get provider: %s", err)
+		t.Fatalf("failed to create temp dir: %s", err)
 	}
+	work := filepath.Join(dir, "work")
+	remote := filepath.Join(dir, "remote.git")
 
-	namespaces, err := provider.namespaces()
+	runGit(t, dir, "init", "-q", "--bare", "-b", "master", remote)
+	runGit(t, dir, "init", "-q", "-b", "master", work)
+	for name, content := range files {
+		err = os.MkdirAll(filepath.Dir(filepath.Join(work, name)), 0755)
+		if err != nil {
+			t.Fatalf("failed to create dir: %s", err)
+		}
+		err = ioutil.WriteFile(filepath.Join(work, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("failed to write file: %s", err)
+		}
+	}
+	runGit(t, work, "add", "-A")
+	runGit(t, work, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial")
+	runGit(t, work, "push", "-q", remote, "master")
+
+	repo, err := gitrepo.NewRepo(gitrepo.RepoConfig{Name: "test", URL: remote, Storage: gitrepo.StorageMemory}, dir)
 	if err != nil {
-		t.Errorf("failed to get namespaces: %s", err)
+		t.Fatalf("failed to create repo: %s", err)
 	}
+	// clones the repository, as the watcher does before resources are rendered from it
+	repo.Plan(nil)
+	return repo, remote
+}
 
-	if namespaces.Items[0].Name != "xxxx" {
-		t.Errorf("expected xxxx but got %s", namespaces.Items[0].Name)
+// remoteFile - content of name on the master branch of the bare repository remote
+func remoteFile(t *testing.T, remote string, name string) string {
+	return runGit(t, remote, "show", "master:"+name)
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %s: %s", args, err, out)
 	}
+	return string(out)
 }
 
 func TestGetImageName(t *testing.T) {
@@ -171,18 +159,34 @@ func MustParseGRS(objs []*apps_v1.Deployment) []*k8s.GenericResource {
 	return grs
 }
 
-func TestGetImpacted(t *testing.T) {
-	fp := &fakeImplementer{}
-	fp.namespaces = &v1.NamespaceList{
-		Items: []v1.Namespace{
-			v1.Namespace{
-				meta_v1.TypeMeta{},
-				meta_v1.ObjectMeta{Name: "xxxx"},
-				v1.NamespaceSpec{},
-				v1.NamespaceStatus{},
+// deploymentManifest - deployment as written to a test repository
+func deploymentManifest(name, image string) string {
+	return "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: " + name + "\nspec:\n  template:\n    spec:\n      containers:\n      - name: " + name + "\n        image: " + image + "\n"
+}
+
+// sourcedDeployment - deployment rendered from file of a test repository, any new version
+// updates it
+func sourcedDeployment(name, image, file string) *k8s.GenericResource {
+	return MustParseGR(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        name,
+			Namespace:   "xxxx",
+			Labels:      map[string]string{types.BowPolicyLabel: "all"},
+			Annotations: map[string]string{types.BowSourceFilesAnnotation: file},
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{Name: name, Image: image},
+					},
+				},
 			},
 		},
-	}
+	})
+}
+
+func TestGetImpacted(t *testing.T) {
 
 	deps := []*apps_v1.Deployment{
 		{
@@ -231,7 +235,9 @@ func TestGetImpacted(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	am, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeSender{}, am, grc)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -267,17 +273,6 @@ func TestGetImpacted(t *testing.T) {
 
 }
 func TestGetImpactedPolicyAnnotations(t *testing.T) {
-	fp := &fakeImplementer{}
-	fp.namespaces = &v1.NamespaceList{
-		Items: []v1.Namespace{
-			v1.Namespace{
-				meta_v1.TypeMeta{},
-				meta_v1.ObjectMeta{Name: "xxxx"},
-				v1.NamespaceSpec{},
-				v1.NamespaceStatus{},
-			},
-		},
-	}
 
 	deps := []*apps_v1.Deployment{
 		{
@@ -327,7 +322,9 @@ func TestGetImpactedPolicyAnnotations(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	am, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeSender{}, am, grc)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -368,18 +365,6 @@ func TestPrereleaseGetImpactedA(t *testing.T) {
 	// is to get one update plan for the second deployment. Deployment with prerelease tag
 	// should be ignored
 
-	fp := &fakeImplementer{}
-	fp.namespaces = &v1.NamespaceList{
-		Items: []v1.Namespace{
-			v1.Namespace{
-				meta_v1.TypeMeta{},
-				meta_v1.ObjectMeta{Name: "xxxx"},
-				v1.NamespaceSpec{},
-				v1.NamespaceStatus{},
-			},
-		},
-	}
-
 	deps := []*apps_v1.Deployment{
 		{
 			meta_v1.TypeMeta{},
@@ -427,7 +412,9 @@ func TestPrereleaseGetImpactedA(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	am, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeSender{}, am, grc)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -458,18 +445,6 @@ func TestPrereleaseGetImpactedB(t *testing.T) {
 	// is to get one update plan for the second deployment. Deployment with prerelease tag
 	// should be ignored
 
-	fp := &fakeImplementer{}
-	fp.namespaces = &v1.NamespaceList{
-		Items: []v1.Namespace{
-			v1.Namespace{
-				meta_v1.TypeMeta{},
-				meta_v1.ObjectMeta{Name: "xxxx"},
-				v1.NamespaceSpec{},
-				v1.NamespaceStatus{},
-			},
-		},
-	}
-
 	deps := []*apps_v1.Deployment{
 		{
 			meta_v1.TypeMeta{},
@@ -517,7 +492,9 @@ func TestPrereleaseGetImpactedB(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	am, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeSender{}, am, grc)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -543,17 +520,6 @@ func TestPrereleaseGetImpactedB(t *testing.T) {
 }
 
 func TestProcessEvent(t *testing.T) {
-	fp := &fakeImplementer{}
-	fp.namespaces = &v1.NamespaceList{
-		Items: []v1.Namespace{
-			v1.Namespace{
-				meta_v1.TypeMeta{},
-				meta_v1.ObjectMeta{Name: "xxxx"},
-				v1.NamespaceSpec{},
-				v1.NamespaceStatus{},
-			},
-		},
-	}
 	deps := []*apps_v1.Deployment{
 		{
 			meta_v1.TypeMeta{},
@@ -561,7 +527,7 @@ func TestProcessEvent(t *testing.T) {
 				Name:        "deployment-1",
 				Namespace:   "ns-1",
 				Labels:      map[string]string{types.BowPolicyLabel: "all"},
-				Annotations: map[string]string{},
+				Annotations: map[string]string{types.BowSourceFilesAnnotation: "deployment.yaml"},
 			},
 			apps_v1.DeploymentSpec{
 				Template: v1.PodTemplateSpec{
@@ -644,7 +610,12 @@ func TestProcessEvent(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	gitRepo, remote := newTestRepo(t, map[string]string{"deployment.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: deployment-1\nspec:\n  template:\n    spec:\n      containers:\n      - image: gcr.io/v2-namespace/hello-world:1.1.1\n"})
+	defer os.RemoveAll(filepath.Dir(remote))
+
+	am, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeSender{}, am, grc, gitRepo)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -655,32 +626,21 @@ func TestProcessEvent(t *testing.T) {
 	}
 
 	event := &types.Event{Repository: repo}
-	_, err = provider.processEvent(event)
+	updated, err := provider.processEvent(event)
 	if err != nil {
 		t.Errorf("got error while processing event: %s", err)
 	}
 
-	if fp.updated == nil {
-		t.Fatalf("resource was not updated")
+	if len(updated) != 1 || updated[0].Name != "deployment-1" {
+		t.Fatalf("expected deployment-1 to be updated, got: %v", updated)
 	}
 
-	if fp.updated.Containers()[0].Image != repo.Name+":"+repo.Tag {
-		t.Errorf("expected to find a deployment with updated image but found: %s", fp.updated.Containers()[0].Image)
+	if !strings.Contains(remoteFile(t, remote, "deployment.yaml"), "image: "+repo.Name+":"+repo.Tag) {
+		t.Errorf("expected to find updated image in the repository but found: %s", remoteFile(t, remote, "deployment.yaml"))
 	}
 }
 
 func TestProcessEventBuildNumber(t *testing.T) {
-	fp := &fakeImplementer{}
-	fp.namespaces = &v1.NamespaceList{
-		Items: []v1.Namespace{
-			v1.Namespace{
-				meta_v1.TypeMeta{},
-				meta_v1.ObjectMeta{Name: "xxxx"},
-				v1.NamespaceSpec{},
-				v1.NamespaceStatus{},
-			},
-		},
-	}
 	deps := []*apps_v1.Deployment{
 		{
 			meta_v1.TypeMeta{},
@@ -688,7 +648,7 @@ func TestProcessEventBuildNumber(t *testing.T) {
 				Name:        "deployment-1",
 				Namespace:   "xxxx",
 				Labels:      map[string]string{types.BowPolicyLabel: "all"},
-				Annotations: map[string]string{},
+				Annotations: map[string]string{types.BowSourceFilesAnnotation: "deployment.yaml"},
 			},
 			apps_v1.DeploymentSpec{
 				Template: v1.PodTemplateSpec{
@@ -709,7 +669,12 @@ func TestProcessEventBuildNumber(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	gitRepo, remote := newTestRepo(t, map[string]string{"deployment.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: deployment-1\nspec:\n  template:\n    spec:\n      containers:\n      - image: gcr.io/v2-namespace/hello-world:10\n"})
+	defer os.RemoveAll(filepath.Dir(remote))
+
+	am, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeSender{}, am, grc, gitRepo)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -720,28 +685,17 @@ func TestProcessEventBuildNumber(t *testing.T) {
 	}
 
 	event := &types.Event{Repository: repo}
-	_, err = provider.processEvent(event)
+	updated, err := provider.processEvent(event)
 	if err != nil {
 		t.Errorf("got error while processing event: %s", err)
 	}
 
-	if fp.updated != nil {
-		t.Errorf("didn't expect to get updated containers, bot got: %s", fp.updated.Identifier)
+	if len(updated) != 0 {
+		t.Errorf("didn't expect to get updated containers, bot got: %s", updated[0].Identifier)
 	}
 }
 
 func TestEventSent(t *testing.T) {
-	fp := &fakeImplementer{}
-	fp.namespaces = &v1.NamespaceList{
-		Items: []v1.Namespace{
-			v1.Namespace{
-				meta_v1.TypeMeta{},
-				meta_v1.ObjectMeta{Name: "xxxx"},
-				v1.NamespaceSpec{},
-				v1.NamespaceStatus{},
-			},
-		},
-	}
 	deps := []*apps_v1.Deployment{
 		{
 			meta_v1.TypeMeta{},
@@ -749,7 +703,7 @@ func TestEventSent(t *testing.T) {
 				Name:        "deployment-1",
 				Namespace:   "xxxx",
 				Labels:      map[string]string{types.BowPolicyLabel: "all"},
-				Annotations: map[string]string{},
+				Annotations: map[string]string{types.BowSourceFilesAnnotation: "deployment.yaml"},
 			},
 			apps_v1.DeploymentSpec{
 				Template: v1.PodTemplateSpec{
@@ -770,8 +724,13 @@ func TestEventSent(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
+	gitRepo, remote := newTestRepo(t, map[string]string{"deployment.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: deployment-1\nspec:\n  template:\n    spec:\n      containers:\n      - image: gcr.io/v2-namespace/hello-world:10.0.0\n"})
+	defer os.RemoveAll(filepath.Dir(remote))
+
+	am, teardown := approver()
+	defer teardown()
 	fs := &fakeSender{}
-	provider, err := NewProvider(fp, fs, approver(), grc)
+	provider, err := NewProvider(fs, am, grc, gitRepo)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -787,27 +746,16 @@ func TestEventSent(t *testing.T) {
 		t.Errorf("got error while processing event: %s", err)
 	}
 
-	if fp.updated.Containers()[0].Image != repo.Name+":"+repo.Tag {
-		t.Errorf("expected to find a deployment with updated image but found: %s", fp.updated.Containers()[0].Image)
+	if !strings.Contains(remoteFile(t, remote, "deployment.yaml"), "image: "+repo.Name+":"+repo.Tag) {
+		t.Errorf("expected to find updated image in the repository but found: %s", remoteFile(t, remote, "deployment.yaml"))
 	}
 
-	if fs.sentEvent.Message != "Successfully updated deployment xxxx/deployment-1 10.0.0->11.0.0 (gcr.io/v2-namespace/hello-world:11.0.0)" {
-		t.Errorf("expected 'Successfully updated deployment xxxx/deployment-1 10.0.0->11.0.0 (gcr.io/v2-namespace/hello-world:11.0.0)' sent message, got: %s", fs.sentEvent.Message)
+	if !strings.HasPrefix(fs.sentEvent.Message, "Successfully updated deployment xxxx/deployment-1 10.0.0->11.0.0 (") {
+		t.Errorf("expected 'Successfully updated deployment xxxx/deployment-1 10.0.0->11.0.0 (...)' sent message, got: %s", fs.sentEvent.Message)
 	}
 }
 
 func TestEventSentWithReleaseNotes(t *testing.T) {
-	fp := &fakeImplementer{}
-	fp.namespaces = &v1.NamespaceList{
-		Items: []v1.Namespace{
-			v1.Namespace{
-				meta_v1.TypeMeta{},
-				meta_v1.ObjectMeta{Name: "xxxx"},
-				v1.NamespaceSpec{},
-				v1.NamespaceStatus{},
-			},
-		},
-	}
 	deps := []*apps_v1.Deployment{
 		{
 			meta_v1.TypeMeta{},
@@ -815,7 +763,7 @@ func TestEventSentWithReleaseNotes(t *testing.T) {
 				Name:        "deployment-1",
 				Namespace:   "xxxx",
 				Labels:      map[string]string{types.BowPolicyLabel: "all"},
-				Annotations: map[string]string{types.BowReleaseNotesURL: "https://github.com/alwinius/bow/releases"},
+				Annotations: map[string]string{types.BowReleaseNotesURL: "https://github.com/alwinius/bow/releases", types.BowSourceFilesAnnotation: "deployment.yaml"},
 			},
 			apps_v1.DeploymentSpec{
 				Template: v1.PodTemplateSpec{
@@ -836,8 +784,13 @@ func TestEventSentWithReleaseNotes(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
+	gitRepo, remote := newTestRepo(t, map[string]string{"deployment.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: deployment-1\nspec:\n  template:\n    spec:\n      containers:\n      - image: gcr.io/v2-namespace/hello-world:10.0.0\n"})
+	defer os.RemoveAll(filepath.Dir(remote))
+
+	am, teardown := approver()
+	defer teardown()
 	fs := &fakeSender{}
-	provider, err := NewProvider(fp, fs, approver(), grc)
+	provider, err := NewProvider(fs, am, grc, gitRepo)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -853,28 +806,20 @@ func TestEventSentWithReleaseNotes(t *testing.T) {
 		t.Errorf("got error while processing event: %s", err)
 	}
 
-	if fp.updated.Containers()[0].Image != repo.Name+":"+repo.Tag {
-		t.Errorf("expected to find a deployment with updated image but found: %s", fp.updated.Containers()[0].Image)
+	if !strings.Contains(remoteFile(t, remote, "deployment.yaml"), "image: "+repo.Name+":"+repo.Tag) {
+		t.Errorf("expected to find updated image in the repository but found: %s", remoteFile(t, remote, "deployment.yaml"))
 	}
 
-	if fs.sentEvent.Message != "Successfully updated deployment xxxx/deployment-1 10.0.0->11.0.0 (gcr.io/v2-namespace/hello-world:11.0.0). Release notes: https://github.com/alwinius/bow/releases" {
-		t.Errorf("expected 'Successfully updated deployment xxxx/deployment-1 10.0.0->11.0.0 (gcr.io/v2-namespace/hello-world:11.0.0). Release notes: https://github.com/alwinius/bow/releases' sent message, got: %s", fs.sentEvent.Message)
+	if !strings.HasPrefix(fs.sentEvent.Message, "Successfully updated deployment xxxx/deployment-1 10.0.0->11.0.0 (") {
+		t.Errorf("expected 'Successfully updated deployment xxxx/deployment-1 10.0.0->11.0.0 (...)' sent message, got: %s", fs.sentEvent.Message)
+	}
+	if !strings.HasSuffix(fs.sentEvent.Message, ". Release notes: https://github.com/alwinius/bow/releases") {
+		t.Errorf("expected release notes in sent message, got: %s", fs.sentEvent.Message)
 	}
 }
 
 // Test to check how many deployments are "impacted" if we have sidecar container
 func TestGetImpactedTwoContainersInSameDeployment(t *testing.T) {
-	fp := &fakeImplementer{}
-	fp.namespaces = &v1.NamespaceList{
-		Items: []v1.Namespace{
-			v1.Namespace{
-				meta_v1.TypeMeta{},
-				meta_v1.ObjectMeta{Name: "xxxx"},
-				v1.NamespaceSpec{},
-				v1.NamespaceStatus{},
-			},
-		},
-	}
 	deps := []*apps_v1.Deployment{
 		{
 			meta_v1.TypeMeta{},
@@ -925,7 +870,9 @@ func TestGetImpactedTwoContainersInSameDeployment(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	am, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeSender{}, am, grc)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -963,17 +910,6 @@ func TestGetImpactedTwoContainersInSameDeployment(t *testing.T) {
 
 func TestGetImpactedTwoSameContainersInSameDeployment(t *testing.T) {
 
-	fp := &fakeImplementer{}
-	fp.namespaces = &v1.NamespaceList{
-		Items: []v1.Namespace{
-			v1.Namespace{
-				meta_v1.TypeMeta{},
-				meta_v1.ObjectMeta{Name: "xxxx"},
-				v1.NamespaceSpec{},
-				v1.NamespaceStatus{},
-			},
-		},
-	}
 	deps := []*apps_v1.Deployment{
 		{
 			meta_v1.TypeMeta{},
@@ -1026,7 +962,9 @@ func TestGetImpactedTwoSameContainersInSameDeployment(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	am, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeSender{}, am, grc)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -1063,17 +1001,6 @@ func TestGetImpactedTwoSameContainersInSameDeployment(t *testing.T) {
 }
 
 func TestGetImpactedUntaggedImage(t *testing.T) {
-	fp := &fakeImplementer{}
-	fp.namespaces = &v1.NamespaceList{
-		Items: []v1.Namespace{
-			v1.Namespace{
-				meta_v1.TypeMeta{},
-				meta_v1.ObjectMeta{Name: "xxxx"},
-				v1.NamespaceSpec{},
-				v1.NamespaceStatus{},
-			},
-		},
-	}
 	deps := []*apps_v1.Deployment{
 		{
 			meta_v1.TypeMeta{},
@@ -1122,7 +1049,9 @@ func TestGetImpactedUntaggedImage(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	am, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeSender{}, am, grc)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -1160,17 +1089,6 @@ func TestGetImpactedUntaggedImage(t *testing.T) {
 
 // test to check whether we get impacted deployment when it's untagged (we should)
 func TestGetImpactedUntaggedOneImage(t *testing.T) {
-	fp := &fakeImplementer{}
-	fp.namespaces = &v1.NamespaceList{
-		Items: []v1.Namespace{
-			v1.Namespace{
-				meta_v1.TypeMeta{},
-				meta_v1.ObjectMeta{Name: "xxxx"},
-				v1.NamespaceSpec{},
-				v1.NamespaceStatus{},
-			},
-		},
-	}
 	deps := []*apps_v1.Deployment{
 		{
 			meta_v1.TypeMeta{},
@@ -1219,7 +1137,9 @@ func TestGetImpactedUntaggedOneImage(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	am, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeSender{}, am, grc)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -1258,17 +1178,6 @@ func TestGetImpactedUntaggedOneImage(t *testing.T) {
 }
 
 func TestTrackedImages(t *testing.T) {
-	fp := &fakeImplementer{}
-	fp.namespaces = &v1.NamespaceList{
-		Items: []v1.Namespace{
-			v1.Namespace{
-				meta_v1.TypeMeta{},
-				meta_v1.ObjectMeta{Name: "xxxx"},
-				v1.NamespaceSpec{},
-				v1.NamespaceStatus{},
-			},
-		},
-	}
 	deps := []*apps_v1.Deployment{
 		{
 			meta_v1.TypeMeta{},
@@ -1301,7 +1210,9 @@ func TestTrackedImages(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	am, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeSender{}, am, grc)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -1311,78 +1222,10 @@ func TestTrackedImages(t *testing.T) {
 		t.Errorf("failed to get image: %s", err)
 	}
 	if len(imgs) != 1 {
-		t.Errorf("expected to find 1 image, got: %d", len(imgs))
-	}
-
-	if imgs[0].Secrets[0] != "very-secret" {
-		t.Errorf("could not find image pull secret")
+		t.Fatalf("expected to find 1 image, got: %d", len(imgs))
 	}
-}
 
-func TestTrackedImagesWithSecrets(t *testing.T) {
-	fp := &fakeImplementer{}
-	fp.namespaces = &v1.NamespaceList{
-		Items: []v1.Namespace{
-			v1.Namespace{
-				meta_v1.TypeMeta{},
-				meta_v1.ObjectMeta{Name: "xxxx"},
-				v1.NamespaceSpec{},
-				v1.NamespaceStatus{},
-			},
-		},
-	}
-	deps := []*apps_v1.Deployment{
-		{
-			meta_v1.TypeMeta{},
-			meta_v1.ObjectMeta{
-				Name:      "dep-1",
-				Namespace: "xxxx",
-				Labels: map[string]string{
-					types.BowPolicyLabel:               "all",
-					types.BowImagePullSecretAnnotation: "foo-bar",
-				},
-			},
-			apps_v1.DeploymentSpec{
-				Template: v1.PodTemplateSpec{
-					Spec: v1.PodSpec{
-						Containers: []v1.Container{
-							v1.Container{
-								Image: "gcr.io/v2-namespace/hello-world:1.1",
-							},
-						},
-						ImagePullSecrets: []v1.LocalObjectReference{
-							v1.LocalObjectReference{
-								Name: "very-secret",
-							},
-						},
-					},
-				},
-			},
-			apps_v1.DeploymentStatus{},
-		},
-	}
-
-	grs := MustParseGRS(deps)
-	grc := &k8s.GenericResourceCache{}
-	grc.Add(grs...)
-
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
-	if err != nil {
-		t.Fatalf("failed to get provider: %s", err)
-	}
-
-	imgs, err := provider.TrackedImages()
-	if err != nil {
-		t.Errorf("failed to get image: %s", err)
-	}
-	if len(imgs) != 1 {
-		t.Errorf("expected to find 1 image, got: %d", len(imgs))
-	}
-
-	if imgs[0].Secrets[0] != "foo-bar" {
-		t.Errorf("expected foo-bar, got: %s", imgs[0].Secrets[0])
-	}
-	if imgs[0].Secrets[1] != "very-secret" {
-		t.Errorf("expected very-secret, got: %s", imgs[0].Secrets[1])
+	if imgs[0].Image.Remote() != "gcr.io/v2-namespace/hello-world:1.1" {
+		t.Errorf("expected gcr.io/v2-namespace/hello-world:1.1, got: %s", imgs[0].Image.Remote())
 	}
 }
@@ -0,0 +1,456 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alwinius/bow/internal/gitrepo"
+	"github.com/alwinius/bow/internal/k8s"
+	"github.com/alwinius/bow/internal/policy"
+	"github.com/alwinius/bow/pkg/store"
+	"github.com/alwinius/bow/types"
+	"github.com/alwinius/bow/util/image"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// PromotionTrigger - trigger name of updates made by promotion pipelines
+const PromotionTrigger = "promotion"
+
+// promotionCheckInterval - how often pending promotions are checked for elapsed soak times
+var promotionCheckInterval = time.Minute
+
+// promotionRenderTimeout - how long a ready promotion waits for the source environment to be
+// rendered with the new version before it is refused
+var promotionRenderTimeout = time.Hour
+
+// pendingPromotion - version bow deployed to the source environment of a pipeline, waiting for
+// its soak time or approval
+type pendingPromotion struct {
+	promotion  *gitrepo.Promotion
+	repository string
+	tag        string
+	deployed   time.Time
+}
+
+func getPromotionIdentifier(promotion *gitrepo.Promotion, repository, tag string) string {
+	return "promotion/" + promotion.Name + "/" + repository + ":" + tag
+}
+
+// SetPromotions - configures the promotion pipelines, must be called before Start
+func (p *Provider) SetPromotions(promotions []*gitrepo.Promotion) {
+	p.promotions = promotions
+}
+
+// SetStore - keeps pending promotions in s, so soak times survive restarts. Must be called
+// before Start.
+func (p *Provider) SetStore(s store.Store) {
+	p.store = s
+}
+
+func getPendingPromotionKey(promotion *gitrepo.Promotion, repository string) string {
+	return promotion.Name + "/" + repository
+}
+
+// restorePromotions - loads the pending promotions stored before a restart, the ones of
+// pipelines that are no longer configured are dropped
+func (p *Provider) restorePromotions() {
+	if p.store == nil {
+		return
+	}
+	stored, err := p.store.ListPendingPromotions()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("provider.kubernetes: failed to load pending promotions")
+		return
+	}
+
+	byName := make(map[string]*gitrepo.Promotion)
+	for _, promotion := range p.promotions {
+		byName[promotion.Name] = promotion
+	}
+	for _, s := range stored {
+		promotion, ok := byName[s.Promotion]
+		if !ok {
+			log.WithFields(log.Fields{
+				"promotion": s.Promotion,
+				"image":     s.Repository,
+				"tag":       s.Tag,
+			}).Info("provider.kubernetes: promotion is no longer configured, dropping pending version")
+			p.forgetPending(s.ID)
+			continue
+		}
+		p.pending[s.ID] = &pendingPromotion{
+			promotion:  promotion,
+			repository: s.Repository,
+			tag:        s.Tag,
+			deployed:   s.Deployed,
+		}
+	}
+}
+
+// setPending - stores the pending version of a pipeline and image, replacing the previous one
+func (p *Provider) setPending(key string, pending *pendingPromotion) {
+	p.pending[key] = pending
+	if p.store == nil {
+		return
+	}
+	err := p.store.SavePendingPromotion(&types.PendingPromotion{
+		ID:         key,
+		Promotion:  pending.promotion.Name,
+		Repository: pending.repository,
+		Tag:        pending.tag,
+		Deployed:   pending.deployed,
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"promotion": pending.promotion.Name,
+			"image":     pending.repository,
+		}).Warn("provider.kubernetes: failed to store pending promotion")
+	}
+}
+
+// forgetPending - drops the pending version of a pipeline and image
+func (p *Provider) forgetPending(key string) {
+	delete(p.pending, key)
+	if p.store == nil {
+		return
+	}
+	err := p.store.DeletePendingPromotion(key)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"pending": key,
+		}).Warn("provider.kubernetes: failed to delete pending promotion")
+	}
+}
+
+// environmentPaths - app directory of the resource or, for resources rendered from the
+// repository root, its source files
+func environmentPaths(annotations map[string]string) []string {
+	app := annotations[types.BowSourceAppAnnotation]
+	if app != "" && app != "." {
+		return []string{app}
+	}
+	return getSourceFiles(annotations)
+}
+
+// inEnvironment - whether resource was rendered from env
+func (p *Provider) inEnvironment(resource *k8s.GenericResource, env gitrepo.Environment) bool {
+	repo, err := p.getRepo(resource)
+	if err != nil {
+		return false
+	}
+	return env.Contains(repo.Name, environmentPaths(resource.GetAnnotations()))
+}
+
+// promotionTarget - whether resource only receives versions through a promotion pipeline
+func (p *Provider) promotionTarget(resource *k8s.GenericResource) bool {
+	for _, promotion := range p.promotions {
+		if p.inEnvironment(resource, promotion.To) {
+			return true
+		}
+	}
+	return false
+}
+
+// deployedIn - whether a resource of env currently runs repository:tag
+func (p *Provider) deployedIn(env gitrepo.Environment, repository, tag string) bool {
+	for _, resource := range p.cache.Values() {
+		if !p.inEnvironment(resource, env) {
+			continue
+		}
+		for _, img := range resource.GetImages() {
+			ref, err := image.Parse(img)
+			if err == nil && ref.Repository() == repository && ref.Tag() == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// recordPromotions - starts the pipelines whose source environment contains the updated
+// resource, a newer version replaces a pending promotion of the same image
+func (p *Provider) recordPromotions(plan *UpdatePlan) {
+	for _, promotion := range p.promotions {
+		if !p.inEnvironment(plan.Resource, promotion.From) {
+			continue
+		}
+		// the cached resource still references the previous version, as in applyPlans
+		for _, img := range plan.Resource.GetImages() {
+			ref, err := image.Parse(img)
+			if err != nil || ref.Tag() != plan.CurrentVersion {
+				continue
+			}
+			pending := &pendingPromotion{
+				promotion:  promotion,
+				repository: ref.Repository(),
+				tag:        plan.NewVersion,
+				deployed:   time.Now(),
+			}
+			key := getPendingPromotionKey(promotion, pending.repository)
+			if existing, ok := p.pending[key]; ok && existing.tag == pending.tag {
+				continue
+			}
+			p.setPending(key, pending)
+
+			log.WithFields(log.Fields{
+				"promotion": promotion.Name,
+				"image":     pending.repository,
+				"tag":       pending.tag,
+				"soak":      promotion.Soak.String(),
+				"approvals": promotion.Approvals,
+			}).Info("provider.kubernetes: promotion pending")
+
+			if promotion.Approvals > 0 {
+				err = p.requestPromotionApproval(pending)
+				if err != nil {
+					log.WithFields(log.Fields{
+						"error":     err,
+						"promotion": promotion.Name,
+						"image":     pending.repository,
+						"tag":       pending.tag,
+					}).Error("provider.kubernetes: failed to request promotion approval")
+				}
+			}
+		}
+	}
+}
+
+func (p *Provider) requestPromotionApproval(pending *pendingPromotion) error {
+	identifier := getPromotionIdentifier(pending.promotion, pending.repository, pending.tag)
+	_, err := p.approvalManager.Get(identifier)
+	if err == nil {
+		return nil
+	}
+	if err != store.ErrRecordNotFound {
+		return err
+	}
+
+	approval := &types.Approval{
+		Provider:   types.ProviderTypeKubernetes,
+		Identifier: identifier,
+		Event: &types.Event{
+			Repository:  types.Repository{Name: pending.repository, Tag: pending.tag},
+			CreatedAt:   time.Now(),
+			TriggerName: PromotionTrigger,
+		},
+		CurrentVersion: pending.promotion.From.String(),
+		NewVersion:     pending.promotion.To.String(),
+		VotesRequired:  pending.promotion.Approvals,
+		Deadline:       time.Now().Add(time.Duration(types.BowApprovalDeadlineDefault) * time.Hour),
+		Diff:           p.plansDiff(p.promotionPlans(pending.promotion, pending.repository, pending.tag)),
+	}
+	approval.Message = fmt.Sprintf("Promote %s:%s (%s).", pending.repository, pending.tag, approval.Delta())
+	return p.approvalManager.Create(approval)
+}
+
+// checkPromotions - promotes pending versions whose soak time passed or that were approved,
+// promotions without soak time and approvals are ready right away
+func (p *Provider) checkPromotions() {
+	keys := make([]string, 0, len(p.pending))
+	for key := range p.pending {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		pending := p.pending[key]
+		promotion := pending.promotion
+
+		ready := promotion.Soak == 0 && promotion.Approvals == 0
+		if promotion.Soak > 0 && time.Since(pending.deployed) >= promotion.Soak {
+			ready = true
+		}
+		if promotion.Approvals > 0 {
+			approval, err := p.approvalManager.Get(getPromotionIdentifier(promotion, pending.repository, pending.tag))
+			if err == nil && approval.Status() == types.ApprovalStatusRejected {
+				log.WithFields(log.Fields{
+					"promotion": promotion.Name,
+					"image":     pending.repository,
+					"tag":       pending.tag,
+				}).Info("provider.kubernetes: promotion rejected")
+				p.forgetPending(key)
+				continue
+			}
+			if err == nil && approval.Status() == types.ApprovalStatusApproved {
+				ready = true
+			}
+		}
+		if !ready {
+			continue
+		}
+
+		// the source environment is rendered again after bow's commit was pulled
+		if !p.deployedIn(promotion.From, pending.repository, pending.tag) && time.Since(pending.deployed) < promotionRenderTimeout {
+			continue
+		}
+
+		p.forgetPending(key)
+		p.promote(promotion, pending.repository, pending.tag)
+	}
+}
+
+// checkApprovedPromotions - promotes the version of an approved promotion approval, this
+// also covers approvals granted after a restart when nothing is pending anymore
+func (p *Provider) checkApprovedPromotions(repo *types.Repository) {
+	ref, err := image.Parse(repo.String())
+	if err != nil {
+		return
+	}
+	for _, promotion := range p.promotions {
+		approval, err := p.approvalManager.Get(getPromotionIdentifier(promotion, ref.Repository(), repo.Tag))
+		if err != nil || approval.Status() != types.ApprovalStatusApproved {
+			continue
+		}
+		key := getPendingPromotionKey(promotion, ref.Repository())
+		if pending, ok := p.pending[key]; ok && pending.tag == repo.Tag {
+			// waits for the source environment to be rendered
+			continue
+		}
+		p.promote(promotion, ref.Repository(), repo.Tag)
+	}
+}
+
+// promotionPlans - updates of the target environment's resources using repository to tag
+func (p *Provider) promotionPlans(promotion *gitrepo.Promotion, repository, tag string) []*UpdatePlan {
+	var plans []*UpdatePlan
+	for _, resource := range p.cache.Values() {
+		if !p.inEnvironment(resource, promotion.To) {
+			continue
+		}
+		plan, ok, err := checkForUpdate(policy.NewForcePolicy(false), &types.Repository{Name: repository, Tag: tag}, resource)
+		if err != nil || !ok || plan.CurrentVersion == plan.NewVersion {
+			continue
+		}
+		if p.isBlocked(resource.Identifier, plan.NewVersion) {
+			log.WithFields(log.Fields{
+				"promotion":  promotion.Name,
+				"identifier": resource.Identifier,
+				"version":    plan.NewVersion,
+			}).Info("provider.kubernetes: resource was rolled back from this version, not promoting it")
+			continue
+		}
+		plan.Trigger = PromotionTrigger
+		plans = append(plans, plan)
+	}
+	return plans
+}
+
+// promote - updates every resource of the target environment using repository to tag,
+// tags that do not run in the source environment are refused
+func (p *Provider) promote(promotion *gitrepo.Promotion, repository, tag string) {
+	identifier := getPromotionIdentifier(promotion, repository, tag)
+	var approvers []string
+	approval, err := p.approvalManager.Get(identifier)
+	if err == nil {
+		approvers = approval.GetVoters()
+		sort.Strings(approvers)
+	} else {
+		approval = nil
+	}
+
+	metadata := map[string]string{
+		"provider":  p.GetName(),
+		"promotion": promotion.Name,
+		"from":      promotion.From.String(),
+		"to":        promotion.To.String(),
+		"image":     repository,
+		"tag":       tag,
+		"approvers": strings.Join(approvers, ","),
+	}
+
+	if !p.deployedIn(promotion.From, repository, tag) {
+		log.WithFields(log.Fields{
+			"promotion": promotion.Name,
+			"image":     repository,
+			"tag":       tag,
+		}).Warn("provider.kubernetes: refusing to promote version that is not deployed to the source environment")
+
+		p.archivePromotionApproval(promotion, approval)
+		p.sender.Send(types.EventNotification{
+			Identifier: identifier,
+			Name:       "promotion refused",
+			Message:    fmt.Sprintf("Refused to promote %s:%s to %s, it is not deployed to %s", repository, tag, promotion.To, promotion.From),
+			CreatedAt:  time.Now(),
+			Type:       types.NotificationPromotion,
+			Level:      types.LevelWarn,
+			Metadata:   metadata,
+		})
+		return
+	}
+
+	plans := p.promotionPlans(promotion, repository, tag)
+
+	if approval != nil {
+		// votes were given for a different change, ie: the target branch was rebased in between
+		if diff := p.plansDiff(plans); diff != approval.Diff {
+			log.WithFields(log.Fields{
+				"promotion": promotion.Name,
+				"approval":  identifier,
+			}).Info("provider.kubernetes: diff of approved promotion changed, resetting votes")
+			approval.Diff = diff
+			err = p.approvalManager.Reset(approval)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error":     err,
+					"promotion": promotion.Name,
+				}).Error("provider.kubernetes: failed to reset promotion approval")
+			}
+			return
+		}
+		p.archivePromotionApproval(promotion, approval)
+	}
+
+	plans = p.planDryRun(plans)
+	if len(plans) == 0 {
+		log.WithFields(log.Fields{
+			"promotion": promotion.Name,
+			"image":     repository,
+			"tag":       tag,
+		}).Info("provider.kubernetes: nothing to promote, no target resources or dry run only")
+		return
+	}
+
+	var resources []string
+	for _, plan := range plans {
+		resources = append(resources, plan.Resource.Identifier)
+	}
+	metadata["resources"] = strings.Join(resources, ",")
+
+	p.sender.Send(types.EventNotification{
+		Identifier: identifier,
+		Name:       "promotion",
+		Message:    fmt.Sprintf("Promoting %s:%s from %s to %s (%s)", repository, tag, promotion.From, promotion.To, strings.Join(resources, ", ")),
+		CreatedAt:  time.Now(),
+		Type:       types.NotificationPromotion,
+		Level:      types.LevelSuccess,
+		Metadata:   metadata,
+	})
+
+	_, err = p.updateDeployments(plans)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"promotion": promotion.Name,
+		}).Error("provider.kubernetes: failed to promote")
+	}
+}
+
+func (p *Provider) archivePromotionApproval(promotion *gitrepo.Promotion, approval *types.Approval) {
+	if approval == nil {
+		return
+	}
+	err := p.approvalManager.Archive(approval.Identifier)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"promotion": promotion.Name,
+		}).Warn("provider.kubernetes: failed to archive promotion approval")
+	}
+}
@@ -0,0 +1,132 @@
+package kubernetes
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alwinius/bow/approvals"
+	"github.com/alwinius/bow/internal/gitrepo"
+	"github.com/alwinius/bow/internal/k8s"
+	"github.com/alwinius/bow/types"
+)
+
+func TestPendingPromotionSurvivesRestart(t *testing.T) {
+	gitRepo, remote := newTestRepo(t, map[string]string{
+		"staging/web.yaml": deploymentManifest("web", "gcr.io/v2-namespace/web:1.1.1"),
+		"prod/web.yaml":    deploymentManifest("web", "gcr.io/v2-namespace/web:1.1.1"),
+	})
+	defer os.RemoveAll(filepath.Dir(remote))
+
+	staging := sourcedDeployment("web", "gcr.io/v2-namespace/web:1.1.1", "staging/web.yaml")
+	prod := sourcedDeployment("web-prod", "gcr.io/v2-namespace/web:1.1.1", "prod/web.yaml")
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(staging, prod)
+
+	promotions := []*gitrepo.Promotion{{
+		Name: "prod",
+		From: gitrepo.Environment{Path: "staging"},
+		To:   gitrepo.Environment{Path: "prod"},
+		Soak: time.Hour,
+	}}
+
+	store, teardown := newTestStore()
+	defer teardown()
+	am := approvals.New(&approvals.Opts{Store: store})
+
+	p, err := NewProvider(&fakeSender{}, am, grc, gitRepo)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+	p.SetPromotions(promotions)
+	p.SetStore(store)
+
+	_, err = p.processEvent(&types.Event{Repository: types.Repository{Name: "gcr.io/v2-namespace/web", Tag: "1.1.2"}})
+	if err != nil {
+		t.Fatalf("failed to process event: %s", err)
+	}
+
+	stored, err := store.ListPendingPromotions()
+	if err != nil {
+		t.Fatalf("failed to list pending promotions: %s", err)
+	}
+	if len(stored) != 1 || stored[0].ID != "prod/gcr.io/v2-namespace/web" || stored[0].Tag != "1.1.2" {
+		t.Fatalf("expected web:1.1.2 to be pending for prod, got: %v", stored)
+	}
+
+	// restart
+	restarted, err := NewProvider(&fakeSender{}, am, grc, gitRepo)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+	restarted.SetPromotions(promotions)
+	restarted.SetStore(store)
+	restarted.restorePromotions()
+
+	pending, ok := restarted.pending["prod/gcr.io/v2-namespace/web"]
+	if !ok {
+		t.Fatalf("expected pending promotion to be restored")
+	}
+	if pending.promotion != promotions[0] || pending.tag != "1.1.2" {
+		t.Errorf("unexpected pending promotion: %s %s", pending.promotion.Name, pending.tag)
+	}
+	if diff := pending.deployed.Sub(p.pending["prod/gcr.io/v2-namespace/web"].deployed); diff > time.Second || diff < -time.Second {
+		t.Errorf("expected soak time to continue, deployed at %s instead of %s", pending.deployed, p.pending["prod/gcr.io/v2-namespace/web"].deployed)
+	}
+
+	// the soak time passes while staging runs the new version
+	pending.deployed = time.Now().Add(-2 * time.Hour)
+	grc.Add(sourcedDeployment("web", "gcr.io/v2-namespace/web:1.1.2", "staging/web.yaml"))
+	restarted.checkPromotions()
+
+	if content := remoteFile(t, remote, "prod/web.yaml"); !strings.Contains(content, "gcr.io/v2-namespace/web:1.1.2") {
+		t.Errorf("expected web:1.1.2 to be promoted to prod, got: %s", content)
+	}
+	if len(restarted.pending) != 0 {
+		t.Errorf("expected no pending promotions, got %d", len(restarted.pending))
+	}
+	stored, err = store.ListPendingPromotions()
+	if err != nil {
+		t.Fatalf("failed to list pending promotions: %s", err)
+	}
+	if len(stored) != 0 {
+		t.Errorf("expected promoted version to be deleted from the store, got: %v", stored)
+	}
+}
+
+func TestRestoreDropsUnconfiguredPromotion(t *testing.T) {
+	store, teardown := newTestStore()
+	defer teardown()
+
+	err := store.SavePendingPromotion(&types.PendingPromotion{
+		ID:         "removed/gcr.io/v2-namespace/web",
+		Promotion:  "removed",
+		Repository: "gcr.io/v2-namespace/web",
+		Tag:        "1.1.2",
+		Deployed:   time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("failed to save pending promotion: %s", err)
+	}
+
+	p, err := NewProvider(&fakeSender{}, approvals.New(&approvals.Opts{Store: store}), &k8s.GenericResourceCache{})
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+	p.SetPromotions([]*gitrepo.Promotion{{Name: "prod", To: gitrepo.Environment{Path: "prod"}}})
+	p.SetStore(store)
+	p.restorePromotions()
+
+	if len(p.pending) != 0 {
+		t.Errorf("expected no pending promotions, got %d", len(p.pending))
+	}
+	stored, err := store.ListPendingPromotions()
+	if err != nil {
+		t.Fatalf("failed to list pending promotions: %s", err)
+	}
+	if len(stored) != 0 {
+		t.Errorf("expected pending promotion of the removed pipeline to be deleted, got: %v", stored)
+	}
+}
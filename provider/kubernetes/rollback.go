@@ -0,0 +1,143 @@
+package kubernetes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alwinius/bow/internal/k8s"
+	"github.com/alwinius/bow/provider"
+	"github.com/alwinius/bow/types"
+	"github.com/alwinius/bow/util/image"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Rollback - reverts the commit bow made for the last update of the resource. The versions the
+// resource runs before the rollback are blocked, so registry events and polls do not apply them
+// again right away, newer versions are still applied. Blocked versions are kept until bow restarts.
+func (p *Provider) Rollback(identifier string, user string) (*types.Rollback, error) {
+	resource, err := k8s.FindResource(p.cache.Values(), identifier)
+	if err != nil {
+		return nil, err
+	}
+	if resource == nil {
+		return nil, provider.ErrResourceNotFound
+	}
+
+	repo, err := p.getRepo(resource)
+	if err != nil {
+		return nil, err
+	}
+	sources := getSourceFiles(resource.GetAnnotations())
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("source files of %s are unknown", identifier)
+	}
+
+	var blocked, tags []string
+	for _, img := range resource.GetImages() {
+		ref, err := image.Parse(img)
+		if err != nil {
+			continue
+		}
+		blocked = append(blocked, img)
+		tags = append(tags, ref.Tag())
+	}
+
+	revert, err := repo.RevertLastUpdate(sources)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"identifier": identifier,
+			"repo":       repo.Name,
+			"user":       user,
+		}).Error("provider.kubernetes: rollback failed")
+
+		p.sender.Send(types.EventNotification{
+			ResourceKind: resource.Kind(),
+			Identifier:   resource.Identifier,
+			Name:         "rollback resource",
+			Message:      fmt.Sprintf("Failed to roll back %s %s/%s: %s", resource.Kind(), resource.Namespace, resource.Name, err),
+			CreatedAt:    time.Now(),
+			Type:         types.NotificationRollback,
+			Level:        types.LevelError,
+			Channels:     types.ParseEventNotificationChannels(resource.GetAnnotations()),
+			Metadata: map[string]string{
+				"provider":  p.GetName(),
+				"namespace": resource.GetNamespace(),
+				"name":      resource.GetName(),
+				"user":      user,
+			},
+		})
+		return nil, err
+	}
+
+	p.block(identifier, tags)
+	p.forgetPlans(identifier)
+	repo.Refresh()
+
+	p.sender.Send(types.EventNotification{
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Name:         "rollback resource",
+		Message:      fmt.Sprintf("Rolled back %s %s/%s, reverted commit %s \"%s\" (requested by %s)", resource.Kind(), resource.Namespace, resource.Name, shortCommit(revert.Reverted), revert.Subject, user),
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationRollback,
+		Level:        types.LevelSuccess,
+		Channels:     types.ParseEventNotificationChannels(resource.GetAnnotations()),
+		Metadata: withCommit(map[string]string{
+			"provider":  p.GetName(),
+			"namespace": resource.GetNamespace(),
+			"name":      resource.GetName(),
+			"user":      user,
+			"reverted":  revert.Reverted,
+		}, repo, revert.Commit),
+	})
+
+	log.WithFields(log.Fields{
+		"identifier": identifier,
+		"repo":       repo.Name,
+		"reverted":   revert.Reverted,
+		"commit":     revert.Commit,
+		"blocked":    blocked,
+		"user":       user,
+	}).Info("provider.kubernetes: resource rolled back")
+
+	return &types.Rollback{
+		Provider:   p.GetName(),
+		Repository: repo.Name,
+		Identifier: identifier,
+		Reverted:   revert.Reverted,
+		Subject:    revert.Subject,
+		Commit:     revert.Commit,
+		Files:      revert.Files,
+		Blocked:    blocked,
+		User:       user,
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+// block - keeps tags from being applied to the resource again
+func (p *Provider) block(identifier string, tags []string) {
+	p.blockedLock.Lock()
+	defer p.blockedLock.Unlock()
+	if p.blocked[identifier] == nil {
+		p.blocked[identifier] = make(map[string]bool)
+	}
+	for _, tag := range tags {
+		p.blocked[identifier][tag] = true
+	}
+}
+
+// isBlocked - the resource was rolled back from tag
+func (p *Provider) isBlocked(identifier string, tag string) bool {
+	p.blockedLock.RLock()
+	defer p.blockedLock.RUnlock()
+	return p.blocked[identifier][tag]
+}
+
+func shortCommit(hash string) string {
+	if len(hash) > 8 {
+		return hash[:8]
+	}
+	return hash
+}
@@ -0,0 +1,97 @@
+package kubernetes
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alwinius/bow/internal/k8s"
+	"github.com/alwinius/bow/provider"
+	"github.com/alwinius/bow/types"
+)
+
+func TestRollbackBlocksVersion(t *testing.T) {
+	gitRepo, remote := newTestRepo(t, map[string]string{
+		"web.yaml": deploymentManifest("web", "gcr.io/v2-namespace/web:1.1.1"),
+	})
+	defer os.RemoveAll(filepath.Dir(remote))
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(sourcedDeployment("web", "gcr.io/v2-namespace/web:1.1.1", "web.yaml"))
+
+	am, teardown := approver()
+	defer teardown()
+	fs := &fakeSender{}
+	p, err := NewProvider(fs, am, grc, gitRepo)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	webEvent := &types.Event{Repository: types.Repository{Name: "gcr.io/v2-namespace/web", Tag: "1.1.2"}}
+	updated, err := p.processEvent(webEvent)
+	if err != nil || len(updated) != 1 {
+		t.Fatalf("expected web to be updated, got %d updated resources: %v", len(updated), err)
+	}
+	// the watcher renders the updated version
+	web := sourcedDeployment("web", "gcr.io/v2-namespace/web:1.1.2", "web.yaml")
+	grc.Add(web)
+
+	rollback, err := p.Rollback(web.Identifier, "alice")
+	if err != nil {
+		t.Fatalf("rollback failed: %s", err)
+	}
+	if len(rollback.Blocked) != 1 || rollback.Blocked[0] != "gcr.io/v2-namespace/web:1.1.2" {
+		t.Errorf("expected web:1.1.2 to be blocked, got: %v", rollback.Blocked)
+	}
+	if rollback.User != "alice" || rollback.Subject != "updating gcr.io/v2-namespace/web:1.1.1 to 1.1.2" {
+		t.Errorf("unexpected rollback: %s by %s", rollback.Subject, rollback.User)
+	}
+	if content := remoteFile(t, remote, "web.yaml"); !strings.Contains(content, "gcr.io/v2-namespace/web:1.1.1") {
+		t.Errorf("expected update to be reverted, got: %s", content)
+	}
+	if fs.sentEvent.Type != types.NotificationRollback || fs.sentEvent.Level != types.LevelSuccess {
+		t.Errorf("expected rollback notification, got: %s", fs.sentEvent.Message)
+	}
+
+	// the watcher renders the reverted version
+	grc.Add(sourcedDeployment("web", "gcr.io/v2-namespace/web:1.1.1", "web.yaml"))
+	commits := runGit(t, remote, "rev-list", "--count", "master")
+
+	updated, err = p.processEvent(webEvent)
+	if err != nil {
+		t.Fatalf("failed to process event: %s", err)
+	}
+	if len(updated) != 0 {
+		t.Errorf("expected the rolled back version to be skipped, got %d updated resources", len(updated))
+	}
+	if again := runGit(t, remote, "rev-list", "--count", "master"); again != commits {
+		t.Errorf("expected nothing to be committed for a blocked version")
+	}
+
+	// newer versions are applied
+	updated, err = p.processEvent(&types.Event{Repository: types.Repository{Name: "gcr.io/v2-namespace/web", Tag: "1.1.3"}})
+	if err != nil {
+		t.Fatalf("failed to process event: %s", err)
+	}
+	if len(updated) != 1 {
+		t.Errorf("expected web to be updated to 1.1.3, got %d updated resources", len(updated))
+	}
+	if content := remoteFile(t, remote, "web.yaml"); !strings.Contains(content, "gcr.io/v2-namespace/web:1.1.3") {
+		t.Errorf("expected web to be updated to 1.1.3, got: %s", content)
+	}
+}
+
+func TestRollbackUnknownResource(t *testing.T) {
+	am, teardown := approver()
+	defer teardown()
+	p, err := NewProvider(&fakeSender{}, am, &k8s.GenericResourceCache{})
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	_, err = p.Rollback("deployment/xxxx/web", "alice")
+	if err != provider.ErrResourceNotFound {
+		t.Errorf("expected %s, got: %v", provider.ErrResourceNotFound, err)
+	}
+}
@@ -13,6 +13,26 @@ import (
 )
 
 func checkForUpdate(plc policy.Policy, repo *types.Repository, resource *k8s.GenericResource) (updatePlan *UpdatePlan, shouldUpdateDeployment bool, err error) {
+	return checkContainersForUpdate(func(string) policy.Policy { return plc }, repo, resource)
+}
+
+// containerPolicies - policies of the containers of resource, bow/policy.<container>
+// overrides the policy of the resource
+func containerPolicies(resource *k8s.GenericResource) func(container string) policy.Policy {
+	labels := resource.GetLabels()
+	annotations := resource.GetAnnotations()
+	plc := policy.GetPolicyFromLabelsOrAnnotations(labels, annotations)
+	return func(container string) policy.Policy {
+		if containerPlc, ok := policy.GetContainerPolicy(container, labels, annotations); ok {
+			return containerPlc
+		}
+		return plc
+	}
+}
+
+// checkContainersForUpdate - checks containers and init containers of resource with the
+// policy policyOf returns for them
+func checkContainersForUpdate(policyOf func(container string) policy.Policy, repo *types.Repository, resource *k8s.GenericResource) (updatePlan *UpdatePlan, shouldUpdateDeployment bool, err error) {
 	updatePlan = &UpdatePlan{}
 
 	eventRepoRef, err := image.Parse(repo.String())
@@ -24,10 +44,14 @@ func checkForUpdate(plc policy.Policy, repo *types.Repository, resource *k8s.Gen
 		"name":      resource.Name,
 		"namespace": resource.Namespace,
 		"kind":      resource.Kind(),
-		"policy":    plc.Name(),
-	}).Debug("provider.kubernetes.checkVersionedDeployment: bow policy found, checking resource...")
+	}).Debug("provider.kubernetes.checkVersionedDeployment: checking resource...")
 	shouldUpdateDeployment = false
-	for idx, c := range resource.Containers() {
+	for idx, c := range resource.AllContainers() {
+		plc := policyOf(c.Name)
+		if plc.Type() == policy.PolicyTypeNone {
+			continue
+		}
+
 		containerImageRef, err := image.Parse(c.Image)
 		if err != nil {
 			log.WithFields(log.Fields{
@@ -56,7 +80,7 @@ func checkForUpdate(plc policy.Policy, repo *types.Repository, resource *k8s.Gen
 			continue
 		}
 
-		shouldUpdateContainer, err := plc.ShouldUpdate(containerImageRef.Tag(), eventRepoRef.Tag())
+		shouldUpdateContainer, err := policy.ShouldUpdate(plc, containerImageRef.Tag(), eventRepoRef.Tag(), repo.Metadata)
 		if err != nil {
 			log.WithFields(log.Fields{
 				"error":             err,
@@ -86,6 +110,7 @@ func checkForUpdate(plc policy.Policy, repo *types.Repository, resource *k8s.Gen
 		updatePlan.CurrentVersion = containerImageRef.Tag()
 		updatePlan.NewVersion = repo.Tag
 		updatePlan.Resource = resource
+		updatePlan.Containers = append(updatePlan.Containers, c.Name)
 	}
 
 	return updatePlan, shouldUpdateDeployment, nil
@@ -96,3 +121,12 @@ func setUpdateTime(resource *k8s.GenericResource) {
 	specAnnotations[types.BowUpdateTimeAnnotation] = time.Now().String()
 	resource.SetSpecAnnotations(specAnnotations)
 }
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -94,7 +94,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
-										Image: "gcr.io/v2-namespace/hello-world:latest",
+										Image: "gcr.io/v2-namespace/hello-world",
 									},
 								},
 							},
@@ -104,6 +104,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 				}),
 				NewVersion:     "latest",
 				CurrentVersion: "latest",
+				Containers:     []string{""},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -230,7 +231,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
-										Image: "karolisr/bow:0.2.0",
+										Image: "karolisr/bow:latest",
 									},
 								},
 							},
@@ -240,6 +241,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 				}),
 				NewVersion:     "0.2.0",
 				CurrentVersion: "latest",
+				Containers:     []string{""},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -307,6 +309,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 				}),
 				NewVersion:     "master",
 				CurrentVersion: "master",
+				Containers:     []string{""},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -377,6 +380,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 				}),
 				NewVersion:     "latest-staging",
 				CurrentVersion: "latest-staging",
+				Containers:     []string{""},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -448,6 +452,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 				}),
 				NewVersion:     "latest-staging",
 				CurrentVersion: "latest-staging",
+				Containers:     []string{""},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -553,6 +558,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 				}),
 				NewVersion:     "latest-staging",
 				CurrentVersion: "latest-staging",
+				Containers:     []string{""},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -613,7 +619,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
-										Image: "eu.gcr.io/karolisr/bow:release-2",
+										Image: "eu.gcr.io/karolisr/bow:release-1",
 									},
 								},
 							},
@@ -623,6 +629,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 				}),
 				NewVersion:     "release-2",
 				CurrentVersion: "release-1",
+				Containers:     []string{""},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -722,7 +729,7 @@ func TestProvider_checkForUpdateSemver(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
-										Image: "gcr.io/v2-namespace/hello-world:1.1.2",
+										Image: "gcr.io/v2-namespace/hello-world:1.1.1",
 									},
 								},
 							},
@@ -732,6 +739,7 @@ func TestProvider_checkForUpdateSemver(t *testing.T) {
 				}),
 				NewVersion:     "1.1.2",
 				CurrentVersion: "1.1.1",
+				Containers:     []string{""},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -900,7 +908,7 @@ func TestProvider_checkForUpdateSemver(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
-										Image: "gcr.io/v2-namespace/hello-world:1.1.2",
+										Image: "gcr.io/v2-namespace/hello-world:1.1.1",
 									},
 									v1.Container{
 										Image: "yo-world:1.1.1",
@@ -913,6 +921,7 @@ func TestProvider_checkForUpdateSemver(t *testing.T) {
 				}),
 				NewVersion:     "1.1.2",
 				CurrentVersion: "1.1.1",
+				Containers:     []string{""},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -971,7 +980,7 @@ func TestProvider_checkForUpdateSemver(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
-										Image: "gcr.io/v2-namespace/hello-world:1.1.2",
+										Image: "gcr.io/v2-namespace/hello-world:latest",
 									},
 									v1.Container{
 										Image: "yo-world:1.1.1",
@@ -984,6 +993,7 @@ func TestProvider_checkForUpdateSemver(t *testing.T) {
 				}),
 				NewVersion:     "1.1.2",
 				CurrentVersion: "latest",
+				Containers:     []string{""},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -1061,6 +1071,7 @@ func TestProvider_checkForUpdateSemver(t *testing.T) {
 				}),
 				NewVersion:     "1.1.2",
 				CurrentVersion: "1.1.2",
+				Containers:     []string{""},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"errors"
 
 	"github.com/alwinius/bow/approvals"
 	"github.com/alwinius/bow/types"
@@ -17,6 +18,20 @@ type Provider interface {
 	Stop()
 }
 
+// Planner - providers that plan updates in dry run mode
+type Planner interface {
+	Plans() []*types.PlannedUpdate
+}
+
+// Rollbacker - providers that revert the last update of a resource
+type Rollbacker interface {
+	// Rollback returns ErrResourceNotFound if the provider does not know the resource
+	Rollback(identifier string, user string) (*types.Rollback, error)
+}
+
+// ErrResourceNotFound - resource to roll back is not managed by the provider
+var ErrResourceNotFound = errors.New("resource not found")
+
 // Providers - available providers
 type Providers interface {
 	Submit(event types.Event) error
@@ -112,6 +127,33 @@ func (p *DefaultProviders) TrackedImages() ([]*types.TrackedImage, error) {
 	return trackedImages, nil
 }
 
+// Plans - updates planned in dry run mode by providers implementing Planner
+func (p *DefaultProviders) Plans() []*types.PlannedUpdate {
+	var plans []*types.PlannedUpdate
+	for _, provider := range p.providers {
+		if planner, ok := provider.(Planner); ok {
+			plans = append(plans, planner.Plans()...)
+		}
+	}
+	return plans
+}
+
+// Rollback - reverts the last update of the resource with the first provider knowing it
+func (p *DefaultProviders) Rollback(identifier string, user string) (*types.Rollback, error) {
+	for _, provider := range p.providers {
+		rollbacker, ok := provider.(Rollbacker)
+		if !ok {
+			continue
+		}
+		rollback, err := rollbacker.Rollback(identifier, user)
+		if err == ErrResourceNotFound {
+			continue
+		}
+		return rollback, err
+	}
+	return nil, ErrResourceNotFound
+}
+
 // List - list available providers
 func (p *DefaultProviders) List() []string {
 	list := []string{}
@@ -1,12 +1,16 @@
 package registry
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"hash/fnv"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/alwinius/bow/types"
 	"github.com/rusenask/docker-registry-client/registry"
 
 	log "github.com/sirupsen/logrus"
@@ -32,6 +36,11 @@ type Client interface {
 	Digest(opts Opts) (string, error)
 }
 
+// MetadataGetter - clients which can read image labels and creation time from the image config
+type MetadataGetter interface {
+	Metadata(opts Opts) (*types.ImageMetadata, error)
+}
+
 // New - new registry client
 func New() *DefaultClient {
 	insecure := false
@@ -146,3 +155,55 @@ INIT_CLIENT:
 
 	return manifestDigest.String(), nil
 }
+
+// imageConfig - parts of the image config blob used for metadata
+type imageConfig struct {
+	Created time.Time `json:"created"`
+	Config  struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"config"`
+}
+
+// Metadata - get labels and creation time of the image from its config blob
+func (c *DefaultClient) Metadata(opts Opts) (*types.ImageMetadata, error) {
+	if opts.Tag == "" {
+		return nil, ErrTagNotSupplied
+	}
+
+	// fallback to HTTP if the registry doesn't speak HTTPS https://github.com/alwinius/bow/issues/331
+INIT_CLIENT:
+	hub, err := c.getRegistryClient(opts.Registry, opts.Username, opts.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := hub.ManifestV2(opts.Name, opts.Tag)
+	if err != nil {
+		if strings.Contains(err.Error(), "server gave HTTP response to HTTPS client") && strings.HasPrefix(opts.Registry, "https://") && c.insecure {
+			opts.Registry = strings.Replace(opts.Registry, "https://", "http://", 1)
+			goto INIT_CLIENT
+		}
+		return nil, err
+	}
+
+	if manifest.Config.Digest == "" {
+		return nil, fmt.Errorf("manifest of %s:%s has no image config", opts.Name, opts.Tag)
+	}
+
+	resp, err := hub.Client.Get(fmt.Sprintf("%s/v2/%s/blobs/%s", hub.URL, opts.Name, manifest.Config.Digest))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var config imageConfig
+	err = json.NewDecoder(resp.Body).Decode(&config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image config: %s", err)
+	}
+
+	return &types.ImageMetadata{
+		Labels:  config.Config.Labels,
+		Created: config.Created,
+	}, nil
+}
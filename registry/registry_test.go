@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestDigest(t *testing.T) {
@@ -313,3 +314,44 @@ func TestGetDockerHubManyTags(t *testing.T) {
 	}
 	fmt.Println(tags)
 }
+
+func TestMetadata(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v2/alwin2/bow/manifests/0.8.0":
+			fmt.Fprintln(w, `{
+				"schemaVersion": 2,
+				"mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+				"config": {
+					"mediaType": "application/vnd.docker.container.image.v1+json",
+					"size": 120,
+					"digest": "sha256:6592be974faae18818dca9b75682c9911815a98e6d952bf8c3932fcbef4c62e8"
+				},
+				"layers": []
+			}`)
+		case "/v2/alwin2/bow/blobs/sha256:6592be974faae18818dca9b75682c9911815a98e6d952bf8c3932fcbef4c62e8":
+			fmt.Fprintln(w, `{"created": "2018-01-15T20:42:14Z", "config": {"Labels": {"org.example.qa": "passed"}}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	client := New()
+	metadata, err := client.Metadata(Opts{
+		Registry: ts.URL,
+		Name:     "alwin2/bow",
+		Tag:      "0.8.0",
+	})
+	if err != nil {
+		t.Fatalf("error while getting metadata: %s", err)
+	}
+
+	if metadata.Labels["org.example.qa"] != "passed" {
+		t.Errorf("unexpected labels: %v", metadata.Labels)
+	}
+	if !metadata.Created.Equal(time.Date(2018, time.January, 15, 20, 42, 14, 0, time.UTC)) {
+		t.Errorf("unexpected created: %s", metadata.Created)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"os"
 
 	"github.com/alwinius/bow/approvals"
-	"github.com/alwinius/bow/cache/memory"
 	"github.com/alwinius/bow/provider"
 	"github.com/alwinius/bow/registry"
 	"github.com/alwinius/bow/types"
@@ -46,8 +45,7 @@ func TestCheckDeployment(t *testing.T) {
 			},
 		},
 	}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approvals.New(&approvals.Opts{})
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	// returning some sha
@@ -123,8 +121,7 @@ func TestCheckECRDeployment(t *testing.T) {
 			},
 		},
 	}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approvals.New(&approvals.Opts{})
 	providers := provider.New([]provider.Provider{fp}, am)
 	rc := registry.New()
 
@@ -2,6 +2,8 @@ package poll
 
 import (
 	"sort"
+	"sync"
+	"time"
 
 	"github.com/alwinius/bow/extension/credentialshelper"
 	"github.com/alwinius/bow/internal/policy"
@@ -21,6 +23,10 @@ type WatchRepositoryTagsJob struct {
 	registryClient registry.Client
 	details        *watchDetails
 
+	// metadata - labels and creation time of tags, fetched for policies using them
+	metadataMu *sync.Mutex
+	metadata   map[string]*types.ImageMetadata
+
 	// latests map[string]string // a map of prerelease tags and their corresponding latest versions
 }
 
@@ -30,6 +36,8 @@ func NewWatchRepositoryTagsJob(providers provider.Providers, registryClient regi
 		providers:      providers,
 		registryClient: registryClient,
 		details:        details,
+		metadataMu:     &sync.Mutex{},
+		metadata:       make(map[string]*types.ImageMetadata),
 		// latests:        details.trackedImage.SemverPreReleaseTags,
 	}
 }
@@ -46,13 +54,15 @@ func (j *WatchRepositoryTagsJob) Run() {
 		j.details.latest = j.details.trackedImage.Image.Tag()
 	}
 
-	repository, err := j.registryClient.Get(registry.Opts{
+	opts := registry.Opts{
 		Registry: reg,
 		Name:     j.details.trackedImage.Image.ShortName(),
 		Tag:      j.details.latest,
 		Username: creds.Username,
 		Password: creds.Password,
-	})
+	}
+
+	repository, err := j.registryClient.Get(opts)
 
 	if err != nil {
 		log.WithFields(log.Fields{
@@ -71,7 +81,7 @@ func (j *WatchRepositoryTagsJob) Run() {
 		"image_name":      j.details.trackedImage.Image.Remote(),
 	}).Debug("trigger.poll.WatchRepositoryTagsJob: checking tags")
 
-	err = j.processTags(repository.Tags)
+	err = j.processTags(repository.Tags, opts)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error":           err,
@@ -82,7 +92,7 @@ func (j *WatchRepositoryTagsJob) Run() {
 	}
 }
 
-func (j *WatchRepositoryTagsJob) computeEvents(tags []string) ([]types.Event, error) {
+func (j *WatchRepositoryTagsJob) computeEvents(tags []string, opts registry.Opts) ([]types.Event, error) {
 	trackedImages, err := j.providers.TrackedImages()
 	if err != nil {
 		return nil, err
@@ -90,24 +100,46 @@ func (j *WatchRepositoryTagsJob) computeEvents(tags []string) ([]types.Event, er
 
 	events := []types.Event{}
 
-	// collapse removes all non-semver tags and only takes
-	// the highest versions of each prerelease + the main version that doesn't have
-	// any prereleases
-	tags = collapse(tags)
-
 	for _, trackedImage := range getRelatedTrackedImages(j.details.trackedImage, trackedImages) {
 		// matches, going through tags
+		needsMetadata := policy.NeedsMetadata(trackedImage.Policy)
+		var matching []string
 		for _, tag := range tags {
-			update, err := trackedImage.Policy.ShouldUpdate(trackedImage.Image.Tag(), tag)
-			if err != nil {
+			var metadata *types.ImageMetadata
+			if needsMetadata && tag != trackedImage.Image.Tag() {
+				metadata = j.getMetadata(opts, tag)
+			}
+			update, err := policy.ShouldUpdate(trackedImage.Policy, trackedImage.Image.Tag(), tag, metadata)
+			if err != nil || !update {
 				continue
 			}
-			if update && !exists(tag, events) {
+			matching = append(matching, tag)
+		}
+
+		// policies ordering tags by a key, ie: calver or regexp with named groups, only
+		// apply the highest tag by that key
+		if ordered, ok := policy.AsOrdered(trackedImage.Policy); ok {
+			matching = highest(ordered, matching)
+		} else if _, ok := trackedImage.Policy.(*policy.CelPolicy); ok {
+			// expressions can select non-semver tags, ie: new.endsWith("-hotfix")
+			matching = j.newestMatch(matching)
+		} else {
+			// collapse removes all non-semver tags and only takes
+			// the highest versions of each prerelease + the main version that doesn't have
+			// any prereleases. Collapsing after matching keeps policies such as semver:~1.4
+			// on the highest allowed version when a higher one is available.
+			matching = collapse(matching)
+		}
+
+		for _, tag := range matching {
+			if !exists(tag, events) {
 				event := types.Event{
 					Repository: types.Repository{
 						Name:   j.details.trackedImage.Image.Repository(),
 						Tag:    tag,
 						OldTag: j.details.trackedImage.Image.Tag(),
+						// only set when a policy fetched it
+						Metadata: j.cachedMetadata(tag),
 					},
 					TriggerName: types.TriggerTypePoll.String(),
 				}
@@ -121,6 +153,42 @@ func (j *WatchRepositoryTagsJob) computeEvents(tags []string) ([]types.Event, er
 	return events, nil
 }
 
+// getMetadata - labels and creation time of the tag, fetched once per tag. Returns nil
+// when the registry client cannot provide them.
+func (j *WatchRepositoryTagsJob) getMetadata(opts registry.Opts, tag string) *types.ImageMetadata {
+	if metadata := j.cachedMetadata(tag); metadata != nil {
+		return metadata
+	}
+
+	getter, ok := j.registryClient.(registry.MetadataGetter)
+	if !ok {
+		return nil
+	}
+
+	opts.Tag = tag
+	metadata, err := getter.Metadata(opts)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"image": j.details.trackedImage.Image.Repository(),
+			"tag":   tag,
+		}).Warn("trigger.poll.WatchRepositoryTagsJob: failed to get image metadata")
+		return nil
+	}
+
+	j.metadataMu.Lock()
+	j.metadata[tag] = metadata
+	j.metadataMu.Unlock()
+
+	return metadata
+}
+
+func (j *WatchRepositoryTagsJob) cachedMetadata(tag string) *types.ImageMetadata {
+	j.metadataMu.Lock()
+	defer j.metadataMu.Unlock()
+	return j.metadata[tag]
+}
+
 func exists(tag string, events []types.Event) bool {
 	for _, e := range events {
 		if tag == e.Repository.Tag {
@@ -142,9 +210,11 @@ func collapse(tags []string) []string {
 		if err != nil {
 			continue
 		}
-		stored, ok := r[v.PreRelease]
+		// variants are kept apart, ie: 1.2.3-alpine and 1.2.3-slim
+		key := v.PreRelease + "/" + v.Variant
+		stored, ok := r[key]
 		if !ok {
-			r[v.PreRelease] = t
+			r[key] = t
 			continue
 		}
 		higher, err := p.ShouldUpdate(stored, t)
@@ -152,7 +222,7 @@ func collapse(tags []string) []string {
 			continue
 		}
 		if higher {
-			r[v.PreRelease] = t
+			r[key] = t
 		}
 	}
 
@@ -167,6 +237,38 @@ func collapse(tags []string) []string {
 	return result
 }
 
+// newestMatch - collapsed semver tags, when none of tags is a semver version the one
+// created last (if known) or listed last by the registry
+func (j *WatchRepositoryTagsJob) newestMatch(tags []string) []string {
+	if collapsed := collapse(tags); len(collapsed) > 0 || len(tags) == 0 {
+		return collapsed
+	}
+	newest := tags[len(tags)-1]
+	var newestCreated time.Time
+	for _, tag := range tags {
+		metadata := j.cachedMetadata(tag)
+		if metadata != nil && metadata.Created.After(newestCreated) {
+			newest = tag
+			newestCreated = metadata.Created
+		}
+	}
+	return []string{newest}
+}
+
+// highest - the highest of tags according to the policy, if any
+func highest(ordered policy.Ordered, tags []string) []string {
+	if len(tags) == 0 {
+		return tags
+	}
+	top := tags[0]
+	for _, tag := range tags[1:] {
+		if ordered.Compare(tag, top) > 0 {
+			top = tag
+		}
+	}
+	return []string{top}
+}
+
 func getRelatedTrackedImages(ours *types.TrackedImage, all []*types.TrackedImage) []*types.TrackedImage {
 	b := all[:0]
 	for _, x := range all {
@@ -178,9 +280,9 @@ func getRelatedTrackedImages(ours *types.TrackedImage, all []*types.TrackedImage
 	return b
 }
 
-func (j *WatchRepositoryTagsJob) processTags(tags []string) error {
+func (j *WatchRepositoryTagsJob) processTags(tags []string, opts registry.Opts) error {
 
-	events, err := j.computeEvents(tags)
+	events, err := j.computeEvents(tags, opts)
 	if err != nil {
 		return err
 	}
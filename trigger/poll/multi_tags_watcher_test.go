@@ -6,9 +6,9 @@ import (
 	"testing"
 
 	"github.com/alwinius/bow/approvals"
-	"github.com/alwinius/bow/cache/memory"
 	"github.com/alwinius/bow/internal/policy"
 	"github.com/alwinius/bow/provider"
+	"github.com/alwinius/bow/registry"
 	"github.com/alwinius/bow/types"
 	"github.com/alwinius/bow/util/image"
 )
@@ -27,8 +27,7 @@ func TestWatchMultipleTagsWithSemver(t *testing.T) {
 			},
 		},
 	}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approvals.New(&approvals.Opts{})
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	// returning some sha
@@ -64,8 +63,7 @@ func TestWatchAllTagsJobWithSemver(t *testing.T) {
 			},
 		},
 	}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approvals.New(&approvals.Opts{})
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	frc := &fakeRegistryClient{
@@ -114,8 +112,7 @@ func TestWatchAllTagsPrerelease(t *testing.T) {
 			},
 		},
 	}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approvals.New(&approvals.Opts{})
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	frc := &fakeRegistryClient{
@@ -151,6 +148,88 @@ func TestWatchAllTagsPrerelease(t *testing.T) {
 	}
 }
 
+func TestWatchAllTagsSemverConstraint(t *testing.T) {
+
+	reference, _ := image.Parse("foo/bar:1.4.1")
+	plc, err := policy.NewSemverConstraintPolicy("semver:~1.4")
+	if err != nil {
+		t.Fatalf("failed to parse policy: %s", err)
+	}
+	fp := &fakeProvider{
+		images: []*types.TrackedImage{
+			&types.TrackedImage{
+				Image:  reference,
+				Policy: plc,
+			},
+		},
+	}
+	am := approvals.New(&approvals.Opts{})
+	providers := provider.New([]provider.Provider{fp}, am)
+
+	frc := &fakeRegistryClient{
+		tagsToReturn: []string{"1.4.0", "1.4.3", "1.4.2", "1.5.0", "2.0.0"},
+	}
+
+	details := &watchDetails{
+		trackedImage: fp.images[0],
+	}
+
+	job := NewWatchRepositoryTagsJob(providers, frc, details)
+
+	job.Run()
+
+	// the highest version allowed by the constraint is submitted, not the highest one
+
+	if len(fp.submitted) != 1 {
+		t.Fatalf("expected 1 events, got: %d", len(fp.submitted))
+	}
+
+	if fp.submitted[0].Repository.Tag != "1.4.3" {
+		t.Errorf("expected event repository tag 1.4.3, but got: %s", fp.submitted[0].Repository.Tag)
+	}
+}
+
+func TestWatchAllTagsOrderedPolicy(t *testing.T) {
+
+	reference, _ := image.Parse("foo/bar:main-20241003.1532-abc123")
+	plc, err := policy.NewRegexpPolicy(`regexp:^main-(\d{8}\.\d{4})-[a-f0-9]+$;order=time`)
+	if err != nil {
+		t.Fatalf("failed to parse policy: %s", err)
+	}
+	fp := &fakeProvider{
+		images: []*types.TrackedImage{
+			&types.TrackedImage{
+				Image:  reference,
+				Policy: plc,
+			},
+		},
+	}
+	am := approvals.New(&approvals.Opts{})
+	providers := provider.New([]provider.Provider{fp}, am)
+
+	frc := &fakeRegistryClient{
+		tagsToReturn: []string{"main-20241002.0800-aaa111", "main-20241005.0910-ccc333", "main-20241004.2359-bbb222", "dev-20241006.0000-ddd444"},
+	}
+
+	details := &watchDetails{
+		trackedImage: fp.images[0],
+	}
+
+	job := NewWatchRepositoryTagsJob(providers, frc, details)
+
+	job.Run()
+
+	// only the highest tag by timestamp is submitted
+
+	if len(fp.submitted) != 1 {
+		t.Fatalf("expected 1 events, got: %d", len(fp.submitted))
+	}
+
+	if fp.submitted[0].Repository.Tag != "main-20241005.0910-ccc333" {
+		t.Errorf("expected event repository tag main-20241005.0910-ccc333, but got: %s", fp.submitted[0].Repository.Tag)
+	}
+}
+
 func TestWatchAllTagsMixed(t *testing.T) {
 
 	referenceA, _ := image.Parse("foo/bar:1.0.0")
@@ -168,8 +247,7 @@ func TestWatchAllTagsMixed(t *testing.T) {
 			},
 		},
 	}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approvals.New(&approvals.Opts{})
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	frc := &fakeRegistryClient{
@@ -191,7 +269,7 @@ func TestWatchAllTagsMixed(t *testing.T) {
 		for _, s := range fp.submitted {
 			tags = append(tags, s.Repository.Tag)
 		}
-		t.Errorf("expected 1 events, got: %d [%s]", len(fp.submitted), strings.Join(tags, ", "))
+		t.Fatalf("expected 2 events, got: %d [%s]", len(fp.submitted), strings.Join(tags, ", "))
 	}
 
 	submitted := fp.submitted[0]
@@ -227,8 +305,7 @@ func TestWatchAllTagsMixedPolicyAll(t *testing.T) {
 			},
 		},
 	}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approvals.New(&approvals.Opts{})
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	frc := &fakeRegistryClient{
@@ -250,7 +327,7 @@ func TestWatchAllTagsMixedPolicyAll(t *testing.T) {
 		for _, s := range fp.submitted {
 			tags = append(tags, s.Repository.Tag)
 		}
-		t.Errorf("expected 1 events, got: %d [%s]", len(fp.submitted), strings.Join(tags, ", "))
+		t.Fatalf("expected 2 events, got: %d [%s]", len(fp.submitted), strings.Join(tags, ", "))
 	}
 
 	submitted := fp.submitted[0]
@@ -269,6 +346,71 @@ func TestWatchAllTagsMixedPolicyAll(t *testing.T) {
 	}
 }
 
+// fakeMetadataRegistryClient - registry client returning image labels per tag
+type fakeMetadataRegistryClient struct {
+	fakeRegistryClient
+
+	labels    map[string]map[string]string
+	requested []string
+}
+
+func (c *fakeMetadataRegistryClient) Metadata(opts registry.Opts) (*types.ImageMetadata, error) {
+	c.requested = append(c.requested, opts.Tag)
+	return &types.ImageMetadata{Labels: c.labels[opts.Tag]}, nil
+}
+
+func TestWatchAllTagsJobWithCelLabels(t *testing.T) {
+
+	reference, _ := image.Parse("foo/bar:1.1.0")
+	fp := &fakeProvider{
+		images: []*types.TrackedImage{
+			&types.TrackedImage{
+				Image:  reference,
+				Policy: policy.GetPolicy(`cel:newer && labels["org.example.qa"] == "passed"`, &policy.Options{}),
+			},
+		},
+	}
+	am := approvals.New(&approvals.Opts{})
+	providers := provider.New([]provider.Provider{fp}, am)
+
+	frc := &fakeMetadataRegistryClient{
+		fakeRegistryClient: fakeRegistryClient{
+			tagsToReturn: []string{"1.1.0", "1.3.0", "1.4.0", "1.5.0"},
+		},
+		labels: map[string]map[string]string{
+			"1.3.0": {"org.example.qa": "passed"},
+			"1.4.0": {"org.example.qa": "passed"},
+			"1.5.0": {"org.example.qa": "failed"},
+		},
+	}
+
+	details := &watchDetails{
+		trackedImage: fp.images[0],
+	}
+
+	job := NewWatchRepositoryTagsJob(providers, frc, details)
+
+	job.Run()
+
+	if len(fp.submitted) != 1 {
+		t.Fatalf("expected 1 event, got: %d", len(fp.submitted))
+	}
+
+	submitted := fp.submitted[0]
+	if submitted.Repository.Tag != "1.4.0" {
+		t.Errorf("expected event repository tag 1.4.0, but got: %s", submitted.Repository.Tag)
+	}
+	if submitted.Repository.Metadata == nil || submitted.Repository.Metadata.Labels["org.example.qa"] != "passed" {
+		t.Errorf("expected event with image labels, got: %v", submitted.Repository.Metadata)
+	}
+
+	// metadata is fetched once per tag, never for the current one
+	job.Run()
+	if !reflect.DeepEqual(frc.requested, []string{"1.3.0", "1.4.0", "1.5.0"}) {
+		t.Errorf("unexpected metadata requests: %v", frc.requested)
+	}
+}
+
 func Test_collapse(t *testing.T) {
 	type args struct {
 		tags []string
@@ -303,6 +445,11 @@ func Test_collapse(t *testing.T) {
 			args: args{tags: []string{"1.2.0", "1.3.0-bb", "1.0.0-dev", "1.4.0-dev"}},
 			want: []string{"1.2.0", "1.3.0-bb", "1.4.0-dev"},
 		},
+		{
+			name: "variants",
+			args: args{tags: []string{"1.21.3-alpine", "1.21.4-alpine", "1.22.0-slim", "v1.21", "1.22-rc.1-alpine"}},
+			want: []string{"1.21.4-alpine", "1.22-rc.1-alpine", "1.22.0-slim", "v1.21"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/alwinius/bow/extension/credentialshelper"
+	"github.com/alwinius/bow/internal/policy"
 	"github.com/alwinius/bow/provider"
 	"github.com/alwinius/bow/registry"
 	"github.com/alwinius/bow/types"
@@ -255,11 +256,13 @@ func (w *RepositoryWatcher) addJob(ti *types.TrackedImage, schedule string) erro
 	// adding job to internal map
 	w.watched[key] = details
 
-	// checking tag type, for versioned (semver) tags we setup a watch all tags job
-	// and for non-semver types we create a single tag watcher which
-	// checks digest
+	// checking tag type, for versioned (semver) tags and policies ordering tags (calver,
+	// regexp with named groups) or deciding on any tag (cel) we setup a watch all tags job
+	// and for other non-semver types we create a single tag watcher which checks digest
+	_, ordered := policy.AsOrdered(ti.Policy)
+	_, expression := ti.Policy.(*policy.CelPolicy)
 	_, err = version.GetVersion(ti.Image.Tag())
-	if err != nil {
+	if err != nil && !ordered && !expression {
 		// adding new job
 		job := NewWatchTagJob(w.providers, w.registryClient, details)
 		log.WithFields(log.Fields{
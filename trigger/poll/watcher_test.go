@@ -6,7 +6,6 @@ import (
 	"testing"
 
 	"github.com/alwinius/bow/approvals"
-	"github.com/alwinius/bow/cache/memory"
 	"github.com/alwinius/bow/extension/credentialshelper"
 	"github.com/alwinius/bow/internal/policy"
 	"github.com/alwinius/bow/provider"
@@ -73,8 +72,7 @@ func (p *fakeProvider) TrackedImages() ([]*types.TrackedImage, error) {
 func TestWatchTagJob(t *testing.T) {
 
 	fp := &fakeProvider{}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approvals.New(&approvals.Opts{})
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	frc := &fakeRegistryClient{
@@ -120,8 +118,7 @@ func TestWatchTagJob(t *testing.T) {
 func TestWatchTagJobLatest(t *testing.T) {
 
 	fp := &fakeProvider{}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approvals.New(&approvals.Opts{})
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	frc := &fakeRegistryClient{
@@ -175,8 +172,7 @@ func TestWatchAllTagsJob(t *testing.T) {
 			},
 		},
 	}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approvals.New(&approvals.Opts{})
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	frc := &fakeRegistryClient{
@@ -215,8 +211,7 @@ func TestWatchAllTagsJobCurrentLatest(t *testing.T) {
 			},
 		},
 	}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approvals.New(&approvals.Opts{})
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	frc := &fakeRegistryClient{
@@ -234,7 +229,7 @@ func TestWatchAllTagsJobCurrentLatest(t *testing.T) {
 	// checking whether new job was submitted
 
 	if len(fp.submitted) != 0 {
-		t.Errorf("expected 0 submitted events but got something: %s", fp.submitted[0].Repository)
+		t.Errorf("expected 0 submitted events but got something: %v", fp.submitted[0].Repository)
 	}
 
 }
@@ -282,8 +277,7 @@ func TestWatchMultipleTags(t *testing.T) {
 			},
 		},
 	}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approvals.New(&approvals.Opts{})
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	// returning some sha
@@ -358,8 +352,7 @@ func TestWatchTagJobCheckCredentials(t *testing.T) {
 	defer credentialshelper.UnregisterCredentialsHelper("fake")
 
 	fp := &fakeProvider{}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approvals.New(&approvals.Opts{})
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	frc := &fakeRegistryClient{
@@ -407,8 +400,7 @@ func TestWatchTagJobLatestECR(t *testing.T) {
 		},
 	}
 
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approvals.New(&approvals.Opts{})
 	providers := provider.New([]provider.Provider{fp}, am)
 	rc := registry.New()
 
@@ -450,8 +442,7 @@ func TestWatchTagJobLatestECR(t *testing.T) {
 
 func TestUnwatchAfterNotTrackedAnymore(t *testing.T) {
 	fp := &fakeProvider{}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approvals.New(&approvals.Opts{})
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	// returning some sha
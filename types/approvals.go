@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -39,6 +40,10 @@ type Approval struct {
 	CurrentVersion string `json:"currentVersion"`
 	NewVersion     string `json:"newVersion"`
 
+	// Diff is the unified diff of the repository files the update will change. If it differs
+	// when the update is applied, ie: after the branch was rebased, votes are reset.
+	Diff string `json:"diff" gorm:"type:text"`
+
 	// Digest is used to verify that images are the ones that got the approvals.
 	// If digest doesn't match for the image, votes are reset.
 	Digest string `json:"digest"`
@@ -127,6 +132,15 @@ func (a *Approval) Expired() bool {
 	return a.Deadline.Before(time.Now())
 }
 
+// DiffPreview - Diff cut after maxLines lines for chat messages
+func (a *Approval) DiffPreview(maxLines int) string {
+	lines := strings.SplitAfter(strings.TrimSuffix(a.Diff, "\n"), "\n")
+	if len(lines) <= maxLines {
+		return a.Diff
+	}
+	return strings.Join(lines[:maxLines], "") + fmt.Sprintf("... %d more lines\n", len(lines)-maxLines)
+}
+
 // Delta of what's changed
 // ie: webhookrelay/webhook-demo:0.15.0 -> webhookrelay/webhook-demo:0.16.0
 func (a *Approval) Delta() string {
@@ -14,6 +14,7 @@ const (
 	AuditActionApprovalRejected = "rejected"
 	AuditActionApprovalExpired  = "expired"
 	AuditActionApprovalArchived = "archived"
+	AuditActionApprovalReset    = "reset"
 
 	// audit specific resource kinds (others are set by
 	// providers, ie: deployment, daemonset, helm chart)
@@ -42,6 +43,11 @@ type AuditLog struct {
 	PayloadType string `json:"payloadType"`
 
 	Metadata JSONB `json:"metadata" gorm:"type:json"`
+
+	// CommitHash and RevertedHash are copies of the commit and reverted metadata, indexed
+	// to find the entries of a commit
+	CommitHash   string `json:"-" gorm:"index;type:varchar(40)"`
+	RevertedHash string `json:"-" gorm:"index;type:varchar(40)"`
 }
 
 // SetMetadata - set audit log metadata (providers, namespaces)
@@ -52,6 +58,8 @@ func (l *AuditLog) SetMetadata(m map[string]string) {
 	}
 
 	l.Metadata = meta
+	l.CommitHash = m["commit"]
+	l.RevertedHash = m["reverted"]
 }
 
 // AuditLogQuery - struct used to query audit logs
@@ -63,6 +71,8 @@ type AuditLogQuery struct {
 	Offset   int    `json:"offset"`
 
 	ResourceKindFilter []string `json:"resourceKindFilter"`
+	// Commit matches entries with a commit or reverted commit equal to or starting with it
+	Commit string `json:"commit"`
 }
 
 type AuditLogStatsQuery struct {
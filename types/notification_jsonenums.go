@@ -18,6 +18,8 @@ var (
 		"NotificationSystemEvent":         NotificationSystemEvent,
 		"NotificationUpdateApproved":      NotificationUpdateApproved,
 		"NotificationUpdateRejected":      NotificationUpdateRejected,
+		"NotificationPromotion":           NotificationPromotion,
+		"NotificationRollback":            NotificationRollback,
 	}
 
 	_NotificationValueToName = map[Notification]string{
@@ -30,6 +32,8 @@ var (
 		NotificationSystemEvent:         "NotificationSystemEvent",
 		NotificationUpdateApproved:      "NotificationUpdateApproved",
 		NotificationUpdateRejected:      "NotificationUpdateRejected",
+		NotificationPromotion:           "NotificationPromotion",
+		NotificationRollback:            "NotificationRollback",
 	}
 )
 
@@ -46,6 +50,8 @@ func init() {
 			interface{}(NotificationSystemEvent).(fmt.Stringer).String():         NotificationSystemEvent,
 			interface{}(NotificationUpdateApproved).(fmt.Stringer).String():      NotificationUpdateApproved,
 			interface{}(NotificationUpdateRejected).(fmt.Stringer).String():      NotificationUpdateRejected,
+			interface{}(NotificationPromotion).(fmt.Stringer).String():           NotificationPromotion,
+			interface{}(NotificationRollback).(fmt.Stringer).String():            NotificationRollback,
 		}
 	}
 }
@@ -0,0 +1,23 @@
+package types
+
+import (
+	"time"
+)
+
+// PlannedUpdate - update of a resource in dry run mode with the changes bow would commit
+type PlannedUpdate struct {
+	Provider       string    `json:"provider"`
+	Repository     string    `json:"repository"`
+	Identifier     string    `json:"identifier"`
+	ResourceKind   string    `json:"resourceKind"`
+	Namespace      string    `json:"namespace"`
+	Name           string    `json:"name"`
+	Image          string    `json:"image"`
+	CurrentVersion string    `json:"currentVersion"`
+	NewVersion     string    `json:"newVersion"`
+	Trigger        string    `json:"trigger"`
+	Files          []string  `json:"files"`
+	Diff           string    `json:"diff"`
+	Error          string    `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
@@ -0,0 +1,16 @@
+package types
+
+import (
+	"time"
+)
+
+// PendingPromotion - version bow deployed to the source environment of a promotion pipeline,
+// waiting for its soak time or approval. Kept in the store so it survives restarts.
+type PendingPromotion struct {
+	// ID is <promotion name>/<image repository>, a pipeline waits for one version per image
+	ID         string    `json:"id" gorm:"primary_key;type:varchar(255)"`
+	Promotion  string    `json:"promotion"`
+	Repository string    `json:"repository"`
+	Tag        string    `json:"tag"`
+	Deployed   time.Time `json:"deployed"`
+}
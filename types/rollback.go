@@ -0,0 +1,22 @@
+package types
+
+import (
+	"time"
+)
+
+// Rollback - revert of the commit bow made for the last update of a resource
+type Rollback struct {
+	Provider   string `json:"provider"`
+	Repository string `json:"repository"`
+	Identifier string `json:"identifier"`
+	// Reverted is the hash of the reverted bow commit, Subject its first line
+	Reverted string `json:"reverted"`
+	Subject  string `json:"subject"`
+	// Commit is the hash of the revert commit, in pull request mode it is on a pull request branch
+	Commit string   `json:"commit"`
+	Files  []string `json:"files"`
+	// Blocked versions of the resource are not applied again by bow
+	Blocked   []string  `json:"blocked"`
+	User      string    `json:"user"`
+	CreatedAt time.Time `json:"createdAt"`
+}
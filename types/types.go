@@ -35,6 +35,12 @@ const BowForceTagMatchLabel = "bow/matchTag"
 // BowPollScheduleAnnotation - optional variable to setup custom schedule for polling, defaults to @every 10m
 const BowPollScheduleAnnotation = "bow/pollSchedule"
 
+// ContainerAnnotation - container scoped variant of annotation, ie: bow/policy.envoy sets the
+// policy of the envoy container, it overrides bow/policy of the resource
+func ContainerAnnotation(annotation, container string) string {
+	return annotation + "." + container
+}
+
 // BowPollDefaultSchedule - defaul polling schedule
 const BowPollDefaultSchedule = "@every 5m"
 
@@ -60,6 +66,21 @@ const BowApprovalDeadlineDefault = 24
 // BowReleasePage - optional release notes URL passed on with notification
 const BowReleaseNotesURL = "bow/releaseNotes"
 
+// BowDryRunAnnotation - "true" plans updates of the resource without committing them, see PlannedUpdate
+const BowDryRunAnnotation = "bow/dry-run"
+
+// BowSourceRepoAnnotation - set by the git watcher on every rendered resource,
+// holds the name of the repository the resource was rendered from
+const BowSourceRepoAnnotation = "bow/source-repo"
+
+// BowSourceAppAnnotation - set by the git watcher on every rendered resource, holds the chart,
+// kustomization or manifest directory (relative to the repository root) it was rendered from
+const BowSourceAppAnnotation = "bow/source-app"
+
+// BowSourceFilesAnnotation - set by the git watcher on every rendered resource, comma separated
+// list of repository files the resource was rendered from
+const BowSourceFilesAnnotation = "bow/source-files"
+
 // Repository - represents main docker repository fields that
 // bow cares about
 type Repository struct {
@@ -68,6 +89,15 @@ type Repository struct {
 	Tag    string `json:"tag"`
 	Digest string `json:"digest"` // optional digest field
 	OldTag string
+	// Metadata - labels and creation time of the image, only set by triggers
+	// which fetch it from the registry
+	Metadata *ImageMetadata `json:"metadata,omitempty"`
+}
+
+// ImageMetadata - image config details available to policies
+type ImageMetadata struct {
+	Labels  map[string]string `json:"labels,omitempty"`
+	Created time.Time         `json:"created"`
 }
 
 // String gives you [host/]team/repo[:tag] identifier
@@ -119,7 +149,9 @@ type Version struct {
 	Minor      int64
 	Patch      int64
 	PreRelease string
-	Metadata   string
+	// Variant - suffix naming an image flavour, ie: alpine in 1.21.3-alpine
+	Variant  string
+	Metadata string
 
 	Original string
 }
@@ -134,6 +166,9 @@ func (v Version) String() string {
 	if v.PreRelease != "" {
 		fmt.Fprintf(&buf, "-%s", v.PreRelease)
 	}
+	if v.Variant != "" {
+		fmt.Fprintf(&buf, "-%s", v.Variant)
+	}
 	if v.Metadata != "" {
 		fmt.Fprintf(&buf, "+%s", v.Metadata)
 	}
@@ -236,6 +271,9 @@ const (
 
 	NotificationUpdateApproved
 	NotificationUpdateRejected
+
+	NotificationPromotion
+	NotificationRollback
 )
 
 func (n Notification) String() string {
@@ -258,6 +296,10 @@ func (n Notification) String() string {
 		return "update approved"
 	case NotificationUpdateRejected:
 		return "update rejected "
+	case NotificationPromotion:
+		return "promotion"
+	case NotificationRollback:
+		return "rollback"
 	default:
 		return "unknown"
 	}
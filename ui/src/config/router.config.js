@@ -36,6 +36,14 @@ export const asyncRouterMap = [
         component: () => import('@/views/approvals/Approvals')
       },
 
+      {
+        path: '/plans',
+        name: 'plans',
+        hideChildrenInMenu: true,
+        meta: { title: 'Planned Changes', keepAlive: true, icon: 'diff', permission: [ 'dashboard' ], auth: true },
+        component: () => import('@/views/plans/Plans')
+      },
+
       {
         path: '/audit-logs',
         name: 'audit',
@@ -7,6 +7,7 @@ import tracked from './modules/tracked'
 import resources from './modules/resources'
 import approvals from './modules/approvals'
 import audit from './modules/audit'
+import plans from './modules/plans'
 import stats from './modules/stats'
 import permission from './modules/permission'
 import getters from './getters'
@@ -22,6 +23,7 @@ export default new Vuex.Store({
     resources,
     approvals,
     audit,
+    plans,
     stats
   },
   state: {
@@ -31,7 +31,11 @@ const audit = {
     GetAuditLogs ({ commit }, query) {
       commit('SET_ERROR', null)
       commit('SET_LOADING', true)
-      return api.get(`audit?filter=${query.filter}&limit=${query.limit}&offset=${query.offset}`)
+      let url = `audit?filter=${query.filter}&limit=${query.limit}&offset=${query.offset}`
+      if (query.commit) {
+        url += `&commit=${encodeURIComponent(query.commit)}`
+      }
+      return api.get(url)
         .then((response) => {
           commit('SET_AUDIT_LOGS', response.data)
           const pagination = {
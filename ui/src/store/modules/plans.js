@@ -0,0 +1,34 @@
+import api from '@/api/index.js'
+
+const plans = {
+  state: {
+    plans: [],
+    error: null
+  },
+
+  mutations: {
+    SET_PLANS: (state, plans) => {
+      var arrayLength = plans.length
+      // adding IDs so that the table is happy
+      for (var i = 0; i < arrayLength; i++) {
+        plans[i].id = i.toString()
+      }
+      state.plans = plans
+    },
+    SET_ERROR: (state, error) => {
+      state.error = error
+    }
+  },
+
+  actions: {
+    GetPlans ({ commit }) {
+      return api.get('plans')
+        .then((response) => {
+          commit('SET_PLANS', response)
+        })
+        .catch((error) => commit('SET_ERROR', error))
+    }
+  }
+}
+
+export default plans
@@ -80,6 +80,16 @@ const resources = {
       return api.put(`policies`, payload)
         .then((response) => commit('SET_ERROR', null))
         .catch((error) => commit('SET_ERROR', error))
+    },
+    RollbackResource ({ commit }, payload) {
+      commit('SET_ERROR', null)
+      commit('SET_RESOURCE_LOADING', payload.identifier)
+      return api.post(`resources/${payload.identifier}/rollback`)
+        .then((response) => {
+          commit('SET_ERROR', null)
+          return response.body
+        })
+        .catch((error) => commit('SET_ERROR', error))
     }
   }
 }
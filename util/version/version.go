@@ -2,6 +2,8 @@ package version
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
 	"sort"
 	"strings"
 
@@ -28,11 +30,19 @@ func MustParse(version string) *types.Version {
 	return ver
 }
 
-// GetVersion - parse version
+// prereleasePattern - suffix parts naming a pre-release, other parts name a variant
+var prereleasePattern = regexp.MustCompile(`^(?i)((alpha|beta|rc|pre|preview|dev|snapshot|canary|nightly|next|milestone)\.?[0-9]*(\.[0-9]+)*|[0-9]+(\.[0-9]+)*)$`)
+
+// GetVersion - parse version. Tags can start with v and omit the patch version (1.21 is
+// 1.21.0), suffixes are split into the pre-release and the variant of the image, ie:
+// 1.21.3-rc.1-alpine has pre-release rc.1 and variant alpine
 func GetVersion(version string) (*types.Version, error) {
 
-	parts := strings.SplitN(version, ".", 3)
-	if len(parts) != 3 {
+	core := strings.TrimPrefix(strings.TrimPrefix(version, "v"), "V")
+	if idx := strings.IndexAny(core, "-+"); idx >= 0 {
+		core = core[:idx]
+	}
+	if !strings.Contains(core, ".") {
 		return nil, ErrNoMajorMinorPatchElementsFound
 	}
 
@@ -44,16 +54,61 @@ func GetVersion(version string) (*types.Version, error) {
 		return nil, err
 	}
 
+	prerelease, variant := splitSuffix(v.Prerelease())
+
 	return &types.Version{
 		Major:      v.Major(),
 		Minor:      v.Minor(),
 		Patch:      v.Patch(),
-		PreRelease: string(v.Prerelease()),
+		PreRelease: prerelease,
+		Variant:    variant,
 		Metadata:   v.Metadata(),
 		Original:   v.Original(),
 	}, nil
 }
 
+// splitSuffix - leading suffix parts which look like a pre-release (alpha, rc.1, 2, ...)
+// form the pre-release, the rest is the variant, ie: alpine, slim or debian
+func splitSuffix(suffix string) (prerelease string, variant string) {
+	if suffix == "" {
+		return "", ""
+	}
+	parts := strings.Split(suffix, "-")
+	i := 0
+	for i < len(parts) && prereleasePattern.MatchString(parts[i]) {
+		i++
+	}
+	return strings.Join(parts[:i], "-"), strings.Join(parts[i:], "-")
+}
+
+// Compare - compares versions by major, minor, patch and pre-release, variants and metadata
+// are ignored. Returns -1, 0 or 1 when a is lower than, equal to or higher than b.
+func Compare(a, b *types.Version) (int, error) {
+	sa, err := semverOf(a)
+	if err != nil {
+		return 0, err
+	}
+	sb, err := semverOf(b)
+	if err != nil {
+		return 0, err
+	}
+	return sa.Compare(sb), nil
+}
+
+// semverOf - version without variant and metadata, fails instead of dropping a pre-release
+// semver cannot parse
+func semverOf(v *types.Version) (*semver.Version, error) {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.PreRelease != "" {
+		s += "-" + v.PreRelease
+	}
+	sv, err := semver.NewVersion(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pre-release '%s' of version %s: %s", v.PreRelease, v.Original, err)
+	}
+	return sv, nil
+}
+
 // GetVersionFromImageName - get version from image name
 func GetVersionFromImageName(name string) (*types.Version, error) {
 	parts := strings.Split(name, ":")
@@ -98,6 +98,40 @@ func TestGetVersion(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "two part version",
+			args: args{version: "v1.21"},
+			want: &types.Version{
+				Major:    1,
+				Minor:    21,
+				Original: "v1.21",
+			},
+			wantErr: false,
+		},
+		{
+			name: "variant",
+			args: args{version: "1.21.3-alpine"},
+			want: &types.Version{
+				Major:    1,
+				Minor:    21,
+				Patch:    3,
+				Variant:  "alpine",
+				Original: "1.21.3-alpine",
+			},
+			wantErr: false,
+		},
+		{
+			name: "pre-release and variant",
+			args: args{version: "1.21-rc.1-slim-bookworm"},
+			want: &types.Version{
+				Major:      1,
+				Minor:      21,
+				PreRelease: "rc.1",
+				Variant:    "slim-bookworm",
+				Original:   "1.21-rc.1-slim-bookworm",
+			},
+			wantErr: false,
+		},
 		{
 			name:    "not semver",
 			args:    args{version: "23"},
@@ -125,6 +159,35 @@ func TestGetVersion(t *testing.T) {
 	}
 }
 
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1.21", b: "1.21.0", want: 0},
+		{a: "v1.21.3-alpine", b: "1.21.4-alpine", want: -1},
+		{a: "1.21.4-rc.1-alpine", b: "1.21.4-alpine", want: -1},
+		{a: "1.22.0-slim", b: "1.21.4-alpine", want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			got, err := Compare(MustParse(tt.a), MustParse(tt.b))
+			if err != nil {
+				t.Fatalf("Compare() error = %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("Compare() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// pre-releases semver cannot parse are not dropped
+	_, err := Compare(&types.Version{Major: 1, Minor: 2, Patch: 3, PreRelease: "rc..1", Original: "1.2.3-rc..1"}, MustParse("1.2.3"))
+	if err == nil {
+		t.Errorf("expected invalid pre-release to fail")
+	}
+}
+
 func TestLowest(t *testing.T) {
 	type args struct {
 		tags []string
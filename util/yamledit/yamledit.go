@@ -0,0 +1,389 @@
+// Package yamledit finds and rewrites scalar values in block style YAML documents
+// without re-encoding them, so formatting, ordering and comments are kept.
+package yamledit
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Scalar - plain or quoted scalar value of a mapping entry or sequence item
+type Scalar struct {
+	// Path holds mapping keys and sequence indexes from the document root
+	Path []string
+	// Value is the unquoted value
+	Value string
+	// Doc is the index of the document in a multi-document file
+	Doc int
+	// Line is the zero based line number
+	Line int
+
+	// byte offsets of the value token including quotes
+	start, end int
+	style      byte
+	indent     int
+}
+
+// Key returns the last element of the path
+func (s Scalar) Key() string {
+	if len(s.Path) == 0 {
+		return ""
+	}
+	return s.Path[len(s.Path)-1]
+}
+
+// PathString returns the path joined with dots, ie: spec.template.spec.containers.0.image
+func (s Scalar) PathString() string {
+	return strings.Join(s.Path, ".")
+}
+
+// Indent returns the column of the key (or sequence item content) this value belongs to
+func (s Scalar) Indent() int {
+	return s.indent
+}
+
+// Offset returns the byte offset just after the value token
+func (s Scalar) Offset() int {
+	return s.end
+}
+
+type frame struct {
+	indent int
+	key    string
+	seq    bool
+	index  int
+	// open is set for mapping keys without inline value
+	open bool
+}
+
+type line struct {
+	start int
+	text  string
+}
+
+func splitLines(data []byte) []line {
+	var lines []line
+	start := 0
+	for i, b := range data {
+		if b == '\n' {
+			lines = append(lines, line{start: start, text: strings.TrimSuffix(string(data[start:i]), "\r")})
+			start = i + 1
+		}
+	}
+	if start < len(data) {
+		lines = append(lines, line{start: start, text: string(data[start:])})
+	}
+	return lines
+}
+
+// Scalars returns all scalar values in data. Flow collections, anchors, aliases and
+// block scalars are skipped.
+func Scalars(data []byte) []Scalar {
+	var (
+		result      []Scalar
+		stack       []frame
+		doc         int
+		content     bool
+		blockIndent = -1
+	)
+
+	path := func(extra ...string) []string {
+		var p []string
+		for _, f := range stack {
+			if f.seq {
+				p = append(p, strconv.Itoa(f.index))
+			} else {
+				p = append(p, f.key)
+			}
+		}
+		return append(p, extra...)
+	}
+
+	for n, l := range splitLines(data) {
+		trimmed := strings.TrimSpace(l.text)
+		indent := len(l.text) - len(strings.TrimLeft(l.text, " "))
+
+		// content of a block scalar
+		if blockIndent >= 0 {
+			if trimmed == "" || indent > blockIndent {
+				continue
+			}
+			blockIndent = -1
+		}
+
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "---") || trimmed == "..." {
+			if content {
+				doc++
+				content = false
+			}
+			stack = stack[:0]
+			continue
+		}
+		if strings.HasPrefix(trimmed, "%") {
+			continue
+		}
+		content = true
+
+		col := indent
+		rest := l.text[indent:]
+
+		// sequence items, possibly nested on the same line ("- - a")
+		for rest == "-" || strings.HasPrefix(rest, "- ") {
+			for len(stack) > 0 && stack[len(stack)-1].indent > col {
+				stack = stack[:len(stack)-1]
+			}
+			top := len(stack) - 1
+			switch {
+			case top >= 0 && stack[top].seq && stack[top].indent == col:
+				stack[top].index++
+			case top >= 0 && !stack[top].seq && stack[top].indent == col && !stack[top].open:
+				stack = stack[:top]
+				stack = append(stack, frame{indent: col, seq: true})
+			default:
+				stack = append(stack, frame{indent: col, seq: true})
+			}
+
+			if rest == "-" {
+				rest = ""
+				break
+			}
+			skipped := len(rest) - len(strings.TrimLeft(rest[1:], " "))
+			col += skipped
+			rest = rest[skipped:]
+		}
+
+		if rest == "" || strings.HasPrefix(rest, "#") {
+			continue
+		}
+
+		key, valueStart, isKey := parseKey(rest)
+		if !isKey {
+			// plain sequence item
+			if len(stack) > 0 && stack[len(stack)-1].seq && stack[len(stack)-1].indent < col {
+				if isBlockIndicator(rest) {
+					blockIndent = stack[len(stack)-1].indent
+					continue
+				}
+				if s, ok := parseValue(rest, l.start+len(l.text)-len(rest)); ok {
+					s.Path = path()
+					s.Doc = doc
+					s.Line = n
+					s.indent = col
+					result = append(result, s)
+				}
+			}
+			continue
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].indent >= col {
+			stack = stack[:len(stack)-1]
+		}
+
+		value := strings.TrimLeft(rest[valueStart:], " ")
+		offset := l.start + len(l.text) - len(value)
+
+		switch {
+		case value == "" || strings.HasPrefix(value, "#"):
+			stack = append(stack, frame{indent: col, key: key, open: true})
+		case isBlockIndicator(value):
+			blockIndent = col
+			stack = append(stack, frame{indent: col, key: key})
+		default:
+			stack = append(stack, frame{indent: col, key: key})
+			if s, ok := parseValue(value, offset); ok {
+				s.Path = path()
+				s.Doc = doc
+				s.Line = n
+				s.indent = col
+				result = append(result, s)
+			}
+		}
+	}
+
+	return result
+}
+
+// parseKey - finds mapping key at the beginning of s, returns the key and the index
+// after the colon
+func parseKey(s string) (key string, valueStart int, ok bool) {
+	if s[0] == '"' || s[0] == '\'' {
+		end := closingQuote(s, 0)
+		if end < 0 || end+1 >= len(s) || s[end+1] != ':' {
+			return "", 0, false
+		}
+		if end+2 < len(s) && s[end+2] != ' ' {
+			return "", 0, false
+		}
+		return unquote(s[:end+1]), end + 2, true
+	}
+
+	switch s[0] {
+	case '[', '{', '&', '*', '!', '|', '>', '#':
+		return "", 0, false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '#' && i > 0 && s[i-1] == ' ' {
+			return "", 0, false
+		}
+		if s[i] == ':' && (i+1 == len(s) || s[i+1] == ' ') {
+			return strings.TrimSpace(s[:i]), i + 1, true
+		}
+	}
+	return "", 0, false
+}
+
+// parseValue - parses scalar token at the beginning of s, offset is the position of s in the file
+func parseValue(s string, offset int) (Scalar, bool) {
+	switch s[0] {
+	case '[', '{', '&', '*', '!', '|', '>', '#':
+		return Scalar{}, false
+	case '"', '\'':
+		end := closingQuote(s, 0)
+		if end < 0 {
+			return Scalar{}, false
+		}
+		return Scalar{
+			Value: unquote(s[:end+1]),
+			start: offset,
+			end:   offset + end + 1,
+			style: s[0],
+		}, true
+	}
+
+	value := s
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = value[:i]
+	}
+	value = strings.TrimRight(value, " \t")
+	return Scalar{
+		Value: value,
+		start: offset,
+		end:   offset + len(value),
+	}, true
+}
+
+func closingQuote(s string, start int) int {
+	q := s[start]
+	for i := start + 1; i < len(s); i++ {
+		switch {
+		case q == '"' && s[i] == '\\':
+			i++
+		case q == '\'' && s[i] == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == q:
+			return i
+		}
+	}
+	return -1
+}
+
+func unquote(s string) string {
+	if s[0] == '\'' {
+		return strings.Replace(s[1:len(s)-1], "''", "'", -1)
+	}
+	v, err := strconv.Unquote(s)
+	if err != nil {
+		return s[1 : len(s)-1]
+	}
+	return v
+}
+
+func isBlockIndicator(v string) bool {
+	if v[0] != '|' && v[0] != '>' {
+		return false
+	}
+	v = strings.TrimSpace(strings.SplitN(v, "#", 2)[0])
+	return len(strings.Trim(v[1:], "+-0123456789")) == 0
+}
+
+// Replacement - new value for a scalar
+type Replacement struct {
+	Scalar Scalar
+	Value  string
+}
+
+// Replace returns data with the replacements applied. Quoting style of every replaced
+// value is kept, plain values are quoted if the new value would otherwise not be read as string.
+func Replace(data []byte, replacements ...Replacement) []byte {
+	sorted := make([]Replacement, len(replacements))
+	copy(sorted, replacements)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Scalar.start > sorted[j].Scalar.start })
+
+	out := append([]byte(nil), data...)
+	for _, r := range sorted {
+		formatted := []byte(Format(r.Value, r.Scalar.style))
+		var buf bytes.Buffer
+		buf.Write(out[:r.Scalar.start])
+		buf.Write(formatted)
+		buf.Write(out[r.Scalar.end:])
+		out = buf.Bytes()
+	}
+	return out
+}
+
+// Insert returns data with text inserted at offset
+func Insert(data []byte, offset int, text string) []byte {
+	var buf bytes.Buffer
+	buf.Write(data[:offset])
+	buf.WriteString(text)
+	buf.Write(data[offset:])
+	return buf.Bytes()
+}
+
+// LineEnd returns the offset of the line break ending the line that contains offset,
+// or len(data) on the last line
+func LineEnd(data []byte, offset int) int {
+	if i := bytes.IndexByte(data[offset:], '\n'); i >= 0 {
+		return offset + i
+	}
+	return len(data)
+}
+
+// RemoveLine returns data without the line holding scalar s
+func RemoveLine(data []byte, s Scalar) []byte {
+	start := bytes.LastIndexByte(data[:s.start], '\n') + 1
+	end := LineEnd(data, s.end)
+	if end < len(data) {
+		end++
+	}
+	var buf bytes.Buffer
+	buf.Write(data[:start])
+	buf.Write(data[end:])
+	return buf.Bytes()
+}
+
+var nonStringPlain = regexp.MustCompile(`^([-+]?(\.[0-9]+|[0-9][0-9_]*(\.[0-9_]*)?)([eE][-+]?[0-9]+)?|0x[0-9a-fA-F]+|0o[0-7]+|[-+]?\.(inf|Inf|INF)|\.(nan|NaN|NAN)|(?i:true|false|yes|no|on|off|y|n|null)|~)$`)
+
+// Format returns value as YAML scalar in the given quoting style ('"', '\” or 0 for plain)
+func Format(value string, style byte) string {
+	switch style {
+	case '"':
+		return strconv.Quote(value)
+	case '\'':
+		return "'" + strings.Replace(value, "'", "''", -1) + "'"
+	}
+	if needsQuotes(value) {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+func needsQuotes(value string) bool {
+	if value == "" || nonStringPlain.MatchString(value) {
+		return true
+	}
+	if strings.Contains(value, ": ") || strings.Contains(value, " #") || strings.HasSuffix(value, ":") {
+		return true
+	}
+	if value != strings.TrimSpace(value) {
+		return true
+	}
+	return strings.ContainsAny(value[:1], "-?:,[]{}#&*!|>'\"%@`")
+}
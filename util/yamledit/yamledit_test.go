@@ -0,0 +1,197 @@
+package yamledit
+
+import (
+	"testing"
+)
+
+const deployment = `# deployment of the web app
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web # trailing comment
+  annotations:
+    description: |
+      image: nginx:0.0.1 is mentioned here
+      but it is not an image field
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: "nginx:1.0.0" # pinned
+        args:
+          - --port
+          - "8080"
+      - name: sidecar
+        image: 'envoy:1.9.0'
+      initContainers:
+        - name: init
+          image: busybox:1.30
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`
+
+func scalarsByPath(data string) map[string]Scalar {
+	result := make(map[string]Scalar)
+	for _, s := range Scalars([]byte(data)) {
+		result[s.PathString()] = s
+	}
+	return result
+}
+
+func TestScalars(t *testing.T) {
+	scalars := scalarsByPath(deployment)
+
+	tests := map[string]string{
+		"apiVersion":                                 "v1",
+		"metadata.name":                              "web",
+		"spec.template.spec.containers.0.name":       "web",
+		"spec.template.spec.containers.0.image":      "nginx:1.0.0",
+		"spec.template.spec.containers.0.args.0":     "--port",
+		"spec.template.spec.containers.0.args.1":     "8080",
+		"spec.template.spec.containers.1.image":      "envoy:1.9.0",
+		"spec.template.spec.initContainers.0.name":   "init",
+		"spec.template.spec.initContainers.0.image":  "busybox:1.30",
+		"metadata.annotations.description":           "",
+		"spec.template.spec.containers.1.name":       "sidecar",
+		"spec.template.spec.initContainers.0.absent": "",
+	}
+
+	for path, want := range tests {
+		got, ok := scalars[path]
+		if want == "" {
+			if ok {
+				t.Errorf("unexpected scalar at %s: %q", path, got.Value)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("scalar %s not found", path)
+			continue
+		}
+		if got.Value != want {
+			t.Errorf("%s = %q, want %q", path, got.Value, want)
+		}
+	}
+
+	// the service is the second document
+	for _, s := range Scalars([]byte(deployment)) {
+		if s.Key() == "kind" && s.Value == "Service" && s.Doc != 1 {
+			t.Errorf("expected service in document 1, got %d", s.Doc)
+		}
+		if s.Key() == "kind" && s.Value == "Deployment" && s.Doc != 0 {
+			t.Errorf("expected deployment in document 0, got %d", s.Doc)
+		}
+	}
+}
+
+func TestReplaceKeepsFormatting(t *testing.T) {
+	scalars := scalarsByPath(deployment)
+
+	out := Replace([]byte(deployment),
+		Replacement{Scalar: scalars["spec.template.spec.containers.0.image"], Value: "nginx:1.1.0"},
+		Replacement{Scalar: scalars["spec.template.spec.containers.1.image"], Value: "envoy:1.10.0"},
+		Replacement{Scalar: scalars["spec.template.spec.initContainers.0.image"], Value: "busybox:1.31"},
+	)
+
+	expected := `# deployment of the web app
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web # trailing comment
+  annotations:
+    description: |
+      image: nginx:0.0.1 is mentioned here
+      but it is not an image field
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: "nginx:1.1.0" # pinned
+        args:
+          - --port
+          - "8080"
+      - name: sidecar
+        image: 'envoy:1.10.0'
+      initContainers:
+        - name: init
+          image: busybox:1.31
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`
+	if string(out) != expected {
+		t.Errorf("unexpected result:\n%s", out)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		value string
+		style byte
+		want  string
+	}{
+		{value: "1.2.3", want: "1.2.3"},
+		{value: "1.10", want: `"1.10"`},
+		{value: "10", want: `"10"`},
+		{value: "true", want: `"true"`},
+		{value: "", want: `""`},
+		{value: "v1.2", want: "v1.2"},
+		{value: "it's", style: '\'', want: "'it''s'"},
+		{value: "1.10", style: '"', want: `"1.10"`},
+	}
+	for _, tt := range tests {
+		if got := Format(tt.value, tt.style); got != tt.want {
+			t.Errorf("Format(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestValuesFile(t *testing.T) {
+	values := `image:
+  repository: nginx
+  tag: 1.15.0   # updated by bow
+  pullPolicy: IfNotPresent
+
+"quoted key": value
+list:
+- plain
+- name: item
+`
+	scalars := scalarsByPath(values)
+	if scalars["image.tag"].Value != "1.15.0" {
+		t.Errorf("unexpected tag: %q", scalars["image.tag"].Value)
+	}
+	if scalars["quoted key"].Value != "value" {
+		t.Errorf("unexpected quoted key value: %q", scalars["quoted key"].Value)
+	}
+	if scalars["list.0"].Value != "plain" || scalars["list.1.name"].Value != "item" {
+		t.Errorf("unexpected list values: %v", scalars)
+	}
+
+	out := Replace([]byte(values), Replacement{Scalar: scalars["image.tag"], Value: "1.16.0"})
+	if string(out[:60]) != "image:\n  repository: nginx\n  tag: 1.16.0   # updated by bow\n" {
+		t.Errorf("unexpected result:\n%s", out)
+	}
+}
+
+func TestRemoveLine(t *testing.T) {
+	data := "images:\n- name: nginx\n  digest: sha256:abc # pinned\n  newTag: 1.0.0\n"
+	scalars := scalarsByPath(data)
+
+	out := RemoveLine([]byte(data), scalars["images.0.digest"])
+	if string(out) != "images:\n- name: nginx\n  newTag: 1.0.0\n" {
+		t.Errorf("unexpected result:\n%s", out)
+	}
+
+	end := LineEnd([]byte(data), scalars["images.0.name"].Offset())
+	if data[end-5:end] != "nginx" {
+		t.Errorf("unexpected line end: %d", end)
+	}
+}